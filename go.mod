@@ -6,10 +6,10 @@ require (
 	github.com/go-resty/resty/v2 v2.16.5
 	github.com/google/go-querystring v1.1.0
 	go.uber.org/ratelimit v0.3.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/benbjohnson/clock v1.3.0 // indirect
 	golang.org/x/net v0.33.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -111,6 +111,11 @@ func (assortmentPosition AssortmentPosition) GetMeta() Meta {
 	return assortmentPosition.Meta
 }
 
+// AsAssortment реализует интерфейс [AssortmentConverter].
+func (assortmentPosition AssortmentPosition) AsAssortment() *AssortmentPosition {
+	return &AssortmentPosition{Meta: assortmentPosition.Meta}
+}
+
 // Raw реализует интерфейс [RawMetaTyper].
 func (assortmentPosition AssortmentPosition) Raw() []byte {
 	return assortmentPosition.raw
@@ -68,7 +68,7 @@ func (attribute Attribute) GetValue() any {
 
 // GetDownload возвращает Метаданные, содержащие ссылку на скачивание файла.
 func (attribute Attribute) GetDownload() Meta {
-	return Deref(attribute.Meta)
+	return Deref(attribute.Download)
 }
 
 // GetFile возвращает Описание файла и контент.
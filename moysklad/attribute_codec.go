@@ -0,0 +1,331 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// AttributeCodecError ошибка сопоставления доп. полей со структурой.
+type AttributeCodecError struct {
+	Missing  []string // Доп. поля из тегов структуры, отсутствующие в метаданных сущности
+	Required []string // Обязательные доп. поля без значения
+}
+
+// Error реализует интерфейс error.
+func (err AttributeCodecError) Error() string {
+	var parts []string
+	if len(err.Missing) > 0 {
+		parts = append(parts, "missing attributes: "+strings.Join(err.Missing, ", "))
+	}
+	if len(err.Required) > 0 {
+		parts = append(parts, "required attributes without value: "+strings.Join(err.Required, ", "))
+	}
+	return "attribute codec: " + strings.Join(parts, "; ")
+}
+
+var (
+	attributeCodecAttribute = reflect.TypeOf(Attribute{})
+	attributeCodecFile      = reflect.TypeOf(AttributeFile{})
+	attributeCodecTime      = reflect.TypeOf(time.Time{})
+	attributeCodecTimestamp = reflect.TypeOf(Timestamp{})
+	attributeCodecMeta      = reflect.TypeOf(Meta{})
+	attributeCodecWrapper   = reflect.TypeOf(MetaWrapper{})
+	attributeCodecOwner     = reflect.TypeOf((*MetaOwner)(nil)).Elem()
+)
+
+// AttributeCodec преобразование доп. полей сущности в поля пользовательской структуры и обратно.
+//
+// Поля структуры сопоставляются с доп. полями по наименованию, указанному в теге ms.
+// Параметр omitempty исключает нулевое значение поля при кодировании.
+//
+// Поддерживаемые типы полей структуры:
+//   - string, text, link – string
+//   - long, double – целые и дробные числа
+//   - boolean – bool
+//   - time – [time.Time] или [Timestamp]
+//   - file – [AttributeFile] (при декодировании заполняется только имя файла)
+//   - справочники (в том числе [CustomEntityElement]) – [Meta], [MetaWrapper] или структура сущности;
+//     при декодировании в string записывается наименование элемента справочника
+//   - любой тип – [Attribute] (доп. поле целиком)
+//
+// Поля-указатели равны nil, если значение доп. поля не заполнено; при кодировании nil сбрасывает значение.
+//
+// Пример:
+//
+//	type ProductAttributes struct {
+//		Color   string                        `ms:"Цвет"`
+//		Weight  *float64                      `ms:"Вес брутто,omitempty"`
+//		Arrival *time.Time                    `ms:"Дата поступления"`
+//		Brand   *moysklad.CustomEntityElement `ms:"Бренд"`
+//	}
+//
+//	codec, err := moysklad.NewAttributeCodecFromMetaType(ctx, client, moysklad.MetaTypeProduct)
+//	var attributes ProductAttributes
+//	err = codec.Decode(product.Attributes, &attributes)
+//	product.Attributes, err = codec.Encode(attributes)
+type AttributeCodec struct {
+	attributes map[string]*Attribute // метаданные доп. полей по наименованию
+}
+
+// NewAttributeCodec возвращает [AttributeCodec] для метаданных доп. полей attributes.
+func NewAttributeCodec(attributes Slice[Attribute]) *AttributeCodec {
+	codec := &AttributeCodec{attributes: make(map[string]*Attribute, len(attributes))}
+	for _, attribute := range attributes {
+		codec.attributes[attribute.GetName()] = attribute
+	}
+	return codec
+}
+
+// NewAttributeCodecFromMetaType запрашивает метаданные доп. полей сущности с кодом metaType
+// и возвращает [AttributeCodec].
+func NewAttributeCodecFromMetaType(ctx context.Context, client *Client, metaType MetaType) (*AttributeCodec, error) {
+	list, _, err := (&endpointAttributes{NewEndpoint(client, EndpointEntity+string(metaType))}).GetAttributeList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewAttributeCodec(list.Rows), nil
+}
+
+// attributeCodecField поле структуры, сопоставленное с доп. полем.
+type attributeCodecField struct {
+	name      string
+	omitempty bool
+	value     reflect.Value
+}
+
+// fields возвращает поля структуры v, отмеченные тегом ms.
+func (codec *AttributeCodec) fields(v reflect.Value) ([]attributeCodecField, error) {
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("attribute codec: expected struct, got %s", v.Type())
+	}
+	var fields []attributeCodecField
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("ms"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		fields = append(fields, attributeCodecField{name: name, omitempty: options == "omitempty", value: v.Field(i)})
+	}
+	return fields, nil
+}
+
+// Decode записывает значения доп. полей attributes в поля структуры, на которую указывает v.
+//
+// Если доп. поле из тега отсутствует в метаданных или обязательное доп. поле не заполнено,
+// то остальные поля заполняются и возвращается ошибка [AttributeCodecError].
+func (codec *AttributeCodec) Decode(attributes Slice[Attribute], v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("attribute codec: expected non-nil pointer to struct, got %T", v)
+	}
+	fields, err := codec.fields(value.Elem())
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]*Attribute, len(attributes))
+	for _, attribute := range attributes {
+		values[attribute.GetID()] = attribute
+	}
+
+	var codecErr AttributeCodecError
+	for _, field := range fields {
+		metadata, ok := codec.attributes[field.name]
+		if !ok {
+			codecErr.Missing = append(codecErr.Missing, field.name)
+			continue
+		}
+		attribute, ok := values[metadata.GetID()]
+		if !ok || attribute.Value == nil || attribute.Value.IsNull() || attribute.GetValue() == nil {
+			if metadata.GetRequired() {
+				codecErr.Required = append(codecErr.Required, field.name)
+			}
+			field.value.SetZero()
+			continue
+		}
+		if err = decodeAttribute(attribute, field.value); err != nil {
+			return fmt.Errorf("attribute codec: %s: %w", field.name, err)
+		}
+	}
+	if len(codecErr.Missing) > 0 || len(codecErr.Required) > 0 {
+		return codecErr
+	}
+	return nil
+}
+
+// decodeAttribute записывает значение доп. поля attribute в поле структуры target.
+func decodeAttribute(attribute *Attribute, target reflect.Value) error {
+	if target.Kind() == reflect.Pointer {
+		elem := reflect.New(target.Type().Elem())
+		if err := decodeAttribute(attribute, elem.Elem()); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	}
+
+	value := attribute.GetValue()
+	switch target.Type() {
+	case attributeCodecAttribute:
+		target.Set(reflect.ValueOf(*attribute))
+		return nil
+	case attributeCodecFile:
+		filename, _ := value.(string)
+		target.Set(reflect.ValueOf(AttributeFile{Filename: filename}))
+		return nil
+	case attributeCodecTime, attributeCodecTimestamp:
+		var t time.Time
+		switch v := value.(type) {
+		case string:
+			var err error
+			if t, err = time.Parse(TimestampFormat, v); err != nil {
+				if t, err = time.Parse(time.DateTime, v); err != nil {
+					return err
+				}
+			}
+		case time.Time:
+			t = v
+		case Timestamp:
+			t = v.Time()
+		case *Timestamp:
+			t = v.Time()
+		default:
+			return fmt.Errorf("unexpected time value %T", value)
+		}
+		target.Set(reflect.ValueOf(t).Convert(target.Type()))
+		return nil
+	case attributeCodecMeta, attributeCodecWrapper:
+		var wrapper MetaWrapper
+		if err := attributeRoundTrip(value, &wrapper); err != nil {
+			return err
+		}
+		if target.Type() == attributeCodecMeta {
+			target.Set(reflect.ValueOf(wrapper.Meta))
+		} else {
+			target.Set(reflect.ValueOf(wrapper))
+		}
+		return nil
+	}
+
+	// значение справочника в строковое поле – наименование элемента
+	if target.Kind() == reflect.String {
+		if _, ok := value.(string); !ok {
+			var named struct {
+				Name string `json:"name"`
+			}
+			if err := attributeRoundTrip(value, &named); err != nil {
+				return err
+			}
+			target.SetString(named.Name)
+			return nil
+		}
+	}
+	return attributeRoundTrip(value, target.Addr().Interface())
+}
+
+// attributeRoundTrip преобразует значение value в target через JSON.
+func attributeRoundTrip(value any, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// Encode возвращает доп. поля со значениями полей структуры v (структура или указатель на неё).
+//
+// Если доп. поле из тега отсутствует в метаданных или обязательное доп. поле не заполнено
+// (в том числе отсутствует в структуре), то вместе с остальными доп. полями возвращается ошибка [AttributeCodecError].
+func (codec *AttributeCodec) Encode(v any) (Slice[Attribute], error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	fields, err := codec.fields(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		codecErr   AttributeCodecError
+		attributes Slice[Attribute]
+		encoded    = make(map[string]bool, len(fields))
+	)
+	for _, field := range fields {
+		metadata, ok := codec.attributes[field.name]
+		if !ok {
+			codecErr.Missing = append(codecErr.Missing, field.name)
+			continue
+		}
+		encoded[field.name] = true
+		if field.value.IsZero() {
+			if metadata.GetRequired() {
+				codecErr.Required = append(codecErr.Required, field.name)
+			}
+			if field.omitempty {
+				continue
+			}
+		}
+		attribute, err := encodeAttribute(metadata, field.value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute codec: %s: %w", field.name, err)
+		}
+		attributes.Push(attribute)
+	}
+	for name, metadata := range codec.attributes {
+		if metadata.GetRequired() && !encoded[name] {
+			codecErr.Required = append(codecErr.Required, name)
+		}
+	}
+	if len(codecErr.Missing) > 0 || len(codecErr.Required) > 0 {
+		return attributes, codecErr
+	}
+	return attributes, nil
+}
+
+// encodeAttribute возвращает доп. поле metadata со значением поля структуры source.
+func encodeAttribute(metadata *Attribute, source reflect.Value) (*Attribute, error) {
+	attribute := &Attribute{Meta: metadata.Meta}
+	if source.Kind() == reflect.Pointer {
+		if source.IsNil() {
+			attribute.Value = NewNullValueAny()
+			return attribute, nil
+		}
+		source = source.Elem()
+	}
+
+	switch source.Type() {
+	case attributeCodecAttribute:
+		value := source.Interface().(Attribute)
+		value.Meta = metadata.Meta
+		return &value, nil
+	case attributeCodecFile:
+		file := source.Interface().(AttributeFile)
+		attribute.File = NewNullValue(&file)
+		return attribute, nil
+	case attributeCodecTime:
+		attribute.Value = NewNullValueAnyFrom(NewTimestamp(source.Interface().(time.Time)))
+		return attribute, nil
+	case attributeCodecMeta:
+		attribute.Value = NewNullValueAnyFrom(source.Interface().(Meta).Wrap())
+		return attribute, nil
+	}
+
+	switch metadata.GetType() {
+	case AttributeTypeDictionaryContract, AttributeTypeDictionaryCounterParty, AttributeTypeDictionaryProject,
+		AttributeTypeDictionaryStore, AttributeTypeDictionaryEmployee, AttributeTypeDictionaryProduct, AttributeTypeDictionaryCustom:
+		owner, ok := source.Interface().(MetaOwner)
+		if !ok && source.CanAddr() && source.Addr().Type().Implements(attributeCodecOwner) {
+			owner, ok = source.Addr().Interface().(MetaOwner)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s value requires meta, got %s", metadata.GetType(), source.Type())
+		}
+		attribute.Value = NewNullValueAnyFrom(owner.GetMeta().Wrap())
+	default:
+		attribute.Value = NewNullValueAnyFrom(source.Interface())
+	}
+	return attribute, nil
+}
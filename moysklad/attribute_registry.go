@@ -0,0 +1,285 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AttributeValidationError ошибка проверки доп. полей сущностей перед отправкой запроса.
+type AttributeValidationError struct {
+	URI      string   // Адрес запроса
+	Problems []string // Описание ошибок
+}
+
+// Error реализует интерфейс error.
+func (err AttributeValidationError) Error() string {
+	return fmt.Sprintf("attribute validation: %s: %s", err.URI, strings.Join(err.Problems, "; "))
+}
+
+// AttributeRegistry реестр метаданных доп. полей сущностей.
+//
+// Метаданные доп. полей каждого типа сущности запрашиваются один раз при первом обращении.
+// Реестр реализует интерфейс [RequestValidator] и может быть установлен в качестве проверки запросов клиента:
+// перед запросами на создание и изменение сущностей (Create, Update, CreateUpdateMany) проверяются
+//   - заполненность обязательных доп. полей (при создании) и отсутствие сброса их значений;
+//   - соответствие значений типам доп. полей;
+//   - принадлежность элементов пользовательских справочников справочнику доп. поля.
+//
+// Пример:
+//
+//	registry := moysklad.NewAttributeRegistry(client)
+//	client.SetRequestValidator(registry)
+//
+//	meta, err := registry.Resolve(ctx, moysklad.MetaTypeProduct, "Цвет")
+//	product.Attributes.Push(new(moysklad.Attribute).SetMeta(meta).SetValue("Красный"))
+//	_, _, err = client.Entity().Product().Create(ctx, product) // ошибка AttributeValidationError до отправки запроса
+type AttributeRegistry struct {
+	client     *Client
+	mu         sync.Mutex
+	attributes map[MetaType]Slice[Attribute]
+}
+
+// NewAttributeRegistry возвращает [AttributeRegistry].
+func NewAttributeRegistry(client *Client) *AttributeRegistry {
+	return &AttributeRegistry{client: client, attributes: make(map[MetaType]Slice[Attribute])}
+}
+
+// Attributes возвращает метаданные доп. полей сущности с кодом metaType.
+//
+// Если сущность не поддерживает доп. поля, то возвращается пустой срез.
+func (registry *AttributeRegistry) Attributes(ctx context.Context, metaType MetaType) (Slice[Attribute], error) {
+	registry.mu.Lock()
+	attributes, ok := registry.attributes[metaType]
+	registry.mu.Unlock()
+	if ok {
+		return attributes, nil
+	}
+
+	list, _, err := configAttributes(registry.client, metaType).GetAttributeList(ctx)
+	var apiErrors ApiErrors
+	switch {
+	case errors.As(err, &apiErrors):
+		attributes = Slice[Attribute]{}
+	case err != nil:
+		return nil, err
+	default:
+		attributes = list.Rows
+	}
+
+	registry.mu.Lock()
+	registry.attributes[metaType] = attributes
+	registry.mu.Unlock()
+	return attributes, nil
+}
+
+// Invalidate удаляет из реестра метаданные доп. полей сущностей metaTypes
+// (или всех сущностей, если metaTypes не переданы).
+func (registry *AttributeRegistry) Invalidate(metaTypes ...MetaType) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if len(metaTypes) == 0 {
+		registry.attributes = make(map[MetaType]Slice[Attribute])
+		return
+	}
+	for _, metaType := range metaTypes {
+		delete(registry.attributes, metaType)
+	}
+}
+
+// Attribute возвращает метаданные доп. поля сущности с кодом metaType по наименованию name.
+func (registry *AttributeRegistry) Attribute(ctx context.Context, metaType MetaType, name string) (*Attribute, error) {
+	attributes, err := registry.Attributes(ctx, metaType)
+	if err != nil {
+		return nil, err
+	}
+	for _, attribute := range attributes {
+		if attribute.GetName() == name {
+			return attribute, nil
+		}
+	}
+	return nil, fmt.Errorf("attribute registry: %s: attribute %q not found", metaType, name)
+}
+
+// Resolve возвращает метаданные доп. поля сущности с кодом metaType по наименованию name.
+func (registry *AttributeRegistry) Resolve(ctx context.Context, metaType MetaType, name string) (*Meta, error) {
+	attribute, err := registry.Attribute(ctx, metaType, name)
+	if err != nil {
+		return nil, err
+	}
+	return attribute.Meta, nil
+}
+
+// attributeRegistryEntity поля сущности в теле запроса, необходимые для проверки доп. полей.
+type attributeRegistryEntity struct {
+	ID         string `json:"id"`
+	Meta       *Meta  `json:"meta"`
+	Attributes []struct {
+		Meta  *Meta           `json:"meta"`
+		Value json.RawMessage `json:"value"`
+		File  json.RawMessage `json:"file"`
+	} `json:"attributes"`
+}
+
+// Validate реализует интерфейс [RequestValidator].
+//
+// Проверяются запросы на создание и изменение сущностей по адресам вида entity/{type} и entity/{type}/{id}.
+func (registry *AttributeRegistry) Validate(ctx context.Context, method, uri string, body any) error {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(uri, baseApiURL), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0]+"/" != EndpointEntity {
+		return nil
+	}
+	metaType := MetaType(parts[1])
+	// элементы пользовательских справочников не содержат доп. полей
+	if metaType == MetaTypeCustomEntity {
+		return nil
+	}
+	if len(parts) == 3 && !configUUIDPattern.MatchString(parts[2]) {
+		return nil
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var entities []attributeRegistryEntity
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &entities)
+	} else {
+		entities = make([]attributeRegistryEntity, 1)
+		err = json.Unmarshal(data, &entities[0])
+	}
+	if err != nil {
+		// тело запроса не является сущностью или списком сущностей
+		return nil
+	}
+
+	attributes, err := registry.Attributes(ctx, metaType)
+	if err != nil {
+		return err
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Attribute, len(attributes))
+	for _, attribute := range attributes {
+		byID[attribute.GetID()] = attribute
+	}
+
+	validationErr := AttributeValidationError{URI: uri}
+	for i, entity := range entities {
+		prefix := ""
+		if len(entities) > 1 {
+			prefix = fmt.Sprintf("[%d] ", i)
+		}
+		create := method == http.MethodPost && len(parts) == 2 && entity.ID == "" && entity.Meta == nil
+
+		filled := make(map[string]bool, len(entity.Attributes))
+		for _, value := range entity.Attributes {
+			id := Deref(value.Meta).GetUUIDFromHref()
+			attribute, ok := byID[id]
+			if !ok {
+				validationErr.Problems = append(validationErr.Problems, fmt.Sprintf("%sunknown attribute %s", prefix, Deref(value.Meta).GetHref()))
+				continue
+			}
+			name := attribute.GetName()
+			isNull := attributeRawIsNull(value.Value) && attributeRawIsNull(value.File)
+			filled[id] = !isNull
+			if isNull {
+				if attribute.GetRequired() {
+					validationErr.Problems = append(validationErr.Problems, fmt.Sprintf("%srequired attribute %q is empty", prefix, name))
+				}
+				continue
+			}
+			if err := validateAttributeValue(attribute, value.Value, value.File); err != nil {
+				validationErr.Problems = append(validationErr.Problems, fmt.Sprintf("%sattribute %q: %s", prefix, name, err))
+			}
+		}
+		if create {
+			for _, attribute := range attributes {
+				if attribute.GetRequired() && !filled[attribute.GetID()] {
+					validationErr.Problems = append(validationErr.Problems, fmt.Sprintf("%srequired attribute %q is missing", prefix, attribute.GetName()))
+				}
+			}
+		}
+	}
+	if len(validationErr.Problems) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+// attributeRawIsNull возвращает true, если значение отсутствует или равно null.
+func attributeRawIsNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+// validateAttributeValue проверяет соответствие значения типу доп. поля attribute.
+func validateAttributeValue(attribute *Attribute, raw json.RawMessage, file json.RawMessage) error {
+	attributeType := attribute.GetType()
+	if attributeType == AttributeTypeFile {
+		var value AttributeFile
+		if err := json.Unmarshal(file, &value); err != nil || value.Filename == "" || value.Content == "" {
+			return fmt.Errorf("expected file with filename and content")
+		}
+		return nil
+	}
+	if attributeRawIsNull(raw) {
+		return fmt.Errorf("expected value, got file")
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	switch attributeType {
+	case AttributeTypeString, AttributeTypeText, AttributeTypeLink:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected string, got %s", raw)
+		}
+	case AttributeTypeDouble:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("expected number, got %s", raw)
+		}
+	case AttributeTypeLong:
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			return fmt.Errorf("expected integer, got %s", raw)
+		}
+	case AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected boolean, got %s", raw)
+		}
+	case AttributeTypeTime:
+		moment, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected date, got %s", raw)
+		}
+		if _, err := time.Parse(time.DateTime, moment); err != nil {
+			if _, err = time.Parse(TimestampFormat, moment); err != nil {
+				return fmt.Errorf("expected date in format %q, got %s", time.DateTime, raw)
+			}
+		}
+	default:
+		var wrapper MetaWrapper
+		if err := json.Unmarshal(raw, &wrapper); err != nil || wrapper.Meta.GetHref() == "" {
+			return fmt.Errorf("expected object with meta, got %s", raw)
+		}
+		if metaType := wrapper.Meta.GetType(); metaType != "" && string(metaType) != string(attributeType) {
+			return fmt.Errorf("expected %s, got %s", attributeType, metaType)
+		}
+		if attributeType == AttributeTypeDictionaryCustom {
+			// ссылка на элемент справочника: entity/customentity/{id справочника}/{id элемента}
+			customEntityID := attribute.GetCustomEntityMeta().GetUUIDFromHref()
+			if !strings.Contains(wrapper.Meta.GetHref(), "/"+EndpointEntity+string(MetaTypeCustomEntity)+"/"+customEntityID+"/") {
+				return fmt.Errorf("element %s does not belong to custom entity %s", wrapper.Meta.GetHref(), customEntityID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,695 @@
+package moysklad
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// BackupTypes сущности, которые сохраняются в резервную копию по умолчанию, в порядке восстановления:
+// справочники, контрагенты, товары, документы.
+//
+// Склады, проекты, статьи расходов, каналы продаж и характеристики модификаций сохраняются в снимке настроек [ConfigSnapshot].
+//
+// Не сохраняются сущности, которые нельзя восстановить без объектов, отсутствующих в архиве:
+//   - сотрудники и отделы, а также зависящие от сотрудников начисления зарплаты и задачи
+//     (ответственный сотрудник задачи обязателен);
+//   - точки продаж, смены и розничные документы (розничные продажи и возвраты, внесения и выплаты, предоплаты
+//     и возвраты предоплат): точки продаж содержат кассиров и настройки касс, а смены открываются кассой;
+//   - тех. процессы, этапы, тех. карты и зависящие от них тех. операции, заказы на производство
+//     и производственные задания;
+//   - бонусные программы и бонусные операции;
+//   - скидки, шаблоны печатных форм, веб-хуки приложений, уведомления и отчёты.
+var BackupTypes = []MetaType{
+	MetaTypeCurrency, MetaTypeUom, MetaTypeCountry, MetaTypeOrganization,
+	MetaTypeCounterparty, MetaTypeContract,
+	MetaTypeProductFolder, MetaTypeProduct, MetaTypeService, MetaTypeVariant, MetaTypeBundle, MetaTypeConsignment,
+	MetaTypeCustomerOrder, MetaTypePurchaseOrder, MetaTypeInternalOrder, MetaTypeInvoiceOut, MetaTypeInvoiceIn,
+	MetaTypeSupply, MetaTypeDemand, MetaTypeMove, MetaTypeEnter, MetaTypeLoss, MetaTypeInventory,
+	MetaTypeSalesReturn, MetaTypePurchaseReturn, MetaTypeCommissionReportIn, MetaTypeCommissionReportOut,
+	MetaTypePaymentIn, MetaTypePaymentOut, MetaTypeCashIn, MetaTypeCashOut, MetaTypeFactureOut, MetaTypeFactureIn,
+	MetaTypeCounterpartyAdjustment, MetaTypePriceList,
+}
+
+// backupChildren вложенные коллекции сущностей, которые сохраняются вместе с сущностью.
+var backupChildren = map[MetaType][]string{
+	MetaTypeCounterparty:   {"accounts", "contactpersons"},
+	MetaTypeOrganization:   {"accounts"},
+	MetaTypeBundle:         {"components"},
+	MetaTypeCustomerOrder:  {"positions"},
+	MetaTypePurchaseOrder:  {"positions"},
+	MetaTypeInternalOrder:  {"positions"},
+	MetaTypeInvoiceOut:     {"positions"},
+	MetaTypeInvoiceIn:      {"positions"},
+	MetaTypeSupply:         {"positions"},
+	MetaTypeDemand:         {"positions"},
+	MetaTypeMove:           {"positions"},
+	MetaTypeEnter:          {"positions"},
+	MetaTypeLoss:           {"positions"},
+	MetaTypeInventory:      {"positions"},
+	MetaTypeSalesReturn:    {"positions"},
+	MetaTypePurchaseReturn: {"positions"},
+	MetaTypePriceList:      {"positions"},
+
+	MetaTypeCommissionReportIn:  {"positions", "returntocommissionerpositions"},
+	MetaTypeCommissionReportOut: {"positions"},
+}
+
+// backupChildFields наименования полей сущности для вложенных коллекций, отличающиеся от адреса коллекции.
+var backupChildFields = map[string]string{
+	"returntocommissionerpositions": "returnToCommissionerPositions",
+}
+
+// backupImageTypes сущности с изображениями.
+var backupImageTypes = map[MetaType]bool{
+	MetaTypeProduct: true, MetaTypeVariant: true, MetaTypeBundle: true,
+}
+
+// backupSkipFields поля, которые не передаются при восстановлении: идентификаторы, вычисляемые поля
+// и коллекции, восстанавливаемые отдельно.
+var backupSkipFields = []string{
+	"id", "meta", "accountId", "updated", "created", "deleted", "printed", "published", "owner", "group",
+	"sum", "vatSum", "payedSum", "shippedSum", "invoicedSum", "reservedSum", "pathName", "notes",
+	"positions", "returnToCommissionerPositions", "components", "accounts", "contactpersons", "files", "images",
+}
+
+// backupChildSkipFields поля вложенных объектов, которые не передаются при восстановлении.
+var backupChildSkipFields = []string{"id", "meta", "accountId", "updated"}
+
+// backupBatchSize количество сущностей в одном запросе на создание при восстановлении.
+const backupBatchSize = 100
+
+// backupManifestName имя файла манифеста в архиве.
+const backupManifestName = "manifest.json"
+
+// backupConfigName имя файла снимка настроек в архиве.
+const backupConfigName = "config.json"
+
+// BackupAttachment Файл или изображение сущности в архиве.
+type BackupAttachment struct {
+	Title    string `json:"title,omitempty"` // Название
+	Filename string `json:"filename"`        // Имя файла
+	Path     string `json:"path"`            // Путь к содержимому в архиве
+}
+
+// BackupRecord Строка архива: сущность с вложенными коллекциями.
+type BackupRecord struct {
+	Entity   json.RawMessage              `json:"entity"`             // Сущность
+	Children map[string][]json.RawMessage `json:"children,omitempty"` // Вложенные коллекции: позиции, компоненты, счета, контактные лица
+	Notes    []json.RawMessage            `json:"notes,omitempty"`    // События контрагента
+	Files    []*BackupAttachment          `json:"files,omitempty"`    // Файлы
+	Images   []*BackupAttachment          `json:"images,omitempty"`   // Изображения
+}
+
+// BackupManifestEntry Сведения о сохранённых сущностях одного типа.
+type BackupManifestEntry struct {
+	Type        MetaType `json:"type"`        // Код сущности
+	Path        string   `json:"path"`        // Путь к файлу JSONL в архиве
+	Count       int      `json:"count"`       // Количество сущностей
+	Attachments int      `json:"attachments"` // Количество файлов и изображений
+}
+
+// BackupManifest Манифест резервной копии.
+type BackupManifest struct {
+	Version  int                    `json:"version"`  // Версия формата архива
+	Started  time.Time              `json:"started"`  // Момент начала резервного копирования
+	Finished time.Time              `json:"finished"` // Момент окончания резервного копирования
+	Config   string                 `json:"config"`   // Путь к снимку настроек в архиве
+	Entries  []*BackupManifestEntry `json:"entries"`  // Сущности в порядке восстановления
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (manifest BackupManifest) String() string {
+	return Stringify(manifest)
+}
+
+// BackupConfig конфигурация резервного копирования.
+type BackupConfig struct {
+	// Сущности в порядке восстановления (по умолчанию [BackupTypes]).
+	Types []MetaType
+
+	// Сохранять файлы сущностей.
+	Files bool
+
+	// Сохранять изображения товаров, модификаций и комплектов.
+	Images bool
+
+	// Конфигурация снимка настроек учётной записи.
+	Config ConfigMigrationConfig
+}
+
+// BackupRestoreResult Результат восстановления из резервной копии.
+type BackupRestoreResult struct {
+	Config  *ConfigMigrationResult // Результат применения снимка настроек
+	Created map[MetaType]int       // Количество созданных сущностей
+	Matched map[MetaType]int       // Количество сущностей, уже существовавших в учётной записи (сопоставлены по наименованию)
+	Failed  []*BackupRestoreError  // Сущности, которые не удалось восстановить
+}
+
+// BackupRestoreError Ошибка восстановления сущности из резервной копии.
+type BackupRestoreError struct {
+	Type      MetaType   // Код сущности
+	ID        string     // ID сущности в исходной учётной записи
+	Name      string     // Наименование или номер сущности
+	Err       error      // Ошибка формирования запроса
+	ApiErrors []ApiError // Ошибки API МойСклад, относящиеся к сущности
+}
+
+// Error реализует интерфейс error.
+func (e BackupRestoreError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s (%s): %s", e.Type, e.ID, e.Name, e.Err)
+	}
+	return fmt.Sprintf("%s %s (%s): %s", e.Type, e.ID, e.Name, ApiErrors{ApiErrors: NewSliceFrom(e.ApiErrors)})
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (result BackupRestoreResult) String() string {
+	return Stringify(result)
+}
+
+// Backup резервное копирование учётной записи в архив ZIP и восстановление из него.
+//
+// Архив содержит манифест, снимок настроек ([ConfigSnapshot]), файлы JSONL с сущностями
+// (по одной строке [BackupRecord] на сущность) и содержимое файлов и изображений.
+//
+// Резервная копия снимается последовательным чтением списков, поэтому изменения, внесённые
+// во время копирования, могут попасть в неё частично; момент начала сохраняется в манифесте.
+//
+// Восстановление выполняется в пустую учётную запись: сначала применяется снимок настроек,
+// затем сущности создаются в порядке манифеста, а ссылки на восстановленные объекты
+// заменяются ссылками на созданные. Сущности, уже существующие в учётной записи,
+// сопоставляются по наименованию (документы – по номеру) и не создаются повторно.
+// Сущности, которые не удалось создать, и документы с позициями, ссылающимися на такие сущности,
+// перечисляются в [BackupRestoreResult.Failed]; в этом случае восстановление завершается с ошибкой.
+//
+// Пример:
+//
+//	backup := moysklad.NewBackup(client, moysklad.BackupConfig{Files: true, Images: true})
+//	manifest, err := backup.Write(ctx, file)
+//
+//	result, err := moysklad.NewBackup(target, moysklad.BackupConfig{}).Restore(ctx, file, size)
+type Backup struct {
+	client *Client
+	config BackupConfig
+}
+
+// NewBackup возвращает [Backup].
+func NewBackup(client *Client, config BackupConfig) *Backup {
+	if config.Types == nil {
+		config.Types = BackupTypes
+	}
+	return &Backup{client: client, config: config}
+}
+
+// backupDownload описывает файл, содержимое которого необходимо скачать в архив.
+type backupDownload struct {
+	href string
+	path string
+}
+
+// Write сохраняет резервную копию учётной записи в архив ZIP.
+func (backup *Backup) Write(ctx context.Context, w io.Writer) (*BackupManifest, error) {
+	manifest := &BackupManifest{Version: 1, Started: time.Now(), Config: backupConfigName}
+	archive := zip.NewWriter(w)
+
+	snapshot, err := NewConfigMigration(backup.config.Config).Export(ctx, backup.client)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := archive.Create(backupConfigName)
+	if err != nil {
+		return nil, err
+	}
+	if err = snapshot.WriteJSON(entry); err != nil {
+		return nil, err
+	}
+
+	for _, metaType := range backup.config.Types {
+		manifestEntry, err := backup.writeType(ctx, archive, metaType)
+		if err != nil {
+			return nil, fmt.Errorf("backup: %s: %w", metaType, err)
+		}
+		manifest.Entries = append(manifest.Entries, manifestEntry)
+	}
+
+	manifest.Finished = time.Now()
+	if entry, err = archive.Create(backupManifestName); err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, archive.Close()
+}
+
+// writeType сохраняет в архив сущности типа metaType и их файлы.
+func (backup *Backup) writeType(ctx context.Context, archive *zip.Writer, metaType MetaType) (*BackupManifestEntry, error) {
+	manifestEntry := &BackupManifestEntry{Type: metaType, Path: "entities/" + string(metaType) + ".jsonl"}
+	entry, err := archive.Create(manifestEntry.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// в ZIP одновременно записывается только один файл, поэтому файлы скачиваются после списка сущностей
+	var downloads []backupDownload
+	encoder := json.NewEncoder(entry)
+	endpoint := NewEndpoint(backup.client, EndpointEntity+string(metaType))
+	var params []func(*Params)
+	if metaType == MetaTypeProductFolder {
+		// родительские группы сохраняются раньше дочерних
+		params = append(params, WithOrder("pathName"))
+	}
+	err = forEachPage(ctx, &endpointGetList[json.RawMessage]{endpoint}, func(rows Slice[json.RawMessage]) error {
+		for _, row := range rows {
+			record, recordDownloads, err := backup.record(ctx, endpoint, metaType, *row)
+			if err != nil {
+				return err
+			}
+			if err = encoder.Encode(record); err != nil {
+				return err
+			}
+			manifestEntry.Count++
+			downloads = append(downloads, recordDownloads...)
+		}
+		return nil
+	}, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, download := range downloads {
+		entry, err := archive.Create(download.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = backup.client.Download(ctx, download.href, entry, 0); err != nil {
+			return nil, err
+		}
+		manifestEntry.Attachments++
+	}
+	return manifestEntry, nil
+}
+
+// backupEntity поля сущности, необходимые для получения вложенных коллекций.
+type backupEntity struct {
+	ID     string                     `json:"id"`
+	Fields map[string]json.RawMessage `json:"-"`
+}
+
+// collectionSize возвращает количество элементов вложенной коллекции name по её метаданным.
+// Если метаданные коллекции отсутствуют, возвращает -1.
+func (entity backupEntity) collectionSize(name string) int {
+	var collection struct {
+		Meta *MetaCollection `json:"meta"`
+	}
+	if raw, ok := entity.Fields[name]; !ok || json.Unmarshal(raw, &collection) != nil || collection.Meta == nil {
+		return -1
+	}
+	return collection.Meta.Size
+}
+
+// record получает вложенные коллекции сущности и формирует строку архива.
+func (backup *Backup) record(ctx context.Context, endpoint Endpoint, metaType MetaType, raw json.RawMessage) (*BackupRecord, []backupDownload, error) {
+	var entity backupEntity
+	if err := json.Unmarshal(raw, &entity); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(raw, &entity.Fields); err != nil {
+		return nil, nil, err
+	}
+	record := &BackupRecord{Entity: raw}
+
+	for _, child := range backupChildren[metaType] {
+		if entity.collectionSize(child) == 0 {
+			continue
+		}
+		rows, err := backupList(ctx, backup.client, fmt.Sprintf("%s/%s/%s", endpoint.uri, entity.ID, child))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) > 0 {
+			if record.Children == nil {
+				record.Children = make(map[string][]json.RawMessage)
+			}
+			record.Children[child] = rows
+		}
+	}
+
+	if metaType == MetaTypeCounterparty && entity.collectionSize("notes") != 0 {
+		notes, err := backupList(ctx, backup.client, fmt.Sprintf("%s/%s/notes", endpoint.uri, entity.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		record.Notes = notes
+	}
+
+	var downloads []backupDownload
+	if backup.config.Files && entity.collectionSize("files") > 0 {
+		files, _, err := (&endpointFiles{endpoint}).GetFileList(ctx, entity.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, file := range files.Rows {
+			attachment := &BackupAttachment{
+				Title:    file.GetTitle(),
+				Filename: file.GetFilename(),
+				Path:     backupAttachmentPath("files", metaType, entity.ID, i, file.GetFilename()),
+			}
+			record.Files = append(record.Files, attachment)
+			downloads = append(downloads, backupDownload{href: file.GetMeta().GetDownloadHref(), path: attachment.Path})
+		}
+	}
+	if backup.config.Images && backupImageTypes[metaType] && entity.collectionSize("images") > 0 {
+		images, _, err := (&endpointImages{endpoint}).GetImageList(ctx, entity.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, image := range images.Rows {
+			attachment := &BackupAttachment{
+				Title:    image.GetTitle(),
+				Filename: image.GetFilename(),
+				Path:     backupAttachmentPath("images", metaType, entity.ID, i, image.GetFilename()),
+			}
+			record.Images = append(record.Images, attachment)
+			downloads = append(downloads, backupDownload{href: image.GetMeta().GetDownloadHref(), path: attachment.Path})
+		}
+	}
+	return record, downloads, nil
+}
+
+// backupAttachmentPath возвращает путь к содержимому файла в архиве.
+func backupAttachmentPath(kind string, metaType MetaType, id string, index int, filename string) string {
+	filename = strings.NewReplacer("/", "_", "\\", "_").Replace(filename)
+	return path.Join(kind, string(metaType), id, fmt.Sprintf("%d-%s", index, filename))
+}
+
+// backupList получает все элементы вложенной коллекции по адресу uri.
+func backupList(ctx context.Context, client *Client, uri string) ([]json.RawMessage, error) {
+	var rows []json.RawMessage
+	for offset := 0; ; offset += MaxPositions {
+		list, _, err := NewRequestBuilder[List[json.RawMessage]](client, uri).
+			SetParams([]func(*Params){WithLimit(MaxPositions), WithOffset(offset)}).Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range list.Rows {
+			rows = append(rows, *row)
+		}
+		if list.Len() < MaxPositions || offset+MaxPositions >= list.Size() {
+			return rows, nil
+		}
+	}
+}
+
+// Restore восстанавливает учётную запись из архива ZIP r размером size.
+func (backup *Backup) Restore(ctx context.Context, r io.ReaderAt, size int64) (*BackupRestoreResult, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var manifest BackupManifest
+	if err = backupReadJSON(files, backupManifestName, &manifest); err != nil {
+		return nil, err
+	}
+	var snapshot ConfigSnapshot
+	if err = backupReadJSON(files, manifest.Config, &snapshot); err != nil {
+		return nil, err
+	}
+
+	apply := newConfigApply(backup.client)
+	result := &BackupRestoreResult{Config: apply.result, Created: make(map[MetaType]int), Matched: make(map[MetaType]int)}
+	if err = apply.run(ctx, &snapshot); err != nil {
+		return result, err
+	}
+
+	restore := &backupRestore{client: backup.client, apply: apply, files: files, result: result}
+	for _, entry := range manifest.Entries {
+		if err = restore.restoreType(ctx, entry); err != nil {
+			return result, fmt.Errorf("restore: %s: %w", entry.Type, err)
+		}
+	}
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("restore: %d entities not restored, first: %w", len(result.Failed), result.Failed[0])
+	}
+	return result, nil
+}
+
+// backupReadJSON читает файл name архива в формате JSON.
+func backupReadJSON(files map[string]*zip.File, name string, v any) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("restore: %s not found in archive", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return json.NewDecoder(reader).Decode(v)
+}
+
+// backupRestore состояние восстановления из резервной копии.
+type backupRestore struct {
+	client *Client
+	apply  *configApply
+	files  map[string]*zip.File
+	result *BackupRestoreResult
+}
+
+// restoreType создаёт сущности одного типа.
+func (restore *backupRestore) restoreType(ctx context.Context, entry *BackupManifestEntry) error {
+	file, ok := restore.files[entry.Path]
+	if !ok {
+		return fmt.Errorf("%s not found in archive", entry.Path)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	uri := EndpointEntity + string(entry.Type)
+	existing := make(map[string]string)
+	err = forEachPage(ctx, &endpointGetList[backupNamed]{NewEndpoint(restore.client, uri)}, func(rows Slice[backupNamed]) error {
+		for _, row := range rows {
+			existing[row.Name] = row.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		sources []*BackupRecord
+		batch   []map[string]any
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, resp, err := NewRequestBuilder[[]json.RawMessage](restore.client, uri).Post(ctx, batch)
+
+		// сервис возвращает массив той же длины, где на месте ошибочных объектов находятся ошибки
+		var elements []json.RawMessage
+		if resp != nil {
+			_ = json.Unmarshal(resp.Body(), &elements)
+		}
+		if len(elements) != len(batch) {
+			if err == nil {
+				err = fmt.Errorf("unexpected response length %d, want %d", len(elements), len(batch))
+			}
+			return err
+		}
+
+		for i, raw := range elements {
+			var source backupNamed
+			_ = json.Unmarshal(sources[i].Entity, &source)
+
+			var element struct {
+				ID     string     `json:"id"`
+				Errors []ApiError `json:"errors"`
+			}
+			if err = json.Unmarshal(raw, &element); err != nil {
+				return err
+			}
+			if len(element.Errors) > 0 || element.ID == "" {
+				restore.fail(entry.Type, source, nil, element.Errors)
+				continue
+			}
+
+			restore.apply.mapID(source.ID, element.ID)
+			restore.result.Created[entry.Type]++
+			if err = restore.notes(ctx, sources[i], element.ID); err != nil {
+				return err
+			}
+		}
+		sources, batch = sources[:0], batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 1<<20), 1<<28)
+	for scanner.Scan() {
+		record := new(BackupRecord)
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return err
+		}
+		var source backupNamed
+		if err = json.Unmarshal(record.Entity, &source); err != nil {
+			return err
+		}
+		if id, ok := existing[source.Name]; ok && source.Name != "" {
+			restore.apply.mapID(source.ID, id)
+			restore.result.Matched[entry.Type]++
+			continue
+		}
+
+		entity, err := restore.entity(record)
+		if err != nil {
+			restore.fail(entry.Type, source, err, nil)
+			continue
+		}
+		sources = append(sources, record)
+		batch = append(batch, entity)
+		// группы товаров ссылаются на родительские группы, поэтому создаются по одной;
+		// сущности с файлами создаются по одной, чтобы не превышать размер запроса
+		if len(batch) >= backupBatchSize || len(record.Files) > 0 || len(record.Images) > 0 || entry.Type == MetaTypeProductFolder {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// backupNamed поля сущности, по которым выполняется сопоставление.
+type backupNamed struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// fail добавляет в результат сущность source, которую не удалось восстановить.
+func (restore *backupRestore) fail(metaType MetaType, source backupNamed, err error, apiErrors []ApiError) {
+	if err == nil && len(apiErrors) == 0 {
+		err = fmt.Errorf("entity was not created")
+	}
+	restore.result.Failed = append(restore.result.Failed, &BackupRestoreError{
+		Type: metaType, ID: source.ID, Name: source.Name, Err: err, ApiErrors: apiErrors,
+	})
+}
+
+// entity формирует тело запроса на создание сущности из строки архива.
+//
+// Возвращает ошибку, если позиция ссылается на товар, который не был восстановлен.
+func (restore *backupRestore) entity(record *BackupRecord) (map[string]any, error) {
+	var entity map[string]any
+	if err := json.Unmarshal(record.Entity, &entity); err != nil {
+		return nil, err
+	}
+	for _, field := range backupSkipFields {
+		delete(entity, field)
+	}
+
+	for name, rows := range record.Children {
+		children := make([]any, 0, len(rows))
+		for _, row := range rows {
+			var child map[string]any
+			if err := json.Unmarshal(row, &child); err != nil {
+				return nil, err
+			}
+			for _, field := range backupChildSkipFields {
+				delete(child, field)
+			}
+			_, hasAssortment := child["assortment"]
+			remapped, _ := restore.apply.remapValue(child)
+			if _, ok := remapped.(map[string]any)["assortment"]; hasAssortment && !ok {
+				return nil, fmt.Errorf("%s: assortment of position %d was not restored", name, len(children)+1)
+			}
+			children = append(children, remapped)
+		}
+		if field, ok := backupChildFields[name]; ok {
+			name = field
+		}
+		entity[name] = children
+	}
+
+	for name, attachments := range map[string][]*BackupAttachment{"files": record.Files, "images": record.Images} {
+		if len(attachments) == 0 {
+			continue
+		}
+		var values []map[string]any
+		for _, attachment := range attachments {
+			content, err := restore.content(attachment.Path)
+			if err != nil {
+				return nil, err
+			}
+			value := map[string]any{"filename": attachment.Filename, "content": content}
+			if attachment.Title != "" {
+				value["title"] = attachment.Title
+			}
+			values = append(values, value)
+		}
+		entity[name] = values
+	}
+
+	remapped, _ := restore.apply.remapValue(entity)
+	return remapped.(map[string]any), nil
+}
+
+// content возвращает содержимое файла архива в кодировке Base64.
+func (restore *backupRestore) content(name string) (string, error) {
+	file, ok := restore.files[name]
+	if !ok {
+		return "", fmt.Errorf("%s not found in archive", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	encoder := base64.NewEncoder(base64.StdEncoding, &buf)
+	if _, err = io.Copy(encoder, reader); err != nil {
+		return "", err
+	}
+	if err = encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// notes создаёт события контрагента.
+func (restore *backupRestore) notes(ctx context.Context, record *BackupRecord, id string) error {
+	service := NewCounterpartyService(restore.client)
+	for _, raw := range record.Notes {
+		var note Note
+		if err := json.Unmarshal(raw, &note); err != nil {
+			return err
+		}
+		if _, _, err := service.CreateNote(ctx, id, &Note{Description: note.Description}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,830 @@
+package moysklad
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BankStatement банковская выписка в формате обмена 1CClientBankExchange.
+type BankStatement struct {
+	Header    map[string]string       // Заголовок файла (ВерсияФормата, Кодировка, Отправитель, ДатаНачала и т.д.)
+	Accounts  []BankStatementAccount  // Секции расчётных счетов (СекцияРасчСчет)
+	Documents []BankStatementDocument // Платёжные документы (СекцияДокумент)
+}
+
+// BankStatementAccount остатки и обороты по расчётному счёту выписки.
+//
+// Суммы указаны в копейках.
+type BankStatementAccount struct {
+	Account        string    // Расчётный счёт
+	DateFrom       time.Time // Дата начала периода
+	DateTo         time.Time // Дата конца периода
+	OpeningBalance float64   // Начальный остаток
+	Income         float64   // Всего поступило
+	Outcome        float64   // Всего списано
+	ClosingBalance float64   // Конечный остаток
+}
+
+// BankStatementDocument платёжный документ выписки.
+//
+// Сумма указана в копейках.
+type BankStatementDocument struct {
+	Kind             string            // Вид документа (Платежное поручение, Банковский ордер и т.д.)
+	Number           string            // Номер документа
+	Date             time.Time         // Дата документа
+	Sum              float64           // Сумма
+	PayerAccount     string            // Расчётный счёт плательщика
+	PayerName        string            // Наименование плательщика
+	PayerINN         string            // ИНН плательщика
+	PayerKPP         string            // КПП плательщика
+	PayerBIC         string            // БИК банка плательщика
+	RecipientAccount string            // Расчётный счёт получателя
+	RecipientName    string            // Наименование получателя
+	RecipientINN     string            // ИНН получателя
+	RecipientKPP     string            // КПП получателя
+	RecipientBIC     string            // БИК банка получателя
+	DebitDate        time.Time         // Дата списания со счёта плательщика (ДатаСписано)
+	CreditDate       time.Time         // Дата поступления на счёт получателя (ДатаПоступило)
+	Purpose          string            // Назначение платежа
+	Fields           map[string]string // Все поля документа
+}
+
+// ExternalCode возвращает внешний код платежа, по которому определяется, был ли документ загружен ранее.
+//
+// Код строится из даты, номера, счетов плательщика и получателя и суммы документа.
+func (document BankStatementDocument) ExternalCode() string {
+	return fmt.Sprintf("1cbank:%s:%s:%s:%s:%.0f",
+		document.Date.Format("20060102"), document.Number,
+		document.PayerAccount, document.RecipientAccount, document.Sum,
+	)
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (document BankStatementDocument) String() string {
+	return fmt.Sprintf("%s № %s от %s на сумму %.2f", document.Kind, document.Number,
+		document.Date.Format("02.01.2006"), document.Sum/100)
+}
+
+// bankStatementSignature первая строка файла обмена.
+const bankStatementSignature = "1CClientBankExchange"
+
+// ParseBankStatement разбирает банковскую выписку в формате обмена 1CClientBankExchange.
+//
+// Кодировка определяется автоматически: UTF-8, Windows-1251 или DOS (CP866) при наличии в заголовке Кодировка=DOS.
+// Суммы преобразуются в копейки.
+func ParseBankStatement(r io.Reader) (*BankStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	var text string
+	if utf8.Valid(data) {
+		text = string(data)
+	} else {
+		// признак Кодировка=DOS записан в самом файле в CP866
+		text = decodeCP866(data)
+		if !strings.Contains(text, "Кодировка=DOS") {
+			text = decodeCP1251(data)
+		}
+	}
+
+	statement := &BankStatement{Header: make(map[string]string)}
+
+	var (
+		account  *BankStatementAccount
+		document map[string]string
+		kind     string
+		started  bool
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		row := strings.TrimSpace(scanner.Text())
+		if row == "" {
+			continue
+		}
+
+		if !started {
+			if row != bankStatementSignature {
+				return nil, fmt.Errorf("bank statement: missing %s signature", bankStatementSignature)
+			}
+			started = true
+			continue
+		}
+
+		key, value, _ := strings.Cut(row, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch {
+		case key == "КонецФайла":
+			return statement, nil
+
+		case key == "СекцияРасчСчет":
+			account = new(BankStatementAccount)
+
+		case key == "КонецРасчСчет":
+			if account == nil {
+				return nil, fmt.Errorf("bank statement: line %d: unexpected %s", line, key)
+			}
+			statement.Accounts = append(statement.Accounts, *account)
+			account = nil
+
+		case key == "СекцияДокумент":
+			document = make(map[string]string)
+			kind = value
+
+		case key == "КонецДокумента":
+			if document == nil {
+				return nil, fmt.Errorf("bank statement: line %d: unexpected %s", line, key)
+			}
+			parsed, err := parseBankStatementDocument(kind, document)
+			if err != nil {
+				return nil, fmt.Errorf("bank statement: line %d: %w", line, err)
+			}
+			statement.Documents = append(statement.Documents, *parsed)
+			document = nil
+
+		case document != nil:
+			document[key] = value
+
+		case account != nil:
+			if err := account.set(key, value); err != nil {
+				return nil, fmt.Errorf("bank statement: line %d: %w", line, err)
+			}
+
+		default:
+			statement.Header[key] = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, fmt.Errorf("bank statement: missing %s signature", bankStatementSignature)
+	}
+	if document != nil || account != nil {
+		return nil, fmt.Errorf("bank statement: unexpected end of file")
+	}
+
+	return statement, nil
+}
+
+// set заполняет поле секции расчётного счёта.
+func (account *BankStatementAccount) set(key, value string) (err error) {
+	switch key {
+	case "РасчСчет":
+		account.Account = value
+	case "ДатаНачала":
+		account.DateFrom, err = parseBankStatementDate(value)
+	case "ДатаКонца":
+		account.DateTo, err = parseBankStatementDate(value)
+	case "НачальныйОстаток":
+		account.OpeningBalance, err = parseBankStatementSum(value)
+	case "ВсегоПоступило":
+		account.Income, err = parseBankStatementSum(value)
+	case "ВсегоСписано":
+		account.Outcome, err = parseBankStatementSum(value)
+	case "КонечныйОстаток":
+		account.ClosingBalance, err = parseBankStatementSum(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// parseBankStatementDocument заполняет платёжный документ по полям секции.
+func parseBankStatementDocument(kind string, fields map[string]string) (*BankStatementDocument, error) {
+	document := &BankStatementDocument{
+		Kind:             kind,
+		Number:           fields["Номер"],
+		PayerAccount:     firstNonEmpty(fields["ПлательщикРасчСчет"], fields["ПлательщикСчет"]),
+		PayerName:        firstNonEmpty(fields["Плательщик1"], fields["Плательщик"]),
+		PayerINN:         fields["ПлательщикИНН"],
+		PayerKPP:         fields["ПлательщикКПП"],
+		PayerBIC:         fields["ПлательщикБИК"],
+		RecipientAccount: firstNonEmpty(fields["ПолучательРасчСчет"], fields["ПолучательСчет"]),
+		RecipientName:    firstNonEmpty(fields["Получатель1"], fields["Получатель"]),
+		RecipientINN:     fields["ПолучательИНН"],
+		RecipientKPP:     fields["ПолучательКПП"],
+		RecipientBIC:     fields["ПолучательБИК"],
+		Purpose:          fields["НазначениеПлатежа"],
+		Fields:           fields,
+	}
+
+	if document.Purpose == "" {
+		var parts []string
+		for i := 1; ; i++ {
+			part, ok := fields["НазначениеПлатежа"+strconv.Itoa(i)]
+			if !ok {
+				break
+			}
+			parts = append(parts, part)
+		}
+		document.Purpose = strings.Join(parts, " ")
+	}
+
+	var err error
+	if document.Date, err = parseBankStatementDate(fields["Дата"]); err != nil {
+		return nil, fmt.Errorf("Дата: %w", err)
+	}
+	if document.Sum, err = parseBankStatementSum(fields["Сумма"]); err != nil {
+		return nil, fmt.Errorf("Сумма: %w", err)
+	}
+	if document.DebitDate, err = parseBankStatementDate(fields["ДатаСписано"]); err != nil {
+		return nil, fmt.Errorf("ДатаСписано: %w", err)
+	}
+	if document.CreditDate, err = parseBankStatementDate(fields["ДатаПоступило"]); err != nil {
+		return nil, fmt.Errorf("ДатаПоступило: %w", err)
+	}
+
+	return document, nil
+}
+
+// parseBankStatementDate разбирает дату формата ДД.ММ.ГГГГ. Пустое значение возвращает нулевую дату.
+func parseBankStatementDate(s string) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return time.Time{}, nil
+	}
+	return parseTime(s)
+}
+
+// parseBankStatementSum разбирает сумму в рублях и возвращает её в копейках.
+func parseBankStatementSum(s string) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	sum, err := parseNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(sum * 100), nil
+}
+
+// firstNonEmpty возвращает первую непустую строку.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// BankStatementImportConfig конфигурация загрузки банковской выписки.
+type BankStatementImportConfig struct {
+	// Юрлицо.
+	//
+	// Если не указано, то юрлицо определяется по расчётному счёту документа среди всех юрлиц.
+	Organization *Organization
+
+	// Статья расходов исходящих платежей (обязательна при наличии в выписке списаний).
+	ExpenseItem *ExpenseItem
+
+	// Проводить ли созданные платежи.
+	Applicable bool
+
+	// Привязывать ли входящие платежи к неоплаченным Счетам покупателям и Заказам покупателей контрагента.
+	//
+	// Документ выбирается по номеру, указанному в назначении платежа,
+	// либо, если такого нет, по совпадению неоплаченной суммы с суммой платежа.
+	LinkOperations bool
+
+	// Только сопоставить документы выписки, не создавая платежи.
+	DryRun bool
+}
+
+// BankStatementStatus результат обработки документа выписки.
+//
+// Возможные значения:
+//   - BankStatementStatusCreated   – Платёж создан
+//   - BankStatementStatusPlanned   – Платёж будет создан (режим DryRun)
+//   - BankStatementStatusDuplicate – Платёж был загружен ранее
+//   - BankStatementStatusTransfer  – Перевод между собственными счетами
+//   - BankStatementStatusForeign   – Документ не относится к счетам юрлица
+//   - BankStatementStatusUnmatched – Контрагент не найден
+//   - BankStatementStatusFailed    – Ошибка создания платежа
+type BankStatementStatus string
+
+const (
+	BankStatementStatusCreated   BankStatementStatus = "created"   // Платёж создан
+	BankStatementStatusPlanned   BankStatementStatus = "planned"   // Платёж будет создан (режим DryRun)
+	BankStatementStatusDuplicate BankStatementStatus = "duplicate" // Платёж был загружен ранее
+	BankStatementStatusTransfer  BankStatementStatus = "transfer"  // Перевод между собственными счетами
+	BankStatementStatusForeign   BankStatementStatus = "foreign"   // Документ не относится к счетам юрлица
+	BankStatementStatusUnmatched BankStatementStatus = "unmatched" // Контрагент не найден
+	BankStatementStatusFailed    BankStatementStatus = "failed"    // Ошибка создания платежа
+)
+
+// BankStatementEntry результат обработки документа выписки.
+type BankStatementEntry struct {
+	Document     *BankStatementDocument // Документ выписки
+	Status       BankStatementStatus    // Результат обработки
+	Incoming     bool                   // Поступление на счёт юрлица (иначе списание)
+	Organization *Organization          // Юрлицо
+	Counterparty *Counterparty          // Контрагент
+	Operation    *Operation             // Привязанный документ (Счет покупателю или Заказ покупателя)
+	PaymentIn    *PaymentIn             // Входящий платеж
+	PaymentOut   *PaymentOut            // Исходящий платеж
+	Err          error                  // Ошибка создания платежа
+}
+
+// BankStatementImport результат загрузки банковской выписки.
+type BankStatementImport struct {
+	Entries []BankStatementEntry
+}
+
+// Count возвращает количество документов с указанным результатом обработки.
+func (result BankStatementImport) Count(status BankStatementStatus) int {
+	var count int
+	for _, entry := range result.Entries {
+		if entry.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (result BankStatementImport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "документов: %d, создано: %d, к созданию: %d, загружено ранее: %d, не найден контрагент: %d, ошибок: %d",
+		len(result.Entries),
+		result.Count(BankStatementStatusCreated),
+		result.Count(BankStatementStatusPlanned),
+		result.Count(BankStatementStatusDuplicate),
+		result.Count(BankStatementStatusUnmatched),
+		result.Count(BankStatementStatusFailed),
+	)
+	for _, entry := range result.Entries {
+		fmt.Fprintf(&sb, "\n%s: %s", entry.Document, entry.Status)
+		if entry.Counterparty != nil {
+			fmt.Fprintf(&sb, ", контрагент %s", entry.Counterparty.GetName())
+		}
+		if entry.Operation != nil {
+			fmt.Fprintf(&sb, ", документ %s", Deref(entry.Operation.Name))
+		}
+		if entry.Err != nil {
+			fmt.Fprintf(&sb, ", ошибка: %s", entry.Err)
+		}
+	}
+	return sb.String()
+}
+
+// BankStatementImporter загрузка банковской выписки во Входящие и Исходящие платежи.
+//
+// Для каждого документа выписки:
+//  1. По расчётному счёту получателя или плательщика определяется юрлицо и направление платежа.
+//  2. Контрагент находится по ИНН/КПП, счёт контрагента – по номеру расчётного счёта.
+//  3. Документы, загруженные ранее, пропускаются (см. [BankStatementDocument.ExternalCode]).
+//  4. Создаётся [PaymentIn] или [PaymentOut] и, при необходимости, привязывается к неоплаченному документу.
+type BankStatementImporter struct {
+	client         *Client
+	config         BankStatementImportConfig
+	accounts       map[string]bankStatementOwnAccount
+	counterparties map[string]bankStatementCounterparty
+	operations     map[string][]*bankStatementOperation
+}
+
+// bankStatementOwnAccount расчётный счёт юрлица.
+type bankStatementOwnAccount struct {
+	organization *Organization
+	account      *AgentAccount
+}
+
+// bankStatementCounterparty найденный контрагент и его расчётный счёт.
+type bankStatementCounterparty struct {
+	counterparty *Counterparty
+	account      *AgentAccount
+}
+
+// bankStatementOperation неоплаченный документ контрагента.
+type bankStatementOperation struct {
+	converter OperationInConverter
+	name      string
+	unpaid    float64
+}
+
+// NewBankStatementImporter возвращает [BankStatementImporter].
+func NewBankStatementImporter(client *Client, config BankStatementImportConfig) *BankStatementImporter {
+	return &BankStatementImporter{client: client, config: config}
+}
+
+// bankStatementCodesPerRequest количество внешних кодов в одном запросе поиска платежей.
+const bankStatementCodesPerRequest = 50
+
+// Run загружает документы банковской выписки.
+//
+// Ошибка создания отдельного платежа не прерывает загрузку и возвращается в поле Err соответствующего документа.
+func (importer *BankStatementImporter) Run(ctx context.Context, statement *BankStatement) (*BankStatementImport, error) {
+	if err := importer.loadAccounts(ctx); err != nil {
+		return nil, err
+	}
+
+	importer.counterparties = make(map[string]bankStatementCounterparty)
+	importer.operations = make(map[string][]*bankStatementOperation)
+
+	result := &BankStatementImport{Entries: make([]BankStatementEntry, 0, len(statement.Documents))}
+
+	var codes []string
+	for i := range statement.Documents {
+		document := &statement.Documents[i]
+		entry := BankStatementEntry{Document: document}
+
+		payer, isPayer := importer.accounts[document.PayerAccount]
+		recipient, isRecipient := importer.accounts[document.RecipientAccount]
+		switch {
+		case isPayer && isRecipient:
+			entry.Status = BankStatementStatusTransfer
+			entry.Organization = payer.organization
+		case isRecipient:
+			entry.Incoming = true
+			entry.Organization = recipient.organization
+			codes = append(codes, document.ExternalCode())
+		case isPayer:
+			entry.Organization = payer.organization
+			codes = append(codes, document.ExternalCode())
+		default:
+			entry.Status = BankStatementStatusForeign
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	existing, err := importer.existingCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Entries {
+		entry := &result.Entries[i]
+		if entry.Status != "" {
+			continue
+		}
+
+		if _, ok := existing[entry.Document.ExternalCode()]; ok {
+			entry.Status = BankStatementStatusDuplicate
+			continue
+		}
+
+		if err = importer.importEntry(ctx, entry); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// importEntry сопоставляет документ выписки с контрагентом и создаёт платёж.
+func (importer *BankStatementImporter) importEntry(ctx context.Context, entry *BankStatementEntry) error {
+	document := entry.Document
+
+	var own bankStatementOwnAccount
+	var inn, kpp, account string
+	if entry.Incoming {
+		own = importer.accounts[document.RecipientAccount]
+		inn, kpp, account = document.PayerINN, document.PayerKPP, document.PayerAccount
+	} else {
+		own = importer.accounts[document.PayerAccount]
+		inn, kpp, account = document.RecipientINN, document.RecipientKPP, document.RecipientAccount
+	}
+
+	agent, err := importer.findCounterparty(ctx, inn, kpp, account)
+	if err != nil {
+		return err
+	}
+	if agent.counterparty == nil {
+		entry.Status = BankStatementStatusUnmatched
+		return nil
+	}
+	entry.Counterparty = agent.counterparty
+
+	if !entry.Incoming && importer.config.ExpenseItem == nil {
+		entry.Status = BankStatementStatusFailed
+		entry.Err = fmt.Errorf("bank statement: expense item is required for outgoing payments")
+		return nil
+	}
+
+	var operation *Operation
+	if entry.Incoming && importer.config.LinkOperations {
+		if operation, err = importer.findOperation(ctx, agent.counterparty, document); err != nil {
+			return err
+		}
+		entry.Operation = operation
+	}
+
+	if entry.Incoming {
+		entry.PaymentIn = importer.newPaymentIn(document, own, agent, operation)
+	} else {
+		entry.PaymentOut = importer.newPaymentOut(document, own, agent)
+	}
+
+	if importer.config.DryRun {
+		entry.Status = BankStatementStatusPlanned
+		return nil
+	}
+
+	if entry.Incoming {
+		entry.PaymentIn, _, entry.Err = NewPaymentInService(importer.client).Create(ctx, entry.PaymentIn)
+	} else {
+		entry.PaymentOut, _, entry.Err = NewPaymentOutService(importer.client).Create(ctx, entry.PaymentOut)
+	}
+
+	entry.Status = BankStatementStatusCreated
+	if entry.Err != nil {
+		entry.Status = BankStatementStatusFailed
+	}
+
+	return nil
+}
+
+// newPaymentIn возвращает Входящий платеж по документу выписки.
+func (importer *BankStatementImporter) newPaymentIn(document *BankStatementDocument, own bankStatementOwnAccount, agent bankStatementCounterparty, operation *Operation) *PaymentIn {
+	paymentIn := new(PaymentIn).
+		SetOrganization(own.organization.Clean()).
+		SetOrganizationAccount(own.account.Clean()).
+		SetAgent(agent.counterparty.Clean()).
+		SetMoment(bankStatementMoment(document.CreditDate, document.Date)).
+		SetIncomingNumber(document.Number).
+		SetIncomingDate(document.Date).
+		SetPaymentPurpose(document.Purpose).
+		SetSum(document.Sum).
+		SetExternalCode(document.ExternalCode()).
+		SetApplicable(importer.config.Applicable)
+
+	if agent.account != nil {
+		paymentIn.SetAgentAccount(agent.account.Clean())
+	}
+	if operation != nil {
+		paymentIn.Operations.Push(operation)
+	}
+
+	return paymentIn
+}
+
+// newPaymentOut возвращает Исходящий платеж по документу выписки.
+func (importer *BankStatementImporter) newPaymentOut(document *BankStatementDocument, own bankStatementOwnAccount, agent bankStatementCounterparty) *PaymentOut {
+	paymentOut := new(PaymentOut).
+		SetOrganization(own.organization.Clean()).
+		SetOrganizationAccount(own.account.Clean()).
+		SetAgent(agent.counterparty.Clean()).
+		SetExpenseItem(importer.config.ExpenseItem.Clean()).
+		SetMoment(bankStatementMoment(document.DebitDate, document.Date)).
+		SetPaymentPurpose(document.Purpose).
+		SetSum(document.Sum).
+		SetDescription(fmt.Sprintf("%s № %s от %s", document.Kind, document.Number, document.Date.Format("02.01.2006"))).
+		SetExternalCode(document.ExternalCode()).
+		SetApplicable(importer.config.Applicable)
+
+	if agent.account != nil {
+		paymentOut.SetAgentAccount(agent.account.Clean())
+	}
+
+	return paymentOut
+}
+
+// bankStatementMoment возвращает дату проведения платежа: дату списания/поступления или дату документа.
+func bankStatementMoment(date, fallback time.Time) time.Time {
+	if date.IsZero() {
+		return fallback
+	}
+	return date
+}
+
+// loadAccounts загружает расчётные счета юрлица из конфигурации или всех юрлиц.
+func (importer *BankStatementImporter) loadAccounts(ctx context.Context) error {
+	service := NewOrganizationService(importer.client)
+
+	organizations := Slice[Organization]{importer.config.Organization}
+	if importer.config.Organization == nil {
+		rows, _, err := service.GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		organizations = Deref(rows)
+	}
+
+	importer.accounts = make(map[string]bankStatementOwnAccount)
+	for _, organization := range organizations {
+		accounts, _, err := service.GetAccountList(ctx, organization.GetID())
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts.Rows {
+			importer.accounts[account.GetAccountNumber()] = bankStatementOwnAccount{organization, account}
+		}
+	}
+
+	if len(importer.accounts) == 0 {
+		return fmt.Errorf("bank statement: organization has no accounts")
+	}
+
+	return nil
+}
+
+// existingCodes возвращает внешние коды платежей, которые уже существуют.
+func (importer *BankStatementImporter) existingCodes(ctx context.Context, codes []string) (map[string]struct{}, error) {
+	existing := make(map[string]struct{})
+
+	paymentInService := NewPaymentInService(importer.client)
+	paymentOutService := NewPaymentOutService(importer.client)
+
+	chunks := NewSliceFrom(codes)
+	for _, chunk := range chunks.IntoChunks(bankStatementCodesPerRequest) {
+		var params []func(*Params)
+		for _, code := range chunk {
+			params = append(params, WithFilterEquals("externalCode", *code))
+		}
+
+		paymentsIn, _, err := paymentInService.GetListAll(ctx, params...)
+		if err != nil {
+			return nil, err
+		}
+		for _, paymentIn := range Deref(paymentsIn) {
+			existing[paymentIn.GetExternalCode()] = struct{}{}
+		}
+
+		paymentsOut, _, err := paymentOutService.GetListAll(ctx, params...)
+		if err != nil {
+			return nil, err
+		}
+		for _, paymentOut := range Deref(paymentsOut) {
+			existing[paymentOut.GetExternalCode()] = struct{}{}
+		}
+	}
+
+	return existing, nil
+}
+
+// findCounterparty находит контрагента по ИНН/КПП и его счёт по номеру расчётного счёта.
+//
+// Если КПП указан, то предпочтение отдаётся контрагентам с совпадающим КПП.
+// Если у нескольких контрагентов совпадает ИНН, то выбирается тот, у которого есть указанный расчётный счёт.
+func (importer *BankStatementImporter) findCounterparty(ctx context.Context, inn, kpp, account string) (bankStatementCounterparty, error) {
+	key := inn + "/" + kpp + "/" + account
+	if found, ok := importer.counterparties[key]; ok {
+		return found, nil
+	}
+
+	var found bankStatementCounterparty
+	if strings.Trim(inn, "0") == "" {
+		importer.counterparties[key] = found
+		return found, nil
+	}
+
+	service := NewCounterpartyService(importer.client)
+	rows, _, err := service.GetListAll(ctx, WithFilterEquals("inn", inn))
+	if err != nil {
+		return found, err
+	}
+
+	candidates := Deref(rows)
+	if kpp != "" {
+		var sameKPP Slice[Counterparty]
+		for _, counterparty := range candidates {
+			if counterparty.GetKPP() == kpp {
+				sameKPP.Push(counterparty)
+			}
+		}
+		if sameKPP.Len() > 0 {
+			candidates = sameKPP
+		}
+	}
+
+	for _, counterparty := range candidates {
+		if found.counterparty == nil {
+			found.counterparty = counterparty
+		}
+		if account == "" {
+			break
+		}
+
+		accounts, _, err := service.GetAccountList(ctx, counterparty.GetID())
+		if err != nil {
+			return found, err
+		}
+		for _, agentAccount := range accounts.Rows {
+			if agentAccount.GetAccountNumber() == account {
+				found = bankStatementCounterparty{counterparty, agentAccount}
+				break
+			}
+		}
+		if found.account != nil {
+			break
+		}
+	}
+
+	importer.counterparties[key] = found
+	return found, nil
+}
+
+// findOperation находит неоплаченный документ контрагента для привязки входящего платежа.
+//
+// Счета покупателям имеют приоритет перед Заказами покупателей.
+func (importer *BankStatementImporter) findOperation(ctx context.Context, counterparty *Counterparty, document *BankStatementDocument) (*Operation, error) {
+	candidates, err := importer.openOperations(ctx, counterparty)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *bankStatementOperation
+	for _, candidate := range candidates {
+		if candidate.unpaid > 0 && containsNumber(document.Purpose, candidate.name) {
+			match = candidate
+			break
+		}
+	}
+
+	if match == nil {
+		var count int
+		for _, candidate := range candidates {
+			if candidate.unpaid == document.Sum {
+				match = candidate
+				count++
+			}
+		}
+		if count != 1 {
+			return nil, nil
+		}
+	}
+
+	linkedSum := math.Min(match.unpaid, document.Sum)
+	match.unpaid -= linkedSum
+
+	operation := match.converter.AsOperationIn()
+	operation.Name = String(match.name)
+	operation.LinkedSum = &linkedSum
+	return operation, nil
+}
+
+// openOperations возвращает проведённые неоплаченные Счета покупателям и Заказы покупателей контрагента.
+func (importer *BankStatementImporter) openOperations(ctx context.Context, counterparty *Counterparty) ([]*bankStatementOperation, error) {
+	href := counterparty.GetMeta().GetHref()
+	if operations, ok := importer.operations[href]; ok {
+		return operations, nil
+	}
+
+	params := []func(*Params){WithFilterEquals("agent", href), WithFilterEquals("applicable", "true")}
+
+	invoices, _, err := NewInvoiceOutService(importer.client).GetListAll(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, _, err := NewCustomerOrderService(importer.client).GetListAll(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []*bankStatementOperation
+	for _, invoice := range Deref(invoices) {
+		if unpaid := invoice.GetSum() - invoice.GetPayedSum(); unpaid > 0 {
+			operations = append(operations, &bankStatementOperation{invoice, invoice.GetName(), unpaid})
+		}
+	}
+	for _, order := range Deref(orders) {
+		if unpaid := order.GetSum() - order.GetPayedSum(); unpaid > 0 {
+			operations = append(operations, &bankStatementOperation{order, order.GetName(), unpaid})
+		}
+	}
+
+	importer.operations[href] = operations
+	return operations, nil
+}
+
+// containsNumber сообщает, содержит ли текст номер документа отдельным словом.
+func containsNumber(text, number string) bool {
+	if number == "" {
+		return false
+	}
+
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], number)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(number)
+
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWord(before)) && (end == len(text) || !isWord(after)) {
+			return true
+		}
+		offset = start + 1
+	}
+}
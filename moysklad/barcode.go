@@ -0,0 +1,330 @@
+package moysklad
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BarcodeCheckDigit вычисляет контрольную цифру GS1 (EAN-8, EAN-13, UPC, GTIN) для последовательности цифр без контрольной цифры.
+func BarcodeCheckDigit(digits string) (int, error) {
+	if digits == "" || !isDigits(digits) {
+		return 0, fmt.Errorf("barcode: %q is not a digit sequence", digits)
+	}
+	var sum int
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10, nil
+}
+
+// AppendBarcodeCheckDigit возвращает последовательность цифр с добавленной контрольной цифрой GS1.
+func AppendBarcodeCheckDigit(digits string) (string, error) {
+	check, err := BarcodeCheckDigit(digits)
+	if err != nil {
+		return "", err
+	}
+	return digits + strconv.Itoa(check), nil
+}
+
+// barcodeLengths допустимые длины штрихкодов GS1 по типам.
+var barcodeLengths = map[BarcodeType][]int{
+	BarcodeEAN13: {13},
+	BarcodeEAN8:  {8},
+	BarcodeUPC:   {12},
+	BarcodeGTIN:  {8, 12, 13, 14},
+}
+
+// Validate проверяет значение штрихкода на соответствие типу.
+//
+// Для EAN-13, EAN-8, UPC и GTIN проверяются длина и контрольная цифра,
+// для Code128 – допустимость символов (печатные символы ASCII).
+func (barcode Barcode) Validate() error {
+	value := barcode.Value
+	if value == "" {
+		return fmt.Errorf("barcode: empty %s value", barcode.Type)
+	}
+
+	if barcode.Type == BarcodeCode128 {
+		for i := 0; i < len(value); i++ {
+			if value[i] < ' ' || value[i] > '~' {
+				return fmt.Errorf("barcode: code128 %q contains unsupported character at %d", value, i)
+			}
+		}
+		return nil
+	}
+
+	lengths, ok := barcodeLengths[barcode.Type]
+	if !ok {
+		return fmt.Errorf("barcode: unknown type %q", barcode.Type)
+	}
+	if !isDigits(value) {
+		return fmt.Errorf("barcode: %s %q must contain only digits", barcode.Type, value)
+	}
+
+	var validLength bool
+	for _, length := range lengths {
+		validLength = validLength || len(value) == length
+	}
+	if !validLength {
+		return fmt.Errorf("barcode: %s %q has invalid length %d", barcode.Type, value, len(value))
+	}
+
+	check, _ := BarcodeCheckDigit(value[:len(value)-1])
+	if int(value[len(value)-1]-'0') != check {
+		return fmt.Errorf("barcode: %s %q has invalid check digit, expected %d", barcode.Type, value, check)
+	}
+	return nil
+}
+
+// IsValid возвращает true, если значение штрихкода соответствует типу.
+func (barcode Barcode) IsValid() bool {
+	return barcode.Validate() == nil
+}
+
+// NewBarcode возвращает [Barcode] указанного типа, предварительно проверив значение.
+func NewBarcode(barcodeType BarcodeType, value string) (*Barcode, error) {
+	barcode := &Barcode{barcodeType, value}
+	if err := barcode.Validate(); err != nil {
+		return nil, err
+	}
+	return barcode, nil
+}
+
+// DetectBarcode определяет тип штрихкода по значению.
+//
+// Цифровые значения длиной 8, 12, 13 и 14 с верной контрольной цифрой считаются
+// EAN-8, UPC, EAN-13 и GTIN соответственно, остальные – Code128.
+func DetectBarcode(value string) *Barcode {
+	value = strings.TrimSpace(value)
+	var barcodeType BarcodeType
+	switch len(value) {
+	case 13:
+		barcodeType = BarcodeEAN13
+	case 8:
+		barcodeType = BarcodeEAN8
+	case 12:
+		barcodeType = BarcodeUPC
+	case 14:
+		barcodeType = BarcodeGTIN
+	}
+	if barcodeType != "" {
+		if barcode := (Barcode{barcodeType, value}); barcode.IsValid() {
+			return &barcode
+		}
+	}
+	return NewBarcodeCode128(value)
+}
+
+// WeightBarcode Штрихкод весового товара.
+//
+// Штрихкод формата EAN-13 печатается весами и состоит из префикса весовых товаров ([BarcodeRules.WeightBarcodePrefix]),
+// кода товара (дополняющего префикс до 7 цифр), веса в граммах (5 цифр) и контрольной цифры.
+type WeightBarcode struct {
+	Prefix string  // Префикс штрихкодов для весовых товаров
+	Code   string  // Код весового товара
+	Weight float64 // Вес в килограммах
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (weightBarcode WeightBarcode) String() string {
+	return Stringify(weightBarcode)
+}
+
+// weightBarcodePrefix возвращает префикс штрихкодов для весовых товаров,
+// если их использование включено в настройках.
+func (barcodeRules BarcodeRules) weightBarcodePrefix() (string, bool) {
+	prefix := barcodeRules.GetWeightBarcodePrefix()
+	if !barcodeRules.GetWeightBarcode() || prefix < 0 || prefix > 99 {
+		return "", false
+	}
+	return strconv.Itoa(prefix), true
+}
+
+// IsWeightBarcode возвращает true, если значение является штрихкодом весового товара согласно настройкам.
+func (barcodeRules BarcodeRules) IsWeightBarcode(value string) bool {
+	prefix, ok := barcodeRules.weightBarcodePrefix()
+	return ok && strings.HasPrefix(value, prefix) && NewBarcodeEAN13(value).IsValid()
+}
+
+// ParseWeightBarcode разбирает штрихкод весового товара на код товара и вес согласно настройкам.
+func (barcodeRules BarcodeRules) ParseWeightBarcode(value string) (*WeightBarcode, error) {
+	prefix, ok := barcodeRules.weightBarcodePrefix()
+	if !ok {
+		return nil, fmt.Errorf("barcode: weight barcodes are disabled")
+	}
+	value = strings.TrimSpace(value)
+	if err := NewBarcodeEAN13(value).Validate(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(value, prefix) {
+		return nil, fmt.Errorf("barcode: %q does not start with weight prefix %s", value, prefix)
+	}
+
+	grams, _ := strconv.Atoi(value[7:12])
+	return &WeightBarcode{
+		Prefix: prefix,
+		Code:   value[len(prefix):7],
+		Weight: float64(grams) / 1000,
+	}, nil
+}
+
+// NewWeightBarcode формирует штрихкод весового товара с кодом code и весом weight (в килограммах) согласно настройкам.
+func (barcodeRules BarcodeRules) NewWeightBarcode(code string, weight float64) (*Barcode, error) {
+	prefix, ok := barcodeRules.weightBarcodePrefix()
+	if !ok {
+		return nil, fmt.Errorf("barcode: weight barcodes are disabled")
+	}
+	width := 7 - len(prefix)
+	if code == "" || len(code) > width || !isDigits(code) {
+		return nil, fmt.Errorf("barcode: weight product code %q must contain up to %d digits", code, width)
+	}
+	grams := math.Round(weight * 1000)
+	if grams < 0 || grams > 99999 {
+		return nil, fmt.Errorf("barcode: weight %g is out of range", weight)
+	}
+
+	value, _ := AppendBarcodeCheckDigit(fmt.Sprintf("%s%s%s%05d", prefix, strings.Repeat("0", width-len(code)), code, int(grams)))
+	return NewBarcodeEAN13(value), nil
+}
+
+// barcodesPerRequest количество штрихкодов в одном запросе проверки на уникальность.
+const barcodesPerRequest = 50
+
+// BarcodeGenerator генератор внутренних штрихкодов EAN-13.
+//
+// Штрихкоды формируются в диапазоне внутренних кодов GS1 (префиксы 20–29, а если весь диапазон 2
+// занят весовыми товарами – 04) с учётом [BarcodeRules] аккаунта: диапазон префикса весовых товаров не используется.
+// Перед выдачей штрихкоды проверяются на отсутствие в ассортименте (включая архивные позиции)
+// и не повторяются в рамках одного генератора.
+//
+// Если в настройках включено [BarcodeRules.FillEAN13Barcode], то МойСклад сам создаёт штрихкод
+// для новых позиций; генератор нужен для заполнения существующих позиций и позиций без штрихкодов.
+type BarcodeGenerator struct {
+	client *Client
+	rules  *BarcodeRules
+	prefix string
+	issued map[string]struct{}
+	mu     sync.Mutex
+}
+
+// NewBarcodeGenerator возвращает [BarcodeGenerator].
+//
+// Настройки штрихкодов загружаются при первой генерации.
+func NewBarcodeGenerator(client *Client) *BarcodeGenerator {
+	return &BarcodeGenerator{client: client, issued: make(map[string]struct{})}
+}
+
+// Rules возвращает настройки правил штрихкодов аккаунта.
+func (generator *BarcodeGenerator) Rules(ctx context.Context) (BarcodeRules, error) {
+	generator.mu.Lock()
+	defer generator.mu.Unlock()
+
+	if err := generator.init(ctx); err != nil {
+		return BarcodeRules{}, err
+	}
+	return *generator.rules, nil
+}
+
+// init загружает настройки и выбирает префикс внутренних штрихкодов.
+func (generator *BarcodeGenerator) init(ctx context.Context) error {
+	if generator.rules != nil {
+		return nil
+	}
+
+	settings, _, err := NewAssortmentService(generator.client).GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+	rules := settings.GetBarcodeRules()
+
+	generator.prefix = "04"
+	weightPrefix, weight := rules.weightBarcodePrefix()
+	for d := 0; d <= 9; d++ {
+		prefix := "2" + strconv.Itoa(d)
+		if !weight || !strings.HasPrefix(prefix, weightPrefix) {
+			generator.prefix = prefix
+			break
+		}
+	}
+	generator.rules = &rules
+	return nil
+}
+
+// Next возвращает новый уникальный штрихкод EAN-13.
+func (generator *BarcodeGenerator) Next(ctx context.Context) (*Barcode, error) {
+	barcodes, err := generator.NextMany(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return (*barcodes)[0], nil
+}
+
+// NextMany возвращает count новых уникальных штрихкодов EAN-13.
+func (generator *BarcodeGenerator) NextMany(ctx context.Context, count int) (*Slice[Barcode], error) {
+	generator.mu.Lock()
+	defer generator.mu.Unlock()
+
+	if err := generator.init(ctx); err != nil {
+		return nil, err
+	}
+
+	service := NewAssortmentService(generator.client)
+	limit := big.NewInt(int64(math.Pow10(12 - len(generator.prefix))))
+	barcodes := NewSlice[Barcode]()
+	for barcodes.Len() < count {
+		var candidates []string
+		for len(candidates) < min(count-barcodes.Len(), barcodesPerRequest) {
+			n, err := rand.Int(rand.Reader, limit)
+			if err != nil {
+				return nil, err
+			}
+			value, _ := AppendBarcodeCheckDigit(fmt.Sprintf("%s%0*d", generator.prefix, 12-len(generator.prefix), n))
+			if _, ok := generator.issued[value]; !ok {
+				generator.issued[value] = struct{}{}
+				candidates = append(candidates, value)
+			}
+		}
+
+		params := []func(*Params){WithFilterEquals("archived", "true"), WithFilterEquals("archived", "false")}
+		for _, candidate := range candidates {
+			params = append(params, WithFilterEquals("barcode", candidate))
+		}
+		rows, _, err := service.GetListAll(ctx, params...)
+		if err != nil {
+			return nil, err
+		}
+
+		used := make(map[string]struct{})
+		for _, assortment := range rows {
+			for barcode := range assortmentBarcodes(assortment, nil) {
+				used[barcode] = struct{}{}
+			}
+		}
+		for _, candidate := range candidates {
+			if _, ok := used[candidate]; !ok {
+				barcodes.Push(NewBarcodeEAN13(candidate))
+			}
+		}
+	}
+	return &barcodes, nil
+}
+
+// isDigits возвращает true, если строка состоит только из цифр.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+package moysklad
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// BarcodeRenderOptions параметры отрисовки штрихкода.
+type BarcodeRenderOptions struct {
+	ModuleWidth int  // Ширина модуля (самой узкой полосы) в пикселях, по умолчанию 2
+	Height      int  // Высота полос в пикселях, по умолчанию 80
+	QuietZone   int  // Ширина свободной зоны слева и справа в модулях, по умолчанию 10
+	Text        bool // Выводить значение штрихкода под полосами (только SVG)
+}
+
+// withDefaults возвращает параметры с заполненными значениями по умолчанию.
+func (options BarcodeRenderOptions) withDefaults() BarcodeRenderOptions {
+	if options.ModuleWidth <= 0 {
+		options.ModuleWidth = 2
+	}
+	if options.Height <= 0 {
+		options.Height = 80
+	}
+	if options.QuietZone <= 0 {
+		options.QuietZone = 10
+	}
+	return options
+}
+
+// Modules возвращает последовательность модулей штрихкода (true – полоса, false – пробел) без свободных зон.
+//
+// EAN-13, EAN-8, UPC-A и GTIN-8/12/13 кодируются соответствующими символиками EAN/UPC,
+// GTIN-14 – символикой ITF-14, Code128 – наборами B или C.
+func (barcode Barcode) Modules() ([]bool, error) {
+	if err := barcode.Validate(); err != nil {
+		return nil, err
+	}
+
+	value := barcode.Value
+	switch barcode.Type {
+	case BarcodeCode128:
+		return code128Modules(value), nil
+	case BarcodeUPC:
+		return ean13Modules("0" + value), nil
+	}
+
+	switch len(value) {
+	case 8:
+		return ean8Modules(value), nil
+	case 12:
+		return ean13Modules("0" + value), nil
+	case 14:
+		return itfModules(value), nil
+	}
+	return ean13Modules(value), nil
+}
+
+// Image возвращает изображение штрихкода.
+func (barcode Barcode) Image(options BarcodeRenderOptions) (image.Image, error) {
+	modules, err := barcode.Modules()
+	if err != nil {
+		return nil, err
+	}
+	options = options.withDefaults()
+
+	width := (len(modules) + 2*options.QuietZone) * options.ModuleWidth
+	img := image.NewGray(image.Rect(0, 0, width, options.Height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for i, bar := range modules {
+		if !bar {
+			continue
+		}
+		x0 := (options.QuietZone + i) * options.ModuleWidth
+		for y := 0; y < options.Height; y++ {
+			for x := x0; x < x0+options.ModuleWidth; x++ {
+				img.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	return img, nil
+}
+
+// WritePNG записывает изображение штрихкода в формате PNG.
+func (barcode Barcode) WritePNG(w io.Writer, options BarcodeRenderOptions) error {
+	img, err := barcode.Image(options)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// WriteSVG записывает изображение штрихкода в формате SVG.
+func (barcode Barcode) WriteSVG(w io.Writer, options BarcodeRenderOptions) error {
+	modules, err := barcode.Modules()
+	if err != nil {
+		return err
+	}
+	options = options.withDefaults()
+
+	width := (len(modules) + 2*options.QuietZone) * options.ModuleWidth
+	height := options.Height
+	fontSize := 6 * options.ModuleWidth
+	if options.Text {
+		height += fontSize + options.ModuleWidth*2
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#fff"/>`, width, height)
+
+	// соседние модули полосы объединяются в один прямоугольник
+	var path strings.Builder
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(modules) && modules[j] {
+			j++
+		}
+		fmt.Fprintf(&path, "M%d 0h%dv%dh-%dz", (options.QuietZone+i)*options.ModuleWidth, (j-i)*options.ModuleWidth, options.Height, (j-i)*options.ModuleWidth)
+		i = j
+	}
+	fmt.Fprintf(bw, `<path d="%s" fill="#000"/>`, path.String())
+
+	if options.Text {
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="middle">%s</text>`,
+			width/2, options.Height+options.ModuleWidth+fontSize, fontSize, html.EscapeString(barcode.Value))
+	}
+	fmt.Fprint(bw, `</svg>`)
+	return bw.Flush()
+}
+
+// eanLeft кодировки цифр левой половины EAN/UPC с нечётной чётностью (набор L).
+var eanLeft = [10]string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+
+// ean13Parity чётность цифр левой половины EAN-13 в зависимости от первой цифры (L – нечётная, G – чётная).
+var ean13Parity = [10]string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+
+// eanDigit возвращает модули цифры EAN в наборе L, G или R.
+func eanDigit(digit byte, set byte) string {
+	pattern := eanLeft[digit-'0']
+	if set == 'L' {
+		return pattern
+	}
+
+	// R – инверсия L, G – R в обратном порядке
+	inverted := make([]byte, len(pattern))
+	for i := range pattern {
+		inverted[i] = '0' + '1' - pattern[i]
+	}
+	if set == 'G' {
+		for i, j := 0, len(inverted)-1; i < j; i, j = i+1, j-1 {
+			inverted[i], inverted[j] = inverted[j], inverted[i]
+		}
+	}
+	return string(inverted)
+}
+
+// ean13Modules возвращает модули штрихкода EAN-13.
+func ean13Modules(value string) []bool {
+	var sb strings.Builder
+	sb.WriteString("101")
+	parity := ean13Parity[value[0]-'0']
+	for i := 1; i <= 6; i++ {
+		sb.WriteString(eanDigit(value[i], parity[i-1]))
+	}
+	sb.WriteString("01010")
+	for i := 7; i <= 12; i++ {
+		sb.WriteString(eanDigit(value[i], 'R'))
+	}
+	sb.WriteString("101")
+	return barcodeBits(sb.String())
+}
+
+// ean8Modules возвращает модули штрихкода EAN-8.
+func ean8Modules(value string) []bool {
+	var sb strings.Builder
+	sb.WriteString("101")
+	for i := 0; i < 4; i++ {
+		sb.WriteString(eanDigit(value[i], 'L'))
+	}
+	sb.WriteString("01010")
+	for i := 4; i < 8; i++ {
+		sb.WriteString(eanDigit(value[i], 'R'))
+	}
+	sb.WriteString("101")
+	return barcodeBits(sb.String())
+}
+
+// itfDigits ширины элементов цифр Interleaved 2 of 5 (n – узкий, w – широкий).
+var itfDigits = [10]string{"nnwwn", "wnnnw", "nwnnw", "wwnnn", "nnwnw", "wnwnn", "nwwnn", "nnnww", "wnnwn", "nwnwn"}
+
+// itfModules возвращает модули штрихкода ITF-14.
+func itfModules(value string) []bool {
+	widths := "nnnn"
+	for i := 0; i+1 < len(value); i += 2 {
+		bars, spaces := itfDigits[value[i]-'0'], itfDigits[value[i+1]-'0']
+		for j := 0; j < 5; j++ {
+			widths += string(bars[j]) + string(spaces[j])
+		}
+	}
+	widths += "wnn"
+
+	var modules []bool
+	for i := 0; i < len(widths); i++ {
+		width := 1
+		if widths[i] == 'w' {
+			width = 3
+		}
+		for j := 0; j < width; j++ {
+			modules = append(modules, i%2 == 0)
+		}
+	}
+	return modules
+}
+
+// code128Patterns ширины полос и пробелов символов Code128 (значения 0–105 и стоп-символ 106).
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+// Служебные символы Code128.
+const (
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// code128Modules возвращает модули штрихкода Code128.
+//
+// Строки из чётного количества цифр кодируются набором C, остальные – набором B.
+func code128Modules(value string) []bool {
+	var symbols []int
+	if len(value)%2 == 0 && isDigits(value) {
+		symbols = append(symbols, code128StartC)
+		for i := 0; i < len(value); i += 2 {
+			symbols = append(symbols, int(value[i]-'0')*10+int(value[i+1]-'0'))
+		}
+	} else {
+		symbols = append(symbols, code128StartB)
+		for i := 0; i < len(value); i++ {
+			symbols = append(symbols, int(value[i])-' ')
+		}
+	}
+
+	checksum := symbols[0]
+	for i := 1; i < len(symbols); i++ {
+		checksum += i * symbols[i]
+	}
+	symbols = append(symbols, checksum%103, code128Stop)
+
+	var modules []bool
+	for _, symbol := range symbols {
+		pattern := code128Patterns[symbol]
+		for i := 0; i < len(pattern); i++ {
+			for j := 0; j < int(pattern[i]-'0'); j++ {
+				modules = append(modules, i%2 == 0)
+			}
+		}
+	}
+	return modules
+}
+
+// barcodeBits преобразует строку из '0' и '1' в модули.
+func barcodeBits(bits string) []bool {
+	modules := make([]bool, len(bits))
+	for i := range bits {
+		modules[i] = bits[i] == '1'
+	}
+	return modules
+}
@@ -0,0 +1,282 @@
+package moysklad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// BundleAvailabilityConfig конфигурация расчёта доступности комплектов.
+type BundleAvailabilityConfig struct {
+	// Тип остатка компонентов (по умолчанию [StockFreeStock] – остаток за вычетом резерва).
+	StockType StockType
+
+	// Склады, по которым рассчитывается доступность (по умолчанию все склады).
+	Stores []*Store
+}
+
+// BundleAvailability Количество комплектов, которое можно собрать на складе.
+type BundleAvailability struct {
+	Bundle        *Bundle          // Комплект
+	Store         *Store           // Склад
+	Quantity      float64          // Количество комплектов, которое можно собрать из остатков компонентов
+	Limiting      *BundleComponent // Компонент, ограничивающий количество (количество указано на один комплект с учётом вложенности)
+	LimitingStock float64          // Остаток ограничивающего компонента на складе
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (bundleAvailability BundleAvailability) String() string {
+	return Stringify(bundleAvailability)
+}
+
+// BundleAvailabilities Результат расчёта доступности комплектов.
+type BundleAvailabilities []*BundleAvailability
+
+// Totals возвращает количество комплектов по всем складам. Ключ – ID комплекта.
+func (bundleAvailabilities BundleAvailabilities) Totals() map[string]float64 {
+	totals := make(map[string]float64)
+	for _, availability := range bundleAvailabilities {
+		totals[availability.Bundle.GetID()] += availability.Quantity
+	}
+	return totals
+}
+
+// ByStore возвращает количество комплектов по складам. Ключи – ID комплекта и ID склада.
+func (bundleAvailabilities BundleAvailabilities) ByStore() map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+	for _, availability := range bundleAvailabilities {
+		bundleID := availability.Bundle.GetID()
+		if result[bundleID] == nil {
+			result[bundleID] = make(map[string]float64)
+		}
+		result[bundleID][availability.Store.GetID()] = availability.Quantity
+	}
+	return result
+}
+
+// BundleAvailabilityCalculator расчёт количества комплектов, которое можно собрать из остатков компонентов.
+//
+// Вложенные комплекты раскрываются до товаров, модификаций и серий с перемножением количеств.
+// Услуги в составе комплекта не ограничивают его доступность.
+//
+// Пример:
+//
+//	calculator := moysklad.NewBundleAvailabilityCalculator(client, moysklad.BundleAvailabilityConfig{})
+//	result, err := calculator.CalculateAll(ctx)
+//	_, err = calculator.UpdateAttribute(ctx, result, "Доступно комплектов")
+type BundleAvailabilityCalculator struct {
+	client     *Client
+	config     BundleAvailabilityConfig
+	components map[string][]*BundleComponent
+}
+
+// NewBundleAvailabilityCalculator возвращает [BundleAvailabilityCalculator].
+func NewBundleAvailabilityCalculator(client *Client, config BundleAvailabilityConfig) *BundleAvailabilityCalculator {
+	if config.StockType == "" {
+		config.StockType = StockFreeStock
+	}
+	return &BundleAvailabilityCalculator{client: client, config: config, components: make(map[string][]*BundleComponent)}
+}
+
+// CalculateAll рассчитывает доступность всех комплектов, подходящих под параметры запроса params.
+func (calculator *BundleAvailabilityCalculator) CalculateAll(ctx context.Context, params ...func(*Params)) (BundleAvailabilities, error) {
+	bundles, _, err := NewBundleService(calculator.client).GetListAll(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+	return calculator.Calculate(ctx, Deref(bundles)...)
+}
+
+// Calculate рассчитывает доступность комплектов bundles по складам.
+//
+// Отчёт об остатках запрашивается при каждом вызове. Для расчёта нескольких порций комплектов
+// по одним и тем же остаткам используйте [BundleAvailabilityCalculator.CalculateWithStock].
+func (calculator *BundleAvailabilityCalculator) Calculate(ctx context.Context, bundles ...*Bundle) (BundleAvailabilities, error) {
+	rows, _, err := NewReportStockService(calculator.client).GetCurrentByStore(ctx, WithStockType(calculator.config.StockType))
+	if err != nil {
+		return nil, err
+	}
+	return calculator.CalculateWithStock(ctx, Deref(rows), bundles...)
+}
+
+// CalculateWithStock рассчитывает доступность комплектов bundles по складам по ранее полученному
+// отчёту об остатках по складам rows.
+//
+// Отчёт должен быть получен с типом остатка [BundleAvailabilityConfig.StockType].
+func (calculator *BundleAvailabilityCalculator) CalculateWithStock(ctx context.Context, rows Slice[StockCurrentByStore], bundles ...*Bundle) (BundleAvailabilities, error) {
+	stores := calculator.config.Stores
+	if len(stores) == 0 {
+		list, _, err := NewStoreService(calculator.client).GetListAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stores = Deref(list)
+	}
+
+	stock := make(map[string]map[string]float64)
+	for _, row := range rows {
+		if stock[row.StoreID] == nil {
+			stock[row.StoreID] = make(map[string]float64)
+		}
+		stock[row.StoreID][row.AssortmentID] += stockValue(row, calculator.config.StockType)
+	}
+
+	var result BundleAvailabilities
+	for _, bundle := range bundles {
+		components, err := calculator.Components(ctx, bundle.GetID())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, store := range stores {
+			availability := &BundleAvailability{Bundle: bundle, Store: store}
+			for _, component := range components {
+				quantity := component.GetQuantity()
+				if quantity <= 0 {
+					continue
+				}
+				current := stock[store.GetID()][component.GetAssortment().GetMeta().GetUUIDFromHref()]
+				assemblable := math.Max(0, math.Floor(current/quantity+1e-9))
+				if availability.Limiting == nil || assemblable < availability.Quantity {
+					availability.Quantity = assemblable
+					availability.Limiting = component
+					availability.LimitingStock = current
+				}
+			}
+			result = append(result, availability)
+		}
+	}
+	return result, nil
+}
+
+// Components возвращает компоненты комплекта с раскрытыми вложенными комплектами.
+//
+// Количество каждого компонента указано на один комплект; услуги не включаются.
+func (calculator *BundleAvailabilityCalculator) Components(ctx context.Context, bundleID string) ([]*BundleComponent, error) {
+	return calculator.expand(ctx, bundleID, make(map[string]bool))
+}
+
+// expand раскрывает компоненты комплекта, path – комплекты текущей ветви для обнаружения циклов.
+func (calculator *BundleAvailabilityCalculator) expand(ctx context.Context, bundleID string, path map[string]bool) ([]*BundleComponent, error) {
+	if components, ok := calculator.components[bundleID]; ok {
+		return components, nil
+	}
+	if path[bundleID] {
+		return nil, fmt.Errorf("bundle availability: bundle %s contains itself", bundleID)
+	}
+	path[bundleID] = true
+	defer delete(path, bundleID)
+
+	list, _, err := NewBundleService(calculator.client).GetComponentList(ctx, bundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		components []*BundleComponent
+		index      = make(map[string]*BundleComponent)
+	)
+	add := func(assortment *AssortmentPosition, quantity float64) {
+		id := assortment.GetMeta().GetUUIDFromHref()
+		if component, ok := index[id]; ok {
+			*component.Quantity += quantity
+			return
+		}
+		component := &BundleComponent{Assortment: assortment, Quantity: Float(quantity)}
+		index[id] = component
+		components = append(components, component)
+	}
+
+	for _, component := range list.Rows {
+		assortment := component.Assortment
+		if assortment == nil {
+			continue
+		}
+		switch assortment.GetMeta().GetType() {
+		case MetaTypeService:
+			continue
+		case MetaTypeBundle:
+			nested, err := calculator.expand(ctx, assortment.GetMeta().GetUUIDFromHref(), path)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range nested {
+				add(child.Assortment, child.GetQuantity()*component.GetQuantity())
+			}
+		default:
+			add(assortment, component.GetQuantity())
+		}
+	}
+
+	calculator.components[bundleID] = components
+	return components, nil
+}
+
+// UpdateAttribute записывает количество комплектов по всем складам результата в доп. поле комплектов с наименованием name.
+//
+// Доп. поле должно иметь числовой тип. Возвращает количество обновлённых комплектов;
+// если часть комплектов не обновлена, то возвращается ошибка с описанием по каждому из них.
+func (calculator *BundleAvailabilityCalculator) UpdateAttribute(ctx context.Context, result BundleAvailabilities, name string) (int, error) {
+	service := NewBundleService(calculator.client)
+	attributes, _, err := service.GetAttributeList(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var attribute *Attribute
+	for _, row := range attributes.Rows {
+		if row.GetName() == name {
+			attribute = row
+			break
+		}
+	}
+	if attribute == nil {
+		return 0, fmt.Errorf("bundle availability: attribute %q not found", name)
+	}
+	if attributeType := attribute.GetType(); attributeType != AttributeTypeLong && attributeType != AttributeTypeDouble {
+		return 0, fmt.Errorf("bundle availability: attribute %q must be numeric, got %s", name, attributeType)
+	}
+
+	totals := result.Totals()
+	bundles := NewSlice[Bundle]()
+	seen := make(map[string]bool)
+	for _, availability := range result {
+		bundleID := availability.Bundle.GetID()
+		if seen[bundleID] {
+			continue
+		}
+		seen[bundleID] = true
+
+		var value any = totals[bundleID]
+		if attribute.GetType() == AttributeTypeLong {
+			value = int64(totals[bundleID])
+		}
+		meta := availability.Bundle.GetMeta()
+		bundles.Push(new(Bundle).SetMeta(&meta).SetAttributes(&Attribute{Meta: attribute.Meta, Type: attribute.Type, Value: NewNullValueAnyFrom(value)}))
+	}
+	updated, failed, err := repriceUpdate[Bundle](ctx, service, bundles)
+	if err == nil && len(failed) > 0 {
+		errs := make([]error, 0, len(failed))
+		for _, failure := range failed {
+			errs = append(errs, failure)
+		}
+		err = fmt.Errorf("bundle availability: %w", errors.Join(errs...))
+	}
+	return updated, err
+}
+
+// stockValue возвращает значение остатка строки отчёта по типу остатка.
+func stockValue(row *StockCurrentByStore, stockType StockType) float64 {
+	switch stockType {
+	case StockFreeStock:
+		return row.FreeStock
+	case StockQuantity:
+		return row.Quantity
+	case StockReserve:
+		return row.Reserve
+	case StockInTransit:
+		return row.InTransit
+	}
+	return row.Stock
+}
@@ -3,6 +3,7 @@ package moysklad
 import (
 	"context"
 	"github.com/go-resty/resty/v2"
+	"io"
 
 	"time"
 )
@@ -169,6 +170,7 @@ func (cashOut CashOut) GetMoment() time.Time {
 //   - InvoiceIn (Счет поставщика)
 //   - PurchaseOrder (Заказ поставщику)
 //   - CommissionReportOut (Выданный отчет комиссионера)
+//   - Payroll (Начисление зарплаты)
 func (cashOut CashOut) GetOperations() Operations {
 	return cashOut.Operations
 }
@@ -355,6 +357,7 @@ func (cashOut *CashOut) SetMoment(moment time.Time) *CashOut {
 //   - InvoiceIn (Счет поставщика)
 //   - PurchaseOrder (Заказ поставщику)
 //   - CommissionReportOut (Выданный отчет комиссионера)
+//   - Payroll (Начисление зарплаты)
 //
 // Принимает множество объектов, реализующих интерфейс [OperationOutConverter].
 func (cashOut *CashOut) SetOperations(operations ...OperationOutConverter) *CashOut {
@@ -652,6 +655,9 @@ type CashOutService interface {
 	// Возвращает список файлов.
 	CreateFile(ctx context.Context, id string, file *File) (*Slice[File], *resty.Response, error)
 
+	// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+	UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error)
+
 	// UpdateFileMany выполняет запрос на массовое создание и/или изменение файлов сущности/документа.
 	// Принимает контекст, ID сущности/документа и множество файлов.
 	// Возвращает созданных и/или изменённых файлов.
@@ -0,0 +1,136 @@
+package moysklad
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cp1251High символы кодировки Windows-1251 в диапазоне 0x80–0xBF.
+//
+// Диапазон 0xC0–0xFF соответствует буквам А–я (U+0410–U+044F).
+var cp1251High = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', utf8.RuneError, '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00a0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00ad', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+// decodeCP1251 преобразует текст в кодировке Windows-1251 в UTF-8.
+func decodeCP1251(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data) * 2)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b < 0xC0:
+			sb.WriteRune(cp1251High[b-0x80])
+		default:
+			sb.WriteRune(rune(b-0xC0) + 'А')
+		}
+	}
+	return sb.String()
+}
+
+// cp1251Index обратная таблица [cp1251High].
+var cp1251Index = func() map[rune]byte {
+	index := make(map[rune]byte, len(cp1251High))
+	for i, r := range cp1251High {
+		if r != utf8.RuneError {
+			index[r] = byte(i) + 0x80
+		}
+	}
+	return index
+}()
+
+// encodeCP1251XML преобразует текст XML в кодировку Windows-1251.
+//
+// Символы, отсутствующие в кодировке, заменяются числовыми ссылками на символы (&#N;).
+func encodeCP1251XML(s string) []byte {
+	data := make([]byte, 0, len(s))
+	for _, r := range s {
+		if b, ok := cp1251Index[r]; ok {
+			data = append(data, b)
+			continue
+		}
+		switch {
+		case r < 0x80:
+			data = append(data, byte(r))
+		case r >= 'А' && r <= 'я':
+			data = append(data, byte(r-'А')+0xC0)
+		default:
+			data = append(data, "&#"+strconv.Itoa(int(r))+";"...)
+		}
+	}
+	return data
+}
+
+// decodeCP866 преобразует текст в кодировке DOS (CP866) в UTF-8.
+//
+// Символы псевдографики заменяются на [utf8.RuneError].
+func decodeCP866(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data) * 2)
+	for _, b := range data {
+		switch {
+		case b < 0x80:
+			sb.WriteByte(b)
+		case b < 0xB0:
+			sb.WriteRune(rune(b-0x80) + 'А')
+		case b >= 0xE0 && b < 0xF0:
+			sb.WriteRune(rune(b-0xE0) + 'р')
+		case b == 0xF0:
+			sb.WriteRune('Ё')
+		case b == 0xF1:
+			sb.WriteRune('ё')
+		case b == 0xFC:
+			sb.WriteRune('№')
+		case b == 0xFF:
+			sb.WriteRune('\u00a0')
+		default:
+			sb.WriteRune(utf8.RuneError)
+		}
+	}
+	return sb.String()
+}
+
+// cp1251Reader преобразует поток в кодировке Windows-1251 в UTF-8.
+type cp1251Reader struct {
+	r       io.Reader
+	buf     []byte
+	pending []byte
+}
+
+// newCP1251Reader возвращает [io.Reader], преобразующий поток r из кодировки Windows-1251 в UTF-8.
+func newCP1251Reader(r io.Reader) io.Reader {
+	return &cp1251Reader{r: r, buf: make([]byte, 4096)}
+}
+
+// Read реализует интерфейс [io.Reader].
+func (reader *cp1251Reader) Read(p []byte) (int, error) {
+	if len(reader.pending) == 0 {
+		n, err := reader.r.Read(reader.buf)
+		if n == 0 {
+			return 0, err
+		}
+		reader.pending = []byte(decodeCP1251(reader.buf[:n]))
+	}
+
+	n := copy(p, reader.pending)
+	reader.pending = reader.pending[n:]
+	return n, nil
+}
+
+// xmlCharsetReader возвращает [io.Reader] для кодировки, указанной в заголовке XML.
+//
+// Используется в качестве [xml.Decoder.CharsetReader].
+func xmlCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "windows-1251", "cp1251":
+		return newCP1251Reader(input), nil
+	}
+	return nil, fmt.Errorf("xml: unsupported charset %s", charset)
+}
@@ -0,0 +1,291 @@
+package moysklad
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// clientStats статистика запросов клиента пула.
+type clientStats struct {
+	requests    atomic.Int64
+	errors      atomic.Int64
+	rateLimited atomic.Int64
+	inFlight    atomic.Int64
+	waiting     atomic.Int64
+	waitTime    atomic.Int64 // суммарное время ожидания лимитов в наносекундах
+	lastUsed    atomic.Int64 // время последнего обращения в наносекундах Unix
+}
+
+// ClientPoolMetrics Статистика запросов учётной записи пула клиентов.
+type ClientPoolMetrics struct {
+	AccountID   string        // ID учётной записи
+	Requests    int64         // Количество выполненных запросов
+	Errors      int64         // Количество ошибок (ответы со статусом 4xx/5xx и ошибки соединения)
+	RateLimited int64         // Количество ответов 429 (превышение лимита запросов)
+	InFlight    int64         // Количество выполняемых запросов
+	Waiting     int64         // Количество запросов, ожидающих лимитов
+	WaitTime    time.Duration // Суммарное время ожидания лимитов
+	Created     time.Time     // Время создания клиента
+	LastUsed    time.Time     // Время последнего запроса
+}
+
+// ClientPoolConfig конфигурация пула клиентов.
+type ClientPoolConfig struct {
+	// Возвращает конфигурацию клиента учётной записи accountID (токен, логин и пароль или источник токена Credentials).
+	// Вызывается при первом обращении к учётной записи и после удаления неактивного клиента.
+	Provider func(ctx context.Context, accountID string) (Config, error)
+
+	// Общее ограничение количества параллельных запросов всех учётных записей (0 – без ограничения).
+	// Для каждой учётной записи действуют ограничения API: MaxQueriesPerSecond и MaxQueriesPerUser.
+	MaxConcurrent int
+
+	// Время неактивности, после которого клиент учётной записи удаляется из пула (0 – не удалять).
+	IdleTimeout time.Duration
+
+	// Общий клиент [http.Client] с пулом соединений для всех учётных записей
+	// (по умолчанию создаётся новый на основе [http.DefaultTransport]).
+	// Используется, если в конфигурации клиента не указаны RestyClient и HTTPClient.
+	HTTPClient *http.Client
+}
+
+// poolTenant клиент учётной записи пула.
+type poolTenant struct {
+	client  *Client
+	stats   *clientStats
+	created time.Time
+}
+
+// ClientPool пул клиентов для работы с несколькими учётными записями МойСклад (например, в решениях Маркетплейса).
+//
+// Клиенты создаются при первом обращении по конфигурации от [ClientPoolConfig.Provider]
+// и используют общий пул соединений. Для каждой учётной записи соблюдаются ограничения API
+// (45 запросов за 3 секунды, 5 параллельных запросов), поэтому одна учётная запись не может занять
+// все общие слоты [ClientPoolConfig.MaxConcurrent]. Неактивные клиенты удаляются по истечении
+// [ClientPoolConfig.IdleTimeout]; ограничения и статистика учётной записи при этом сохраняются,
+// поэтому клиент, созданный повторно, и ранее полученный клиент соблюдают общие ограничения.
+//
+// Пример:
+//
+//	pool := moysklad.NewClientPool(moysklad.ClientPoolConfig{
+//		Provider: func(ctx context.Context, accountID string) (moysklad.Config, error) {
+//			token, err := storage.Token(ctx, accountID)
+//			return moysklad.Config{Token: token}, err
+//		},
+//		MaxConcurrent: 50,
+//		IdleTimeout:   30 * time.Minute,
+//	})
+//	defer pool.Close()
+//
+//	client, err := pool.Get(ctx, accountID)
+type ClientPool struct {
+	config     ClientPoolConfig
+	httpClient *http.Client
+	global     chan struct{}
+	mu         sync.Mutex
+	tenants    map[string]*poolTenant
+	limits     map[string]*queryLimits // Ограничения запросов учётных записей (сохраняются при удалении неактивных клиентов)
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewClientPool возвращает [ClientPool].
+//
+// Если указано [ClientPoolConfig.IdleTimeout], то запускается фоновое удаление неактивных клиентов,
+// которое останавливается методом [ClientPool.Close].
+func NewClientPool(config ClientPoolConfig) *ClientPool {
+	pool := &ClientPool{
+		config:     config,
+		httpClient: config.HTTPClient,
+		tenants:    make(map[string]*poolTenant),
+		limits:     make(map[string]*queryLimits),
+		done:       make(chan struct{}),
+	}
+	if pool.httpClient == nil {
+		pool.httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+	if config.MaxConcurrent > 0 {
+		pool.global = make(chan struct{}, config.MaxConcurrent)
+	}
+	if config.IdleTimeout > 0 {
+		go pool.evictLoop()
+	}
+	return pool
+}
+
+// Get возвращает клиент учётной записи accountID, создавая его при первом обращении.
+func (pool *ClientPool) Get(ctx context.Context, accountID string) (*Client, error) {
+	pool.mu.Lock()
+	tenant, ok := pool.tenants[accountID]
+	pool.mu.Unlock()
+	if ok {
+		return tenant.client, nil
+	}
+
+	if pool.config.Provider == nil {
+		return nil, errors.New("client pool: provider is not configured")
+	}
+	config, err := pool.config.Provider(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	// клиент мог быть создан параллельным вызовом
+	if existing, ok := pool.tenants[accountID]; ok {
+		return existing.client, nil
+	}
+	tenant = pool.newTenant(accountID, config)
+	pool.tenants[accountID] = tenant
+	return tenant.client, nil
+}
+
+// newTenant создаёт клиент учётной записи accountID с общим пулом соединений.
+//
+// Если клиент учётной записи уже создавался, то новый клиент использует его ограничения и статистику.
+func (pool *ClientPool) newTenant(accountID string, config Config) *poolTenant {
+	if config.RestyClient == nil && config.HTTPClient == nil {
+		config.HTTPClient = pool.httpClient
+	}
+	client := New(config)
+
+	now := time.Now()
+	if limits, ok := pool.limits[accountID]; ok {
+		client.limits = limits
+	} else {
+		client.limits.global = pool.global
+		client.limits.stats = new(clientStats)
+		pool.limits[accountID] = client.limits
+	}
+	stats := client.limits.stats
+	stats.lastUsed.Store(now.UnixNano())
+
+	client.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		stats.requests.Add(1)
+		if resp.StatusCode() >= http.StatusBadRequest {
+			stats.errors.Add(1)
+		}
+		if resp.StatusCode() == http.StatusTooManyRequests {
+			stats.rateLimited.Add(1)
+		}
+		return nil
+	})
+	client.OnError(func(_ *resty.Request, err error) {
+		// ответы с ошибкой уже учтены в OnAfterResponse
+		var responseErr *resty.ResponseError
+		if !errors.As(err, &responseErr) {
+			stats.requests.Add(1)
+			stats.errors.Add(1)
+		}
+	})
+	return &poolTenant{client: client, stats: stats, created: now}
+}
+
+// Remove удаляет клиент учётной записи accountID, его ограничения и статистику из пула
+// (например, после отключения приложения). Ранее полученный клиент не следует использовать.
+func (pool *ClientPool) Remove(accountID string) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	delete(pool.tenants, accountID)
+	delete(pool.limits, accountID)
+}
+
+// Len возвращает количество клиентов в пуле.
+func (pool *ClientPool) Len() int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	return len(pool.tenants)
+}
+
+// EvictIdle удаляет клиенты без выполняемых запросов, неактивные дольше idle, и возвращает их количество.
+//
+// Ограничения запросов учётной записи сохраняются: клиент, созданный повторно, использует их
+// совместно с ранее полученными экземплярами.
+func (pool *ClientPool) EvictIdle(idle time.Duration) int {
+	threshold := time.Now().Add(-idle).UnixNano()
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	var evicted int
+	for accountID, tenant := range pool.tenants {
+		stats := tenant.stats
+		if stats.inFlight.Load() == 0 && stats.waiting.Load() == 0 && stats.lastUsed.Load() < threshold {
+			delete(pool.tenants, accountID)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// evictLoop периодически удаляет неактивные клиенты.
+func (pool *ClientPool) evictLoop() {
+	ticker := time.NewTicker(max(pool.config.IdleTimeout/2, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.done:
+			return
+		case <-ticker.C:
+			pool.EvictIdle(pool.config.IdleTimeout)
+		}
+	}
+}
+
+// Close останавливает фоновое удаление неактивных клиентов и очищает пул.
+func (pool *ClientPool) Close() {
+	pool.closeOnce.Do(func() { close(pool.done) })
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.tenants = make(map[string]*poolTenant)
+	pool.limits = make(map[string]*queryLimits)
+}
+
+// metrics возвращает статистику клиента учётной записи.
+func (tenant *poolTenant) metrics(accountID string) ClientPoolMetrics {
+	stats := tenant.stats
+	return ClientPoolMetrics{
+		AccountID:   accountID,
+		Requests:    stats.requests.Load(),
+		Errors:      stats.errors.Load(),
+		RateLimited: stats.rateLimited.Load(),
+		InFlight:    stats.inFlight.Load(),
+		Waiting:     stats.waiting.Load(),
+		WaitTime:    time.Duration(stats.waitTime.Load()),
+		Created:     tenant.created,
+		LastUsed:    time.Unix(0, stats.lastUsed.Load()),
+	}
+}
+
+// Metrics возвращает статистику запросов учётной записи accountID.
+//
+// Возвращает false, если клиента учётной записи нет в пуле.
+func (pool *ClientPool) Metrics(accountID string) (ClientPoolMetrics, bool) {
+	pool.mu.Lock()
+	tenant, ok := pool.tenants[accountID]
+	pool.mu.Unlock()
+	if !ok {
+		return ClientPoolMetrics{}, false
+	}
+	return tenant.metrics(accountID), true
+}
+
+// MetricsAll возвращает статистику запросов всех учётных записей пула, упорядоченную по ID учётной записи.
+func (pool *ClientPool) MetricsAll() []ClientPoolMetrics {
+	pool.mu.Lock()
+	metrics := make([]ClientPoolMetrics, 0, len(pool.tenants))
+	for accountID, tenant := range pool.tenants {
+		metrics = append(metrics, tenant.metrics(accountID))
+	}
+	pool.mu.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].AccountID < metrics[j].AccountID })
+	return metrics
+}
@@ -113,6 +113,9 @@ type EntityService interface {
 	// PaymentOut возвращает сервис для работы с исходящими платежами.
 	PaymentOut() PaymentOutService
 
+	// Payroll возвращает сервис для работы с начислениями зарплаты.
+	Payroll() PayrollService
+
 	// Prepayment возвращает сервис для работы с предоплатами.
 	Prepayment() PrepaymentService
 
@@ -449,6 +452,10 @@ func (service *entityService) PaymentOut() PaymentOutService {
 	return NewPaymentOutService(service.client)
 }
 
+func (service *entityService) Payroll() PayrollService {
+	return NewPayrollService(service.client)
+}
+
 func (service *entityService) Prepayment() PrepaymentService {
 	return NewPrepaymentService(service.client)
 }
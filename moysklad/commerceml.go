@@ -0,0 +1,470 @@
+package moysklad
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// CommerceMLVersion версия схемы CommerceML, указываемая при выгрузке.
+const CommerceMLVersion = "2.10"
+
+// CommerceMLClassifier Классификатор каталога CommerceML.
+type CommerceMLClassifier struct {
+	XMLName    xml.Name              `xml:"Классификатор"`
+	ID         string                `xml:"Ид"`                          // Идентификатор классификатора
+	Name       string                `xml:"Наименование"`                // Наименование классификатора
+	Groups     []CommerceMLGroup     `xml:"Группы>Группа,omitempty"`     // Дерево групп товаров
+	Properties []CommerceMLProperty  `xml:"Свойства>Свойство,omitempty"` // Свойства товаров
+	PriceTypes []CommerceMLPriceType `xml:"ТипыЦен>ТипЦены,omitempty"`   // Типы цен (CommerceML 2.10)
+	Warehouses []CommerceMLWarehouse `xml:"Склады>Склад,omitempty"`      // Склады (CommerceML 2.10)
+}
+
+// MarshalXML реализует интерфейс [xml.Marshaler].
+func (classifier CommerceMLClassifier) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalCommerceML(e, start, classifier)
+}
+
+// CommerceMLGroup Группа товаров.
+type CommerceMLGroup struct {
+	ID     string            `xml:"Ид"`                      // Идентификатор группы
+	Name   string            `xml:"Наименование"`            // Наименование группы
+	Groups []CommerceMLGroup `xml:"Группы>Группа,omitempty"` // Дочерние группы
+}
+
+// MarshalXML реализует интерфейс [xml.Marshaler].
+func (group CommerceMLGroup) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalCommerceML(e, start, group)
+}
+
+// CommerceMLProperty Свойство товаров.
+//
+// Возможные значения ТипЗначений: Строка, Число, Время, Справочник.
+type CommerceMLProperty struct {
+	ID        string                      `xml:"Ид"`                                    // Идентификатор свойства
+	Name      string                      `xml:"Наименование"`                          // Наименование свойства
+	ValueType string                      `xml:"ТипЗначений,omitempty"`                 // Тип значений
+	Values    []CommerceMLDictionaryValue `xml:"ВариантыЗначений>Справочник,omitempty"` // Варианты значений свойства типа Справочник
+}
+
+// MarshalXML реализует интерфейс [xml.Marshaler].
+func (property CommerceMLProperty) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalCommerceML(e, start, property)
+}
+
+// CommerceMLDictionaryValue Вариант значения свойства типа Справочник.
+type CommerceMLDictionaryValue struct {
+	ID    string `xml:"ИдЗначения"` // Идентификатор значения
+	Value string `xml:"Значение"`   // Значение
+}
+
+// CommerceMLPropertyValue Значение свойства товара.
+type CommerceMLPropertyValue struct {
+	ID    string `xml:"Ид"`       // Идентификатор свойства
+	Value string `xml:"Значение"` // Значение (для свойств типа Справочник – идентификатор варианта значения)
+}
+
+// CommerceMLRequisite Значение реквизита или характеристики товара.
+type CommerceMLRequisite struct {
+	Name  string `xml:"Наименование"` // Наименование
+	Value string `xml:"Значение"`     // Значение
+}
+
+// CommerceMLTaxRate Ставка налога.
+type CommerceMLTaxRate struct {
+	Name string `xml:"Наименование"` // Наименование налога (НДС)
+	Rate string `xml:"Ставка"`       // Ставка налога (число или «Без налога»)
+}
+
+// CommerceMLUnit Базовая единица измерения.
+type CommerceMLUnit struct {
+	Code          string `xml:"Код,attr,omitempty"`                     // Код ОКЕИ
+	FullName      string `xml:"НаименованиеПолное,attr,omitempty"`      // Полное наименование
+	International string `xml:"МеждународноеСокращение,attr,omitempty"` // Международное сокращение
+	Name          string `xml:",chardata"`                              // Краткое наименование
+}
+
+// CommerceMLProduct Товар каталога.
+//
+// Идентификатор модификации имеет вид «ИдТовара#ИдХарактеристики».
+type CommerceMLProduct struct {
+	XMLName         xml.Name                  `xml:"Товар"`
+	ID              string                    `xml:"Ид"`                                                  // Идентификатор товара
+	Barcode         string                    `xml:"Штрихкод,omitempty"`                                  // Штрихкод
+	Article         string                    `xml:"Артикул,omitempty"`                                   // Артикул
+	Name            string                    `xml:"Наименование"`                                        // Наименование
+	Unit            *CommerceMLUnit           `xml:"БазоваяЕдиница,omitempty"`                            // Базовая единица измерения
+	Groups          []string                  `xml:"Группы>Ид,omitempty"`                                 // Идентификаторы групп
+	Description     string                    `xml:"Описание,omitempty"`                                  // Описание
+	Images          []string                  `xml:"Картинка,omitempty"`                                  // Пути к изображениям
+	PropertyValues  []CommerceMLPropertyValue `xml:"ЗначенияСвойств>ЗначенияСвойства,omitempty"`          // Значения свойств
+	TaxRates        []CommerceMLTaxRate       `xml:"СтавкиНалогов>СтавкаНалога,omitempty"`                // Ставки налогов
+	Characteristics []CommerceMLRequisite     `xml:"ХарактеристикиТовара>ХарактеристикаТовара,omitempty"` // Характеристики модификации
+	Requisites      []CommerceMLRequisite     `xml:"ЗначенияРеквизитов>ЗначениеРеквизита,omitempty"`      // Значения реквизитов (Вес, ВидНоменклатуры и т.д.)
+}
+
+// MarshalXML реализует интерфейс [xml.Marshaler].
+func (product CommerceMLProduct) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalCommerceML(e, start, product)
+}
+
+// Requisite возвращает значение реквизита товара по наименованию.
+func (product CommerceMLProduct) Requisite(name string) string {
+	for _, requisite := range product.Requisites {
+		if requisite.Name == name {
+			return requisite.Value
+		}
+	}
+	return ""
+}
+
+// CommerceMLPriceType Тип цены пакета предложений.
+type CommerceMLPriceType struct {
+	ID       string `xml:"Ид"`               // Идентификатор типа цены
+	Name     string `xml:"Наименование"`     // Наименование типа цены
+	Currency string `xml:"Валюта,omitempty"` // Код валюты
+}
+
+// CommerceMLWarehouse Склад пакета предложений.
+type CommerceMLWarehouse struct {
+	ID   string `xml:"Ид"`           // Идентификатор склада
+	Name string `xml:"Наименование"` // Наименование склада
+}
+
+// CommerceMLPrice Цена предложения.
+type CommerceMLPrice struct {
+	Presentation string  `xml:"Представление,omitempty"` // Представление цены
+	PriceTypeID  string  `xml:"ИдТипаЦены"`              // Идентификатор типа цены
+	Value        float64 `xml:"ЦенаЗаЕдиницу"`           // Цена за единицу (в рублях)
+	Currency     string  `xml:"Валюта,omitempty"`        // Код валюты
+	Unit         string  `xml:"Единица,omitempty"`       // Единица измерения
+	Ratio        float64 `xml:"Коэффициент,omitempty"`   // Коэффициент пересчёта единицы
+}
+
+// CommerceMLStock Остаток предложения на складе.
+type CommerceMLStock struct {
+	WarehouseID string  `xml:"ИдСклада,attr"`           // Идентификатор склада
+	Quantity    float64 `xml:"КоличествоНаСкладе,attr"` // Количество на складе
+}
+
+// CommerceMLOffer Предложение (цены и остатки товара или модификации).
+type CommerceMLOffer struct {
+	XMLName         xml.Name              `xml:"Предложение"`
+	ID              string                `xml:"Ид"`                                                  // Идентификатор товара или модификации
+	Barcode         string                `xml:"Штрихкод,omitempty"`                                  // Штрихкод
+	Article         string                `xml:"Артикул,omitempty"`                                   // Артикул
+	Name            string                `xml:"Наименование"`                                        // Наименование
+	Unit            *CommerceMLUnit       `xml:"БазоваяЕдиница,omitempty"`                            // Базовая единица измерения
+	Characteristics []CommerceMLRequisite `xml:"ХарактеристикиТовара>ХарактеристикаТовара,omitempty"` // Характеристики модификации
+	Prices          []CommerceMLPrice     `xml:"Цены>Цена,omitempty"`                                 // Цены
+	Quantity        *float64              `xml:"Количество,omitempty"`                                // Общий остаток
+	Stocks          []CommerceMLStock     `xml:"Склад,omitempty"`                                     // Остатки по складам
+}
+
+// MarshalXML реализует интерфейс [xml.Marshaler].
+func (offer CommerceMLOffer) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalCommerceML(e, start, offer)
+}
+
+// TotalQuantity возвращает общий остаток предложения: значение Количество
+// или, если оно не указано, сумму остатков по складам.
+func (offer CommerceMLOffer) TotalQuantity() (float64, bool) {
+	if offer.Quantity != nil {
+		return *offer.Quantity, true
+	}
+	var quantity float64
+	for _, stock := range offer.Stocks {
+		quantity += stock.Quantity
+	}
+	return quantity, len(offer.Stocks) > 0
+}
+
+// marshalCommerceML записывает поля структуры v в элемент start.
+//
+// В отличие от [xml.Marshal], пустые списки с путём вида «Родитель>Элемент» и признаком omitempty
+// не записываются вместе с родительским элементом.
+func marshalCommerceML(e *xml.Encoder, start xml.StartElement, v any) error {
+	value := reflect.ValueOf(v)
+	if field, ok := value.Type().FieldByName("XMLName"); ok {
+		start.Name.Local = field.Tag.Get("xml")
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("xml"), ",")
+		if name == "" || name == "-" || field.Name == "XMLName" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if strings.Contains(options, "omitempty") && (fieldValue.IsZero() || fieldValue.Kind() == reflect.Slice && fieldValue.Len() == 0) {
+			continue
+		}
+
+		path := strings.Split(name, ">")
+		for _, parent := range path[:len(path)-1] {
+			if err := e.EncodeToken(xml.StartElement{Name: xml.Name{Local: parent}}); err != nil {
+				return err
+			}
+		}
+		if err := e.EncodeElement(fieldValue.Interface(), xml.StartElement{Name: xml.Name{Local: path[len(path)-1]}}); err != nil {
+			return err
+		}
+		for j := len(path) - 2; j >= 0; j-- {
+			if err := e.EncodeToken(xml.EndElement{Name: xml.Name{Local: path[j]}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// splitCommerceMLID разделяет идентификатор вида «ИдТовара#ИдХарактеристики».
+func splitCommerceMLID(id string) (productID, variantID string) {
+	productID, variantID, _ = strings.Cut(id, "#")
+	return productID, variantID
+}
+
+// CommerceMLHandler обработчик элементов файла CommerceML.
+//
+// Методы вызываются по мере чтения файла в порядке следования элементов.
+type CommerceMLHandler interface {
+	Classifier(classifier *CommerceMLClassifier) error // Классификатор (группы и свойства)
+	Product(product *CommerceMLProduct) error          // Товар каталога
+	PriceTypes(priceTypes []CommerceMLPriceType) error // Типы цен пакета предложений
+	Warehouses(warehouses []CommerceMLWarehouse) error // Склады пакета предложений
+	Offer(offer *CommerceMLOffer) error                // Предложение
+}
+
+// ReadCommerceML потоково читает файл CommerceML (import.xml или offers.xml)
+// и передаёт элементы обработчику handler, не загружая файл в память целиком.
+//
+// Поддерживаются кодировки UTF-8 и Windows-1251.
+func ReadCommerceML(r io.Reader, handler CommerceMLHandler) error {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = xmlCharsetReader
+
+	var root bool
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			if !root {
+				return fmt.Errorf("commerceml: missing КоммерческаяИнформация element")
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("commerceml: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "КоммерческаяИнформация":
+			root = true
+
+		case "Классификатор":
+			var classifier CommerceMLClassifier
+			if err = decoder.DecodeElement(&classifier, &start); err == nil {
+				err = handler.Classifier(&classifier)
+			}
+
+		case "Товар":
+			var product CommerceMLProduct
+			if err = decoder.DecodeElement(&product, &start); err == nil {
+				err = handler.Product(&product)
+			}
+
+		case "ТипыЦен":
+			var priceTypes struct {
+				Items []CommerceMLPriceType `xml:"ТипЦены"`
+			}
+			if err = decoder.DecodeElement(&priceTypes, &start); err == nil {
+				err = handler.PriceTypes(priceTypes.Items)
+			}
+
+		case "Склады":
+			var warehouses struct {
+				Items []CommerceMLWarehouse `xml:"Склад"`
+			}
+			if err = decoder.DecodeElement(&warehouses, &start); err == nil {
+				err = handler.Warehouses(warehouses.Items)
+			}
+
+		case "Предложение":
+			var offer CommerceMLOffer
+			if err = decoder.DecodeElement(&offer, &start); err == nil {
+				err = handler.Offer(&offer)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CommerceMLWriter потоковая запись файла CommerceML.
+//
+// Порядок записи каталога (import.xml):
+//
+//	writer.WriteClassifier(classifier)
+//	writer.BeginCatalog(id, classifierID, name)
+//	writer.WriteProduct(product) // для каждого товара
+//	writer.Close()
+//
+// Порядок записи пакета предложений (offers.xml):
+//
+//	writer.BeginOffers(id, catalogID, classifierID, name, priceTypes, warehouses)
+//	writer.WriteOffer(offer) // для каждого предложения
+//	writer.Close()
+type CommerceMLWriter struct {
+	encoder *xml.Encoder
+	w       io.Writer
+	stack   []xml.Name
+	now     time.Time
+}
+
+// NewCommerceMLWriter возвращает [CommerceMLWriter], записывающий файл в w.
+func NewCommerceMLWriter(w io.Writer) *CommerceMLWriter {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	return &CommerceMLWriter{encoder: encoder, w: w, now: time.Now()}
+}
+
+// begin открывает элемент.
+func (writer *CommerceMLWriter) begin(name string, attrs ...xml.Attr) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	if err := writer.encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	writer.stack = append(writer.stack, start.Name)
+	return nil
+}
+
+// end закрывает открытые элементы до уровня depth.
+func (writer *CommerceMLWriter) end(depth int) error {
+	for len(writer.stack) > depth {
+		name := writer.stack[len(writer.stack)-1]
+		if err := writer.encoder.EncodeToken(xml.EndElement{Name: name}); err != nil {
+			return err
+		}
+		writer.stack = writer.stack[:len(writer.stack)-1]
+	}
+	return nil
+}
+
+// root открывает корневой элемент при первой записи и закрывает открытые разделы.
+func (writer *CommerceMLWriter) root() error {
+	if len(writer.stack) == 0 {
+		if _, err := io.WriteString(writer.w, xml.Header); err != nil {
+			return err
+		}
+		return writer.begin("КоммерческаяИнформация",
+			xml.Attr{Name: xml.Name{Local: "ВерсияСхемы"}, Value: CommerceMLVersion},
+			xml.Attr{Name: xml.Name{Local: "ДатаФормирования"}, Value: writer.now.Format("2006-01-02T15:04:05")},
+		)
+	}
+	return writer.end(1)
+}
+
+// element записывает простой элемент с текстовым значением.
+func (writer *CommerceMLWriter) element(name, value string) error {
+	return writer.encoder.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+// WriteClassifier записывает Классификатор.
+func (writer *CommerceMLWriter) WriteClassifier(classifier *CommerceMLClassifier) error {
+	if err := writer.root(); err != nil {
+		return err
+	}
+	return writer.encoder.Encode(classifier)
+}
+
+// BeginCatalog открывает Каталог с полной выгрузкой товаров.
+func (writer *CommerceMLWriter) BeginCatalog(id, classifierID, name string) error {
+	if err := writer.root(); err != nil {
+		return err
+	}
+	if err := writer.begin("Каталог", xml.Attr{Name: xml.Name{Local: "СодержитТолькоИзменения"}, Value: "false"}); err != nil {
+		return err
+	}
+	for _, field := range [][2]string{{"Ид", id}, {"ИдКлассификатора", classifierID}, {"Наименование", name}} {
+		if err := writer.element(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	return writer.begin("Товары")
+}
+
+// WriteProduct записывает Товар каталога.
+func (writer *CommerceMLWriter) WriteProduct(product *CommerceMLProduct) error {
+	if len(writer.stack) == 0 || writer.stack[len(writer.stack)-1].Local != "Товары" {
+		return fmt.Errorf("commerceml: catalog is not started")
+	}
+	return writer.encoder.Encode(product)
+}
+
+// BeginOffers открывает Пакет предложений с полной выгрузкой цен и остатков.
+func (writer *CommerceMLWriter) BeginOffers(id, catalogID, classifierID, name string, priceTypes []CommerceMLPriceType, warehouses []CommerceMLWarehouse) error {
+	if err := writer.root(); err != nil {
+		return err
+	}
+	if err := writer.begin("ПакетПредложений", xml.Attr{Name: xml.Name{Local: "СодержитТолькоИзменения"}, Value: "false"}); err != nil {
+		return err
+	}
+	for _, field := range [][2]string{{"Ид", id}, {"Наименование", name}, {"ИдКаталога", catalogID}, {"ИдКлассификатора", classifierID}} {
+		if err := writer.element(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	if len(priceTypes) > 0 {
+		section := struct {
+			XMLName xml.Name              `xml:"ТипыЦен"`
+			Items   []CommerceMLPriceType `xml:"ТипЦены"`
+		}{Items: priceTypes}
+		if err := writer.encoder.Encode(section); err != nil {
+			return err
+		}
+	}
+	if len(warehouses) > 0 {
+		section := struct {
+			XMLName xml.Name              `xml:"Склады"`
+			Items   []CommerceMLWarehouse `xml:"Склад"`
+		}{Items: warehouses}
+		if err := writer.encoder.Encode(section); err != nil {
+			return err
+		}
+	}
+
+	return writer.begin("Предложения")
+}
+
+// WriteOffer записывает Предложение.
+func (writer *CommerceMLWriter) WriteOffer(offer *CommerceMLOffer) error {
+	if len(writer.stack) == 0 || writer.stack[len(writer.stack)-1].Local != "Предложения" {
+		return fmt.Errorf("commerceml: offers package is not started")
+	}
+	return writer.encoder.Encode(offer)
+}
+
+// Close закрывает все открытые элементы и завершает запись.
+func (writer *CommerceMLWriter) Close() error {
+	if err := writer.end(0); err != nil {
+		return err
+	}
+	return writer.encoder.Flush()
+}
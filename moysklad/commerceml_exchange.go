@@ -0,0 +1,292 @@
+package moysklad
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommerceMLExchangeConfig конфигурация HTTP-обмена с 1С по протоколу CommerceML.
+type CommerceMLExchangeConfig struct {
+	// Логин и пароль, которые 1С передаёт при авторизации (Basic).
+	Login    string
+	Password string
+
+	// Каталог для временного хранения загруженных файлов (по умолчанию [os.TempDir]).
+	Dir string
+
+	// Максимальный размер части файла в байтах, сообщаемый 1С (по умолчанию 100 МБ).
+	FileLimit int64
+
+	// Время жизни неактивной сессии обмена (по умолчанию 1 час).
+	// По истечении сессия закрывается, а её каталог с принятыми файлами удаляется.
+	SessionTTL time.Duration
+}
+
+// commerceMLCookie наименование cookie сессии обмена.
+const commerceMLCookie = "moysklad_exchange"
+
+// commerceMLSession сессия обмена.
+type commerceMLSession struct {
+	dir      string    // каталог принятых файлов
+	lastUsed time.Time // время последнего запроса
+}
+
+// CommerceMLExchange обработчик HTTP-обмена с 1С по протоколу CommerceML (type=catalog).
+//
+// Поддерживаются этапы обмена:
+//   - mode=checkauth – авторизация, выдача cookie сессии
+//   - mode=init – параметры обмена (без архивирования)
+//   - mode=file – приём файла (частями)
+//   - mode=import – загрузка принятого файла через [CommerceMLImporter]
+//
+// Если логин или пароль не указаны, то авторизация не выполняется и обмен невозможен.
+// Каталог сессии удаляется после загрузки всех принятых XML-файлов или по истечении
+// [CommerceMLExchangeConfig.SessionTTL].
+//
+// Пример:
+//
+//	importer := moysklad.NewCommerceMLImporter(client, moysklad.CommerceMLImportConfig{})
+//	exchange := moysklad.NewCommerceMLExchange(importer, moysklad.CommerceMLExchangeConfig{Login: "1c", Password: "secret"})
+//	http.Handle("/exchange", exchange)
+type CommerceMLExchange struct {
+	importer *CommerceMLImporter
+	config   CommerceMLExchangeConfig
+	sessions map[string]*commerceMLSession
+	mu       sync.Mutex
+	importMu sync.Mutex
+}
+
+// NewCommerceMLExchange возвращает [CommerceMLExchange].
+func NewCommerceMLExchange(importer *CommerceMLImporter, config CommerceMLExchangeConfig) *CommerceMLExchange {
+	if config.Dir == "" {
+		config.Dir = os.TempDir()
+	}
+	if config.FileLimit <= 0 {
+		config.FileLimit = 100 << 20
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = time.Hour
+	}
+	return &CommerceMLExchange{importer: importer, config: config, sessions: make(map[string]*commerceMLSession)}
+}
+
+// ServeHTTP реализует интерфейс [http.Handler].
+func (exchange *CommerceMLExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if exchangeType := query.Get("type"); exchangeType != "catalog" {
+		exchange.failure(w, fmt.Errorf("unsupported exchange type %q", exchangeType))
+		return
+	}
+
+	mode := query.Get("mode")
+	if mode == "checkauth" {
+		exchange.checkAuth(w, r)
+		return
+	}
+
+	dir, ok := exchange.session(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		exchange.failure(w, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	switch mode {
+	case "init":
+		fmt.Fprintf(w, "zip=no\nfile_limit=%d\n", exchange.config.FileLimit)
+
+	case "file":
+		path, err := exchange.path(dir, query.Get("filename"))
+		if err == nil {
+			err = exchange.receive(path, r.Body)
+		}
+		if err != nil {
+			exchange.failure(w, err)
+			return
+		}
+		fmt.Fprint(w, "success\n")
+
+	case "import":
+		path, err := exchange.path(dir, query.Get("filename"))
+		if err != nil {
+			exchange.failure(w, err)
+			return
+		}
+		result, err := exchange.importFile(r, path)
+		exchange.cleanupDir(dir)
+		if err != nil {
+			exchange.failure(w, err)
+			return
+		}
+		fmt.Fprintf(w, "success\n%s\n", result)
+
+	default:
+		exchange.failure(w, fmt.Errorf("unsupported mode %q", mode))
+	}
+}
+
+// failure записывает ответ об ошибке.
+func (exchange *CommerceMLExchange) failure(w http.ResponseWriter, err error) {
+	fmt.Fprintf(w, "failure\n%s\n", err)
+}
+
+// checkAuth проверяет логин и пароль и открывает сессию обмена.
+func (exchange *CommerceMLExchange) checkAuth(w http.ResponseWriter, r *http.Request) {
+	if exchange.config.Login == "" || exchange.config.Password == "" {
+		w.WriteHeader(http.StatusForbidden)
+		exchange.failure(w, fmt.Errorf("exchange login and password are not configured"))
+		return
+	}
+
+	login, password, _ := r.BasicAuth()
+	if subtle.ConstantTimeCompare([]byte(login), []byte(exchange.config.Login)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(exchange.config.Password)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		exchange.failure(w, fmt.Errorf("invalid login or password"))
+		return
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		exchange.failure(w, err)
+		return
+	}
+	session := hex.EncodeToString(token)
+
+	dir := filepath.Join(exchange.config.Dir, "commerceml-"+session)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		exchange.failure(w, err)
+		return
+	}
+
+	exchange.expire()
+	exchange.mu.Lock()
+	exchange.sessions[session] = &commerceMLSession{dir: dir, lastUsed: time.Now()}
+	exchange.mu.Unlock()
+
+	fmt.Fprintf(w, "success\n%s\n%s\n", commerceMLCookie, session)
+}
+
+// session возвращает каталог файлов сессии по cookie запроса.
+func (exchange *CommerceMLExchange) session(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(commerceMLCookie)
+	if err != nil {
+		return "", false
+	}
+	exchange.expire()
+	exchange.mu.Lock()
+	defer exchange.mu.Unlock()
+	session, ok := exchange.sessions[cookie.Value]
+	if !ok {
+		return "", false
+	}
+	session.lastUsed = time.Now()
+	return session.dir, true
+}
+
+// expire закрывает неактивные сессии и удаляет их каталоги.
+func (exchange *CommerceMLExchange) expire() {
+	threshold := time.Now().Add(-exchange.config.SessionTTL)
+
+	var dirs []string
+	exchange.mu.Lock()
+	for id, session := range exchange.sessions {
+		if session.lastUsed.Before(threshold) {
+			delete(exchange.sessions, id)
+			dirs = append(dirs, session.dir)
+		}
+	}
+	exchange.mu.Unlock()
+
+	for _, dir := range dirs {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// cleanupDir удаляет каталог сессии dir, если в нём не осталось незагруженных XML-файлов.
+//
+// Сессия остаётся открытой: при приёме следующего файла каталог создаётся заново.
+func (exchange *CommerceMLExchange) cleanupDir(dir string) {
+	var pending bool
+	_ = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() && strings.EqualFold(filepath.Ext(path), ".xml") {
+			pending = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if !pending {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// path возвращает путь к файлу сессии, не допуская выхода за пределы её каталога.
+func (exchange *CommerceMLExchange) path(dir, filename string) (string, error) {
+	name := filepath.Clean(filepath.FromSlash(strings.TrimSpace(filename)))
+	if filename == "" || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid filename %q", filename)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// receive дописывает часть файла.
+//
+// Если часть превышает [CommerceMLExchangeConfig.FileLimit], то она не сохраняется и возвращается ошибка.
+func (exchange *CommerceMLExchange) receive(path string, body io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	limit := exchange.config.FileLimit
+	n, err := io.Copy(file, io.LimitReader(body, limit+1))
+	if err == nil && n > limit {
+		err = fmt.Errorf("file part exceeds file_limit %d bytes", limit)
+	}
+	if err != nil {
+		// часть файла отбрасывается, чтобы 1С могла повторить её отправку
+		_ = file.Truncate(info.Size())
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// importFile загружает принятый файл и удаляет его.
+//
+// Файлы, не являющиеся XML (например, изображения), пропускаются.
+func (exchange *CommerceMLExchange) importFile(r *http.Request, path string) (*CommerceMLImportResult, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".xml") {
+		return new(CommerceMLImportResult), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+	defer file.Close()
+
+	// загрузка файлов одного обмена выполняется последовательно
+	exchange.importMu.Lock()
+	defer exchange.importMu.Unlock()
+
+	return exchange.importer.Import(r.Context(), file)
+}
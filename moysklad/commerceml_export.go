@@ -0,0 +1,493 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// CommerceMLExportConfig конфигурация выгрузки в формате CommerceML.
+type CommerceMLExportConfig struct {
+	// Идентификатор каталога и классификатора (по умолчанию «moysklad»).
+	CatalogID string
+
+	// Наименование каталога (по умолчанию «Каталог товаров»).
+	CatalogName string
+
+	// Наименования выгружаемых типов цен (по умолчанию все типы цен).
+	PriceTypes []string
+
+	// Склады, остатки которых выгружаются (по умолчанию все склады).
+	Stores []*Store
+
+	// Параметры запроса товаров и модификаций (например, фильтрация архивных).
+	Params []func(*Params)
+
+	// Выгружать комплекты. Остатком комплекта считается количество, которое можно собрать
+	// из остатков компонентов (см. [BundleAvailabilityCalculator]).
+	Bundles bool
+}
+
+// CommerceMLExporter выгрузка каталога (import.xml) и пакета предложений (offers.xml) в формате CommerceML.
+//
+// Идентификатором товара, модификации, группы и склада служит внешний код, а если он не заполнен – ID.
+// Идентификатор модификации имеет вид «ИдТовара#ИдМодификации».
+// Товары и модификации запрашиваются постранично и записываются по мере получения.
+type CommerceMLExporter struct {
+	client *Client
+	config CommerceMLExportConfig
+}
+
+// NewCommerceMLExporter возвращает [CommerceMLExporter].
+func NewCommerceMLExporter(client *Client, config CommerceMLExportConfig) *CommerceMLExporter {
+	if config.CatalogID == "" {
+		config.CatalogID = "moysklad"
+	}
+	if config.CatalogName == "" {
+		config.CatalogName = "Каталог товаров"
+	}
+	return &CommerceMLExporter{client: client, config: config}
+}
+
+// commerceMLID возвращает идентификатор CommerceML: внешний код или, если он не заполнен, ID.
+func commerceMLID(externalCode, id string) string {
+	if externalCode != "" {
+		return externalCode
+	}
+	return id
+}
+
+// WriteCatalog записывает каталог (import.xml): классификатор с группами и свойствами, товары, модификации и комплекты ([CommerceMLExportConfig.Bundles]).
+func (exporter *CommerceMLExporter) WriteCatalog(ctx context.Context, w io.Writer) error {
+	folders, _, err := NewProductFolderService(exporter.client).GetListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	attributes, _, err := NewProductService(exporter.client).GetAttributeList(ctx)
+	if err != nil {
+		return err
+	}
+
+	uoms, err := exporter.uoms(ctx)
+	if err != nil {
+		return err
+	}
+
+	classifier := &CommerceMLClassifier{ID: exporter.config.CatalogID, Name: exporter.config.CatalogName}
+
+	folderIDs := make(map[string]string)
+	children := make(map[string][]*ProductFolder)
+	for _, folder := range Deref(folders) {
+		folderIDs[folder.GetMeta().GetHref()] = commerceMLID(folder.GetExternalCode(), folder.GetID())
+		parent := folder.GetProductFolder().GetMeta().GetHref()
+		children[parent] = append(children[parent], folder)
+	}
+	var groups func(parent string) []CommerceMLGroup
+	groups = func(parent string) []CommerceMLGroup {
+		var result []CommerceMLGroup
+		for _, folder := range children[parent] {
+			result = append(result, CommerceMLGroup{
+				ID:     folderIDs[folder.GetMeta().GetHref()],
+				Name:   folder.GetName(),
+				Groups: groups(folder.GetMeta().GetHref()),
+			})
+		}
+		return result
+	}
+	classifier.Groups = groups("")
+
+	for _, attribute := range attributes.Rows {
+		valueType := "Строка"
+		switch attribute.GetType() {
+		case AttributeTypeDouble, AttributeTypeLong:
+			valueType = "Число"
+		case AttributeTypeTime:
+			valueType = "Время"
+		}
+		classifier.Properties = append(classifier.Properties, CommerceMLProperty{
+			ID:        attribute.GetID(),
+			Name:      attribute.GetName(),
+			ValueType: valueType,
+		})
+	}
+
+	writer := NewCommerceMLWriter(w)
+	if err = writer.WriteClassifier(classifier); err != nil {
+		return err
+	}
+	if err = writer.BeginCatalog(exporter.config.CatalogID, exporter.config.CatalogID, exporter.config.CatalogName); err != nil {
+		return err
+	}
+
+	productIDs := make(map[string]string)
+	err = forEachPage(ctx, NewProductService(exporter.client), func(rows Slice[Product]) error {
+		for _, product := range rows {
+			id := commerceMLID(product.GetExternalCode(), product.GetID())
+			productIDs[product.GetID()] = id
+
+			element := &CommerceMLProduct{
+				ID:          id,
+				Article:     product.GetArticle(),
+				Name:        product.GetName(),
+				Unit:        commerceMLUnit(uoms, product.GetUom()),
+				Description: product.GetDescription(),
+				Barcode:     commerceMLFirstBarcode(product.GetBarcodes()),
+				Requisites: []CommerceMLRequisite{
+					{Name: "ВидНоменклатуры", Value: "Товар"},
+					{Name: "ТипНоменклатуры", Value: "Товар"},
+				},
+			}
+			if product.ProductFolder != nil {
+				if folderID, ok := folderIDs[product.GetProductFolder().GetMeta().GetHref()]; ok {
+					element.Groups = []string{folderID}
+				}
+			}
+			if product.VatEnabled != nil {
+				rate := "Без налога"
+				if product.GetVatEnabled() {
+					rate = strconv.Itoa(product.GetVat())
+				}
+				element.TaxRates = []CommerceMLTaxRate{{Name: "НДС", Rate: rate}}
+			}
+			if weight := product.GetWeight(); weight > 0 {
+				element.Requisites = append(element.Requisites, CommerceMLRequisite{Name: "Вес", Value: strconv.FormatFloat(weight, 'f', -1, 64)})
+			}
+			for _, attribute := range product.GetAttributes() {
+				if value := commerceMLAttributeValue(attribute); value != "" {
+					element.PropertyValues = append(element.PropertyValues, CommerceMLPropertyValue{ID: attribute.GetID(), Value: value})
+				}
+			}
+
+			if err := writer.WriteProduct(element); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, exporter.config.Params...)
+	if err != nil {
+		return err
+	}
+
+	err = forEachPage(ctx, NewVariantService(exporter.client), func(rows Slice[Variant]) error {
+		for _, variant := range rows {
+			productID, ok := productIDs[variant.GetProduct().GetMeta().GetUUIDFromHref()]
+			if !ok {
+				continue
+			}
+
+			element := &CommerceMLProduct{
+				ID:              productID + "#" + commerceMLID(variant.GetExternalCode(), variant.GetID()),
+				Name:            variant.GetName(),
+				Barcode:         commerceMLFirstBarcode(variant.GetBarcodes()),
+				Characteristics: commerceMLCharacteristics(variant.GetCharacteristics()),
+			}
+			if err := writer.WriteProduct(element); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, exporter.config.Params...)
+	if err != nil {
+		return err
+	}
+
+	if exporter.config.Bundles {
+		err = forEachPage(ctx, NewBundleService(exporter.client), func(rows Slice[Bundle]) error {
+			for _, bundle := range rows {
+				element := &CommerceMLProduct{
+					ID:          commerceMLID(bundle.GetExternalCode(), bundle.GetID()),
+					Article:     bundle.GetArticle(),
+					Name:        bundle.GetName(),
+					Unit:        commerceMLUnit(uoms, bundle.GetUom()),
+					Description: bundle.GetDescription(),
+					Barcode:     commerceMLFirstBarcode(bundle.GetBarcodes()),
+					Requisites: []CommerceMLRequisite{
+						{Name: "ВидНоменклатуры", Value: "Набор"},
+						{Name: "ТипНоменклатуры", Value: "Набор"},
+					},
+				}
+				if bundle.ProductFolder != nil {
+					if folderID, ok := folderIDs[bundle.GetProductFolder().GetMeta().GetHref()]; ok {
+						element.Groups = []string{folderID}
+					}
+				}
+				if err := writer.WriteProduct(element); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, exporter.config.Params...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// WriteOffers записывает пакет предложений (offers.xml): типы цен, склады, цены и остатки товаров, модификаций и комплектов.
+//
+// Для товаров с модификациями предложения выгружаются только по модификациям.
+func (exporter *CommerceMLExporter) WriteOffers(ctx context.Context, w io.Writer) error {
+	priceTypes, err := exporter.priceTypes(ctx)
+	if err != nil {
+		return err
+	}
+
+	stores := exporter.config.Stores
+	if len(stores) == 0 {
+		rows, _, err := NewStoreService(exporter.client).GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		stores = Deref(rows)
+	}
+
+	uoms, err := exporter.uoms(ctx)
+	if err != nil {
+		return err
+	}
+
+	currencies, _, err := NewCurrencyService(exporter.client).GetListAll(ctx)
+	if err != nil {
+		return err
+	}
+	currencyCodes := make(map[string]string)
+	for _, currency := range Deref(currencies) {
+		currencyCodes[currency.GetMeta().GetHref()] = currency.GetISOCode()
+	}
+
+	stocks, _, err := NewReportStockService(exporter.client).GetCurrentByStore(ctx)
+	if err != nil {
+		return err
+	}
+	quantities := make(map[string]map[string]float64)
+	for _, stock := range Deref(stocks) {
+		if quantities[stock.AssortmentID] == nil {
+			quantities[stock.AssortmentID] = make(map[string]float64)
+		}
+		quantities[stock.AssortmentID][stock.StoreID] += stock.Stock
+	}
+
+	var (
+		offerPriceTypes []CommerceMLPriceType
+		warehouses      []CommerceMLWarehouse
+		priceTypeIDs    = make(map[string]string)
+	)
+	for _, priceType := range priceTypes {
+		id := commerceMLID(priceType.GetExternalCode(), priceType.GetID())
+		priceTypeIDs[priceType.GetMeta().GetHref()] = id
+		offerPriceTypes = append(offerPriceTypes, CommerceMLPriceType{ID: id, Name: priceType.GetName()})
+	}
+	for _, store := range stores {
+		warehouses = append(warehouses, CommerceMLWarehouse{ID: commerceMLID(store.GetExternalCode(), store.GetID()), Name: store.GetName()})
+	}
+
+	offer := func(id, name, assortmentID string, salePrices Slice[SalePrice], unit string) *CommerceMLOffer {
+		element := &CommerceMLOffer{ID: id, Name: name}
+		for _, salePrice := range salePrices {
+			priceTypeID, ok := priceTypeIDs[salePrice.GetPriceType().GetMeta().GetHref()]
+			if !ok {
+				continue
+			}
+			element.Prices = append(element.Prices, CommerceMLPrice{
+				Presentation: fmt.Sprintf("%.2f", salePrice.GetValue()/100),
+				PriceTypeID:  priceTypeID,
+				Value:        salePrice.GetValue() / 100,
+				Currency:     currencyCodes[salePrice.GetCurrency().GetMeta().GetHref()],
+				Unit:         unit,
+				Ratio:        1,
+			})
+		}
+
+		var total float64
+		for i, store := range stores {
+			quantity := quantities[assortmentID][store.GetID()]
+			total += quantity
+			element.Stocks = append(element.Stocks, CommerceMLStock{WarehouseID: warehouses[i].ID, Quantity: quantity})
+		}
+		element.Quantity = &total
+		return element
+	}
+
+	writer := NewCommerceMLWriter(w)
+	err = writer.BeginOffers(exporter.config.CatalogID+"#offers", exporter.config.CatalogID, exporter.config.CatalogID,
+		exporter.config.CatalogName, offerPriceTypes, warehouses)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make(map[string]string)
+	units := make(map[string]string)
+	err = forEachPage(ctx, NewProductService(exporter.client), func(rows Slice[Product]) error {
+		for _, product := range rows {
+			id := commerceMLID(product.GetExternalCode(), product.GetID())
+			productIDs[product.GetID()] = id
+
+			var unit string
+			if element := commerceMLUnit(uoms, product.GetUom()); element != nil {
+				unit = element.Name
+			}
+			units[product.GetID()] = unit
+
+			if product.GetVariantsCount() > 0 {
+				continue
+			}
+
+			element := offer(id, product.GetName(), product.GetID(), product.GetSalePrices(), unit)
+			element.Article = product.GetArticle()
+			element.Barcode = commerceMLFirstBarcode(product.GetBarcodes())
+			if err := writer.WriteOffer(element); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, exporter.config.Params...)
+	if err != nil {
+		return err
+	}
+
+	err = forEachPage(ctx, NewVariantService(exporter.client), func(rows Slice[Variant]) error {
+		for _, variant := range rows {
+			parentID := variant.GetProduct().GetMeta().GetUUIDFromHref()
+			productID, ok := productIDs[parentID]
+			if !ok {
+				continue
+			}
+
+			id := productID + "#" + commerceMLID(variant.GetExternalCode(), variant.GetID())
+			element := offer(id, variant.GetName(), variant.GetID(), variant.GetSalePrices(), units[parentID])
+			element.Barcode = commerceMLFirstBarcode(variant.GetBarcodes())
+			element.Characteristics = commerceMLCharacteristics(variant.GetCharacteristics())
+			if err := writer.WriteOffer(element); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, exporter.config.Params...)
+	if err != nil {
+		return err
+	}
+
+	if exporter.config.Bundles {
+		calculator := NewBundleAvailabilityCalculator(exporter.client, BundleAvailabilityConfig{StockType: StockDefault, Stores: stores})
+		err = forEachPage(ctx, NewBundleService(exporter.client), func(rows Slice[Bundle]) error {
+			availability, err := calculator.CalculateWithStock(ctx, Deref(stocks), rows...)
+			if err != nil {
+				return err
+			}
+			for bundleID, byStore := range availability.ByStore() {
+				quantities[bundleID] = byStore
+			}
+
+			for _, bundle := range rows {
+				var unit string
+				if element := commerceMLUnit(uoms, bundle.GetUom()); element != nil {
+					unit = element.Name
+				}
+				element := offer(commerceMLID(bundle.GetExternalCode(), bundle.GetID()), bundle.GetName(), bundle.GetID(), bundle.GetSalePrices(), unit)
+				element.Article = bundle.GetArticle()
+				element.Barcode = commerceMLFirstBarcode(bundle.GetBarcodes())
+				if err := writer.WriteOffer(element); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, exporter.config.Params...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+// priceTypes возвращает выгружаемые типы цен.
+func (exporter *CommerceMLExporter) priceTypes(ctx context.Context) (Slice[PriceType], error) {
+	priceTypes, _, err := NewContextCompanySettingsService(exporter.client).GetPriceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(exporter.config.PriceTypes) == 0 {
+		return Deref(priceTypes), nil
+	}
+
+	names := make(map[string]bool)
+	for _, name := range exporter.config.PriceTypes {
+		names[name] = true
+	}
+	var result Slice[PriceType]
+	for _, priceType := range Deref(priceTypes) {
+		if names[priceType.GetName()] {
+			result.Push(priceType)
+		}
+	}
+	return result, nil
+}
+
+// uoms возвращает единицы измерения по href.
+func (exporter *CommerceMLExporter) uoms(ctx context.Context) (map[string]*Uom, error) {
+	rows, _, err := NewUomService(exporter.client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uoms := make(map[string]*Uom)
+	for _, uom := range Deref(rows) {
+		uoms[uom.GetMeta().GetHref()] = uom
+	}
+	return uoms, nil
+}
+
+// commerceMLUnit возвращает базовую единицу измерения CommerceML.
+func commerceMLUnit(uoms map[string]*Uom, uom Uom) *CommerceMLUnit {
+	found, ok := uoms[uom.GetMeta().GetHref()]
+	if !ok {
+		return nil
+	}
+	return &CommerceMLUnit{Code: found.GetCode(), FullName: found.GetDescription(), Name: found.GetName()}
+}
+
+// commerceMLFirstBarcode возвращает первый штрихкод.
+func commerceMLFirstBarcode(barcodes Slice[Barcode]) string {
+	if len(barcodes) == 0 {
+		return ""
+	}
+	return barcodes[0].Value
+}
+
+// commerceMLCharacteristics возвращает характеристики модификации CommerceML.
+func commerceMLCharacteristics(characteristics Slice[Characteristic]) []CommerceMLRequisite {
+	var result []CommerceMLRequisite
+	for _, characteristic := range characteristics {
+		result = append(result, CommerceMLRequisite{Name: characteristic.GetName(), Value: characteristic.GetValue()})
+	}
+	return result
+}
+
+// commerceMLAttributeValue возвращает строковое значение доп. поля.
+//
+// Для доп. полей типа справочник возвращается наименование элемента.
+func commerceMLAttributeValue(attribute *Attribute) string {
+	if attribute.Value == nil {
+		return ""
+	}
+	switch value := attribute.GetValue().(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case map[string]any:
+		if name, ok := value["name"].(string); ok {
+			return name
+		}
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
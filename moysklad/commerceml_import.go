@@ -0,0 +1,911 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CommerceMLImportConfig конфигурация загрузки файлов CommerceML.
+type CommerceMLImportConfig struct {
+	// Соответствие наименований типов цен CommerceML наименованиям типов цен МойСклад.
+	//
+	// Если тип цены не указан, то используется тип цены МойСклад с тем же наименованием.
+	PriceTypes map[string]string
+
+	// Склад, на который загружаются остатки предложений.
+	//
+	// Если не указан, то остатки не загружаются.
+	// Расхождение с текущими остатками оформляется Оприходованием и Списанием.
+	Store *Store
+
+	// Юрлицо Оприходования и Списания (обязательно при указанном складе).
+	Organization *Organization
+
+	// Проводить ли созданные Оприходование и Списание.
+	Applicable bool
+
+	// Создавать ли доп. поля товаров для свойств CommerceML, которых нет в МойСклад.
+	//
+	// Если не установлено, то значения свойств без соответствующего доп. поля пропускаются.
+	CreateAttributes bool
+
+	// Количество элементов в одном запросе массового создания и изменения (по умолчанию 100).
+	BatchSize int
+}
+
+// CommerceMLImportResult результат загрузки файла CommerceML.
+type CommerceMLImportResult struct {
+	Folders    int     // Загружено групп товаров
+	Attributes int     // Создано доп. полей
+	Created    int     // Создано товаров и модификаций
+	Updated    int     // Изменено товаров и модификаций
+	Offers     int     // Загружено цен предложений
+	Enter      *Enter  // Оприходование излишков остатков
+	Loss       *Loss   // Списание недостачи остатков
+	Errors     []error // Ошибки отдельных элементов, не прервавшие загрузку
+}
+
+// HasErrors возвращает true, если при загрузке были ошибки.
+func (result CommerceMLImportResult) HasErrors() bool {
+	return len(result.Errors) > 0
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (result CommerceMLImportResult) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "групп: %d, доп. полей: %d, создано: %d, изменено: %d, предложений: %d, ошибок: %d",
+		result.Folders, result.Attributes, result.Created, result.Updated, result.Offers, len(result.Errors))
+	for _, err := range result.Errors {
+		fmt.Fprintf(&sb, "\n%s", err)
+	}
+	return sb.String()
+}
+
+// commerceMLEntity найденный или созданный товар (модификация).
+type commerceMLEntity struct {
+	meta       Meta
+	salePrices Slice[SalePrice]
+}
+
+// CommerceMLImporter загрузка каталога (import.xml) и пакета предложений (offers.xml) CommerceML.
+//
+// Сопоставление выполняется по внешнему коду:
+//   - Группа – Группа товаров
+//   - Товар – Товар (Ид товара)
+//   - Товар и Предложение с Ид вида «ИдТовара#ИдХарактеристики» – Модификация (Ид характеристики)
+//   - Свойство – Доп. поле товаров (по наименованию)
+//   - БазоваяЕдиница – Единица измерения (по коду ОКЕИ или наименованию)
+//   - ТипЦены – Тип цены (по наименованию)
+//
+// Существующие элементы изменяются, отсутствующие – создаются.
+// Справочники и найденные элементы кешируются между вызовами [CommerceMLImporter.Import],
+// поэтому файлы одного обмена (import.xml, затем offers.xml) следует загружать одним экземпляром.
+type CommerceMLImporter struct {
+	client *Client
+	config CommerceMLImportConfig
+
+	properties      map[string]CommerceMLProperty
+	folders         map[string]*ProductFolder
+	attributes      map[string]*Attribute
+	uoms            map[string]*Uom
+	characteristics map[string]*Characteristic
+	priceTypes      map[string]*PriceType
+	offerPriceTypes map[string]*PriceType
+	products        map[string]*commerceMLEntity
+	variants        map[string]*commerceMLEntity
+}
+
+// NewCommerceMLImporter возвращает [CommerceMLImporter].
+func NewCommerceMLImporter(client *Client, config CommerceMLImportConfig) *CommerceMLImporter {
+	if config.BatchSize <= 0 || config.BatchSize > MaxPositions {
+		config.BatchSize = 100
+	}
+	return &CommerceMLImporter{
+		client:          client,
+		config:          config,
+		properties:      make(map[string]CommerceMLProperty),
+		offerPriceTypes: make(map[string]*PriceType),
+		products:        make(map[string]*commerceMLEntity),
+		variants:        make(map[string]*commerceMLEntity),
+	}
+}
+
+// Import загружает файл CommerceML (import.xml или offers.xml).
+//
+// Файл читается потоково: товары и предложения отправляются порциями по мере чтения.
+// Ошибки отдельных порций не прерывают загрузку и возвращаются в поле Errors результата.
+func (importer *CommerceMLImporter) Import(ctx context.Context, r io.Reader) (*CommerceMLImportResult, error) {
+	if err := importer.prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	run := &commerceMLImport{
+		CommerceMLImporter: importer,
+		ctx:                ctx,
+		result:             new(CommerceMLImportResult),
+		quantities:         make(map[string]float64),
+	}
+
+	if err := ReadCommerceML(r, run); err != nil {
+		return run.result, err
+	}
+	if err := run.flush(); err != nil {
+		return run.result, err
+	}
+	if err := run.importStocks(); err != nil {
+		return run.result, err
+	}
+
+	return run.result, nil
+}
+
+// prepare загружает справочники, необходимые для сопоставления.
+func (importer *CommerceMLImporter) prepare(ctx context.Context) error {
+	if importer.folders == nil {
+		folders, _, err := NewProductFolderService(importer.client).GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		importer.folders = make(map[string]*ProductFolder)
+		for _, folder := range Deref(folders) {
+			if code := folder.GetExternalCode(); code != "" {
+				importer.folders[code] = folder
+			}
+		}
+	}
+
+	if importer.attributes == nil {
+		list, _, err := NewProductService(importer.client).GetAttributeList(ctx)
+		if err != nil {
+			return err
+		}
+		importer.attributes = make(map[string]*Attribute)
+		for _, attribute := range list.Rows {
+			importer.attributes[attribute.GetName()] = attribute
+		}
+	}
+
+	if importer.uoms == nil {
+		uoms, _, err := NewUomService(importer.client).GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		importer.uoms = make(map[string]*Uom)
+		for _, uom := range Deref(uoms) {
+			importer.addUom(uom)
+		}
+	}
+
+	if importer.characteristics == nil {
+		metadata, _, err := NewVariantService(importer.client).GetMetadata(ctx)
+		if err != nil {
+			return err
+		}
+		importer.characteristics = make(map[string]*Characteristic)
+		for _, characteristic := range metadata.Characteristics {
+			importer.characteristics[characteristic.GetName()] = characteristic
+		}
+	}
+
+	if importer.priceTypes == nil {
+		priceTypes, _, err := NewContextCompanySettingsService(importer.client).GetPriceTypes(ctx)
+		if err != nil {
+			return err
+		}
+		importer.priceTypes = make(map[string]*PriceType)
+		for _, priceType := range Deref(priceTypes) {
+			importer.priceTypes[priceType.GetName()] = priceType
+		}
+	}
+
+	return nil
+}
+
+// addUom добавляет единицу измерения в кеш по коду и наименованию.
+func (importer *CommerceMLImporter) addUom(uom *Uom) {
+	if code := Deref(uom.Code); code != "" {
+		importer.uoms["code:"+code] = uom
+	}
+	if name := strings.ToLower(Deref(uom.Name)); name != "" {
+		importer.uoms["name:"+name] = uom
+	}
+}
+
+// commerceMLImport состояние загрузки одного файла, реализует интерфейс [CommerceMLHandler].
+type commerceMLImport struct {
+	*CommerceMLImporter
+	ctx             context.Context
+	result          *CommerceMLImportResult
+	pendingProducts []*CommerceMLProduct
+	pendingVariants []*CommerceMLProduct
+	pendingOffers   []*CommerceMLOffer
+	quantities      map[string]float64
+	order           []Meta
+}
+
+// fail добавляет ошибку элемента в результат.
+func (run *commerceMLImport) fail(id string, err error) {
+	run.result.Errors = append(run.result.Errors, fmt.Errorf("commerceml: %s: %w", id, err))
+}
+
+// Classifier реализует интерфейс [CommerceMLHandler].
+func (run *commerceMLImport) Classifier(classifier *CommerceMLClassifier) error {
+	for _, property := range classifier.Properties {
+		run.properties[property.ID] = property
+	}
+
+	if err := run.importAttributes(classifier.Properties); err != nil {
+		return err
+	}
+	if err := run.importGroups(classifier.Groups); err != nil {
+		return err
+	}
+	if len(classifier.PriceTypes) > 0 {
+		return run.PriceTypes(classifier.PriceTypes)
+	}
+	return nil
+}
+
+// importAttributes создаёт доп. поля товаров для свойств, которых нет в МойСклад.
+func (run *commerceMLImport) importAttributes(properties []CommerceMLProperty) error {
+	if !run.config.CreateAttributes {
+		return nil
+	}
+
+	var attributes Slice[Attribute]
+	for _, property := range properties {
+		if _, ok := run.attributes[property.Name]; ok || property.Name == "" {
+			continue
+		}
+		attributeType := AttributeTypeString
+		switch property.ValueType {
+		case "Число":
+			attributeType = AttributeTypeDouble
+		case "Время":
+			attributeType = AttributeTypeTime
+		}
+		attributes.Push(new(Attribute).SetName(property.Name).SetType(attributeType))
+	}
+
+	if attributes.Len() == 0 {
+		return nil
+	}
+
+	created, _, err := NewProductService(run.client).CreateUpdateAttributeMany(run.ctx, attributes...)
+	if err != nil {
+		return err
+	}
+	for _, attribute := range Deref(created) {
+		run.attributes[attribute.GetName()] = attribute
+	}
+	run.result.Attributes += created.Len()
+	return nil
+}
+
+// importGroups создаёт и изменяет группы товаров по уровням дерева.
+func (run *commerceMLImport) importGroups(groups []CommerceMLGroup) error {
+	type node struct {
+		group  CommerceMLGroup
+		parent string
+	}
+
+	var level []node
+	for _, group := range groups {
+		level = append(level, node{group: group})
+	}
+
+	service := NewProductFolderService(run.client)
+	for len(level) > 0 {
+		var folders Slice[ProductFolder]
+		for _, item := range level {
+			folder := new(ProductFolder).SetName(item.group.Name).SetExternalCode(item.group.ID)
+			if parent, ok := run.folders[item.parent]; ok {
+				folder.SetProductFolder(parent.Clean())
+			}
+			if existing, ok := run.folders[item.group.ID]; ok {
+				folder.SetMeta(existing.Meta)
+			}
+			folders.Push(folder)
+		}
+
+		for _, chunk := range folders.IntoChunks(MaxPositions) {
+			rows, _, err := service.CreateUpdateMany(run.ctx, chunk)
+			if err != nil {
+				return err
+			}
+			for _, folder := range Deref(rows) {
+				run.folders[folder.GetExternalCode()] = folder
+			}
+			run.result.Folders += rows.Len()
+		}
+
+		var next []node
+		for _, item := range level {
+			for _, group := range item.group.Groups {
+				next = append(next, node{group: group, parent: item.group.ID})
+			}
+		}
+		level = next
+	}
+
+	return nil
+}
+
+// Product реализует интерфейс [CommerceMLHandler].
+func (run *commerceMLImport) Product(product *CommerceMLProduct) error {
+	if _, variantID := splitCommerceMLID(product.ID); variantID != "" {
+		run.pendingVariants = append(run.pendingVariants, product)
+	} else {
+		run.pendingProducts = append(run.pendingProducts, product)
+	}
+
+	if len(run.pendingProducts) >= run.config.BatchSize {
+		return run.flushProducts()
+	}
+	if len(run.pendingVariants) >= run.config.BatchSize {
+		if err := run.flushProducts(); err != nil {
+			return err
+		}
+		return run.flushVariants()
+	}
+	return nil
+}
+
+// PriceTypes реализует интерфейс [CommerceMLHandler].
+func (run *commerceMLImport) PriceTypes(priceTypes []CommerceMLPriceType) error {
+	for _, priceType := range priceTypes {
+		name := priceType.Name
+		if mapped, ok := run.config.PriceTypes[name]; ok {
+			name = mapped
+		}
+		found, ok := run.priceTypes[name]
+		if !ok {
+			run.fail(priceType.ID, fmt.Errorf("price type %q not found", name))
+			continue
+		}
+		run.offerPriceTypes[priceType.ID] = found
+	}
+	return nil
+}
+
+// Warehouses реализует интерфейс [CommerceMLHandler].
+//
+// Остатки по складам CommerceML суммируются и загружаются на склад из конфигурации.
+func (run *commerceMLImport) Warehouses([]CommerceMLWarehouse) error {
+	return nil
+}
+
+// Offer реализует интерфейс [CommerceMLHandler].
+func (run *commerceMLImport) Offer(offer *CommerceMLOffer) error {
+	run.pendingOffers = append(run.pendingOffers, offer)
+	if len(run.pendingOffers) >= run.config.BatchSize {
+		return run.flushOffers()
+	}
+	return nil
+}
+
+// flush отправляет оставшиеся элементы.
+func (run *commerceMLImport) flush() error {
+	if err := run.flushProducts(); err != nil {
+		return err
+	}
+	if err := run.flushVariants(); err != nil {
+		return err
+	}
+	return run.flushOffers()
+}
+
+// commerceMLCodesPerRequest количество внешних кодов в одном запросе поиска.
+const commerceMLCodesPerRequest = 50
+
+// commerceMLLookup находит сущности по внешним кодам, которых ещё нет в кеше cache.
+//
+// Функция index возвращает внешний код и данные найденной сущности.
+func commerceMLLookup[T any](ctx context.Context, service interface {
+	GetListAll(ctx context.Context, params ...func(*Params)) (*Slice[T], *resty.Response, error)
+}, cache map[string]*commerceMLEntity, codes []string, index func(row *T) (string, *commerceMLEntity)) error {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if _, ok := cache[code]; !ok && code != "" && !seen[code] {
+			seen[code] = true
+			missing = append(missing, code)
+		}
+	}
+
+	chunks := NewSliceFrom(missing)
+	for _, chunk := range chunks.IntoChunks(commerceMLCodesPerRequest) {
+		var params []func(*Params)
+		for _, code := range chunk {
+			params = append(params, WithFilterEquals("externalCode", *code))
+		}
+		rows, _, err := service.GetListAll(ctx, params...)
+		if err != nil {
+			return err
+		}
+		for _, row := range Deref(rows) {
+			code, entity := index(row)
+			cache[code] = entity
+		}
+	}
+	return nil
+}
+
+// lookupProducts находит товары по внешним кодам.
+func (run *commerceMLImport) lookupProducts(codes []string) error {
+	return commerceMLLookup(run.ctx, NewProductService(run.client), run.products, codes,
+		func(product *Product) (string, *commerceMLEntity) {
+			return product.GetExternalCode(), &commerceMLEntity{meta: product.GetMeta(), salePrices: product.SalePrices}
+		})
+}
+
+// lookupVariants находит модификации по внешним кодам.
+func (run *commerceMLImport) lookupVariants(codes []string) error {
+	return commerceMLLookup(run.ctx, NewVariantService(run.client), run.variants, codes,
+		func(variant *Variant) (string, *commerceMLEntity) {
+			return variant.GetExternalCode(), &commerceMLEntity{meta: variant.GetMeta(), salePrices: variant.SalePrices}
+		})
+}
+
+// flushProducts создаёт и изменяет накопленные товары.
+func (run *commerceMLImport) flushProducts() error {
+	if len(run.pendingProducts) == 0 {
+		return nil
+	}
+	pending := run.pendingProducts
+	run.pendingProducts = nil
+
+	codes := make([]string, 0, len(pending))
+	for _, product := range pending {
+		codes = append(codes, product.ID)
+	}
+	if err := run.lookupProducts(codes); err != nil {
+		return err
+	}
+
+	var (
+		products Slice[Product]
+		updates  int
+	)
+	for _, element := range pending {
+		product, err := run.newProduct(element)
+		if err != nil {
+			run.fail(element.ID, err)
+			continue
+		}
+		if existing, ok := run.products[element.ID]; ok {
+			product.SetMeta(&existing.meta)
+			updates++
+		}
+		products.Push(product)
+	}
+
+	if products.Len() == 0 {
+		return nil
+	}
+
+	rows, _, err := NewProductService(run.client).CreateUpdateMany(run.ctx, products)
+	if err != nil {
+		run.fail("товары", err)
+		return nil
+	}
+	for _, product := range Deref(rows) {
+		if _, ok := run.products[product.GetExternalCode()]; !ok {
+			run.products[product.GetExternalCode()] = &commerceMLEntity{meta: product.GetMeta(), salePrices: product.SalePrices}
+		}
+	}
+	run.result.Updated += updates
+	run.result.Created += rows.Len() - updates
+	return nil
+}
+
+// newProduct возвращает товар МойСклад по товару CommerceML.
+func (run *commerceMLImport) newProduct(element *CommerceMLProduct) (*Product, error) {
+	product := new(Product).SetName(element.Name).SetExternalCode(element.ID)
+	if element.Article != "" {
+		product.SetArticle(element.Article)
+	}
+	if element.Description != "" {
+		product.SetDescription(element.Description)
+	}
+	if element.Barcode != "" {
+		product.SetBarcodes(DetectBarcode(element.Barcode))
+	}
+
+	if len(element.Groups) > 0 {
+		if folder, ok := run.folders[element.Groups[0]]; ok {
+			product.SetProductFolder(folder.Clean())
+		}
+	}
+
+	uom, err := run.uom(element.Unit)
+	if err != nil {
+		return nil, err
+	}
+	if uom != nil {
+		product.SetUom(uom.Clean())
+	}
+
+	for _, taxRate := range element.TaxRates {
+		if !strings.EqualFold(taxRate.Name, "НДС") {
+			continue
+		}
+		rate := strings.TrimSuffix(strings.TrimSpace(taxRate.Rate), "%")
+		if vat, err := strconv.Atoi(rate); err == nil {
+			product.SetVat(vat).SetVatEnabled(true)
+		} else {
+			product.SetVatEnabled(false)
+		}
+	}
+
+	if weight := element.Requisite("Вес"); weight != "" {
+		if value, err := parseNumber(weight); err == nil {
+			product.SetWeight(value)
+		}
+	}
+
+	for _, propertyValue := range element.PropertyValues {
+		property, ok := run.properties[propertyValue.ID]
+		if !ok {
+			continue
+		}
+		attribute, ok := run.attributes[property.Name]
+		if !ok {
+			continue
+		}
+
+		value := propertyValue.Value
+		if property.ValueType == "Справочник" {
+			for _, variant := range property.Values {
+				if variant.ID == value {
+					value = variant.Value
+					break
+				}
+			}
+		}
+		if value == "" {
+			continue
+		}
+
+		converted, err := convertAttributeValue(attribute.GetType(), value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", property.Name, err)
+		}
+		product.SetAttributes(new(Attribute).SetMeta(attribute.Meta).SetValue(converted))
+	}
+
+	return product, nil
+}
+
+// uom возвращает единицу измерения по коду ОКЕИ или наименованию, при необходимости создавая её.
+func (run *commerceMLImport) uom(unit *CommerceMLUnit) (*Uom, error) {
+	if unit == nil {
+		return nil, nil
+	}
+
+	code, name := strings.TrimSpace(unit.Code), strings.TrimSpace(unit.Name)
+	if uom, ok := run.uoms["code:"+code]; ok && code != "" {
+		return uom, nil
+	}
+	if uom, ok := run.uoms["name:"+strings.ToLower(name)]; ok && name != "" {
+		return uom, nil
+	}
+	if name == "" {
+		name = unit.FullName
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	uom := new(Uom).SetName(name)
+	if code != "" {
+		uom.SetCode(code)
+	}
+	if unit.FullName != "" {
+		uom.Description = String(unit.FullName)
+	}
+
+	created, _, err := NewUomService(run.client).Create(run.ctx, uom)
+	if err != nil {
+		return nil, err
+	}
+	run.addUom(created)
+	return created, nil
+}
+
+// flushVariants создаёт и изменяет накопленные модификации.
+func (run *commerceMLImport) flushVariants() error {
+	if len(run.pendingVariants) == 0 {
+		return nil
+	}
+	pending := run.pendingVariants
+	run.pendingVariants = nil
+
+	var productCodes, variantCodes []string
+	for _, element := range pending {
+		productID, variantID := splitCommerceMLID(element.ID)
+		productCodes = append(productCodes, productID)
+		variantCodes = append(variantCodes, variantID)
+	}
+	if err := run.lookupProducts(productCodes); err != nil {
+		return err
+	}
+	if err := run.lookupVariants(variantCodes); err != nil {
+		return err
+	}
+
+	var (
+		variants Slice[Variant]
+		updates  int
+	)
+	for _, element := range pending {
+		productID, variantID := splitCommerceMLID(element.ID)
+
+		variant := new(Variant).SetExternalCode(variantID)
+		if existing, ok := run.variants[variantID]; ok {
+			variant.SetMeta(&existing.meta)
+			updates++
+		} else {
+			product, ok := run.products[productID]
+			if !ok {
+				run.fail(element.ID, fmt.Errorf("product %s not found", productID))
+				continue
+			}
+			if len(element.Characteristics) == 0 {
+				run.fail(element.ID, fmt.Errorf("variant has no characteristics"))
+				continue
+			}
+			variant.SetProduct(&Product{Meta: &product.meta})
+		}
+
+		for _, requisite := range element.Characteristics {
+			characteristic, err := run.characteristic(requisite.Name)
+			if err != nil {
+				return err
+			}
+			variant.SetCharacteristics(new(Characteristic).SetMeta(characteristic.Meta).SetValue(requisite.Value))
+		}
+		if element.Barcode != "" {
+			variant.SetBarcodes(DetectBarcode(element.Barcode))
+		}
+		if element.Description != "" {
+			variant.SetDescription(element.Description)
+		}
+
+		variants.Push(variant)
+	}
+
+	if variants.Len() == 0 {
+		return nil
+	}
+
+	rows, _, err := NewVariantService(run.client).CreateUpdateMany(run.ctx, variants)
+	if err != nil {
+		run.fail("модификации", err)
+		return nil
+	}
+	for _, variant := range Deref(rows) {
+		if _, ok := run.variants[variant.GetExternalCode()]; !ok {
+			run.variants[variant.GetExternalCode()] = &commerceMLEntity{meta: variant.GetMeta(), salePrices: variant.SalePrices}
+		}
+	}
+	run.result.Updated += updates
+	run.result.Created += rows.Len() - updates
+	return nil
+}
+
+// characteristic возвращает характеристику модификаций по наименованию, при необходимости создавая её.
+func (run *commerceMLImport) characteristic(name string) (*Characteristic, error) {
+	if characteristic, ok := run.characteristics[name]; ok {
+		return characteristic, nil
+	}
+	characteristic, _, err := NewVariantService(run.client).CreateCharacteristic(run.ctx, new(Characteristic).SetName(name))
+	if err != nil {
+		return nil, err
+	}
+	run.characteristics[name] = characteristic
+	return characteristic, nil
+}
+
+// flushOffers загружает цены и остатки накопленных предложений.
+func (run *commerceMLImport) flushOffers() error {
+	if len(run.pendingOffers) == 0 {
+		return nil
+	}
+	pending := run.pendingOffers
+	run.pendingOffers = nil
+
+	// CommerceML 2.08 и выше передаёт характеристики модификаций в предложениях
+	var productCodes, variantCodes []string
+	for _, offer := range pending {
+		productID, variantID := splitCommerceMLID(offer.ID)
+		productCodes = append(productCodes, productID)
+		variantCodes = append(variantCodes, variantID)
+	}
+	if err := run.lookupProducts(productCodes); err != nil {
+		return err
+	}
+	if err := run.lookupVariants(variantCodes); err != nil {
+		return err
+	}
+	for _, offer := range pending {
+		if _, variantID := splitCommerceMLID(offer.ID); variantID != "" && len(offer.Characteristics) > 0 {
+			if _, ok := run.variants[variantID]; !ok {
+				run.pendingVariants = append(run.pendingVariants, &CommerceMLProduct{
+					ID:              offer.ID,
+					Barcode:         offer.Barcode,
+					Name:            offer.Name,
+					Characteristics: offer.Characteristics,
+				})
+			}
+		}
+	}
+	if err := run.flushVariants(); err != nil {
+		return err
+	}
+
+	var (
+		products Slice[Product]
+		variants Slice[Variant]
+	)
+	for _, offer := range pending {
+		productID, variantID := splitCommerceMLID(offer.ID)
+
+		entity, ok := run.products[productID]
+		if variantID != "" {
+			entity, ok = run.variants[variantID]
+		}
+		if !ok {
+			run.fail(offer.ID, fmt.Errorf("assortment not found"))
+			continue
+		}
+
+		if quantity, ok := offer.TotalQuantity(); ok && run.config.Store != nil {
+			href := entity.meta.GetHref()
+			if _, seen := run.quantities[href]; !seen {
+				run.order = append(run.order, entity.meta)
+			}
+			run.quantities[href] = quantity
+		}
+
+		changes := make(map[string]float64)
+		for _, price := range offer.Prices {
+			if priceType, ok := run.offerPriceTypes[price.PriceTypeID]; ok {
+				changes[priceType.GetMeta().GetHref()] = math.Round(price.Value * 100)
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+
+		entity.salePrices = commerceMLSalePrices(entity.salePrices, changes, run.offerPriceTypes)
+		meta := entity.meta
+		if variantID != "" {
+			variants.Push(&Variant{Meta: &meta, SalePrices: entity.salePrices})
+		} else {
+			products.Push(&Product{Meta: &meta, SalePrices: entity.salePrices})
+		}
+	}
+
+	updated, failed, err := repriceUpdate(run.ctx, NewProductService(run.client), products)
+	run.result.Offers += updated
+	if err != nil {
+		run.fail("цены товаров", err)
+	}
+	for _, failure := range failed {
+		run.fail("цены товаров", failure)
+	}
+	updated, failed, err = repriceUpdate(run.ctx, NewVariantService(run.client), variants)
+	run.result.Offers += updated
+	if err != nil {
+		run.fail("цены модификаций", err)
+	}
+	for _, failure := range failed {
+		run.fail("цены модификаций", failure)
+	}
+
+	return nil
+}
+
+// commerceMLSalePrices возвращает цены продажи с изменёнными значениями changes (по href типа цены).
+func commerceMLSalePrices(salePrices Slice[SalePrice], changes map[string]float64, priceTypes map[string]*PriceType) Slice[SalePrice] {
+	var (
+		result = make(Slice[SalePrice], 0, len(salePrices))
+		seen   = make(map[string]bool)
+	)
+
+	for _, salePrice := range salePrices {
+		href := salePrice.GetPriceType().GetMeta().GetHref()
+		value := salePrice.GetValue()
+		if changed, ok := changes[href]; ok {
+			value = changed
+		}
+		seen[href] = true
+		result.Push(&SalePrice{Value: &value, Currency: salePrice.Currency, PriceType: salePrice.GetPriceType().Clean()})
+	}
+
+	for _, priceType := range priceTypes {
+		href := priceType.GetMeta().GetHref()
+		value, ok := changes[href]
+		if !ok || seen[href] {
+			continue
+		}
+		seen[href] = true
+		result.Push(&SalePrice{Value: &value, PriceType: priceType.Clean()})
+	}
+
+	return result
+}
+
+// importStocks оформляет расхождение загруженных остатков с текущими Оприходованием и Списанием.
+func (run *commerceMLImport) importStocks() error {
+	if run.config.Store == nil || len(run.order) == 0 {
+		return nil
+	}
+	if run.config.Organization == nil {
+		return fmt.Errorf("commerceml: organization is required to import stocks")
+	}
+
+	stocks, _, err := NewReportStockService(run.client).GetCurrentByStore(run.ctx, WithFilterEquals("storeId", run.config.Store.GetID()))
+	if err != nil {
+		return err
+	}
+	current := make(map[string]float64)
+	for _, stock := range Deref(stocks) {
+		current[stock.AssortmentID] += stock.Stock
+	}
+
+	var (
+		enterPositions Slice[EnterPosition]
+		lossPositions  Slice[LossPosition]
+	)
+	for _, meta := range run.order {
+		difference := run.quantities[meta.GetHref()] - current[meta.GetUUIDFromHref()]
+		assortment := &AssortmentPosition{Meta: meta}
+		switch {
+		case difference > 0:
+			enterPositions.Push(new(EnterPosition).SetAssortment(assortment).SetQuantity(difference))
+		case difference < 0:
+			lossPositions.Push(new(LossPosition).SetAssortment(assortment).SetQuantity(-difference))
+		}
+	}
+
+	const description = "Загрузка остатков CommerceML"
+
+	if enterPositions.Len() > 0 {
+		service := NewEnterService(run.client)
+		enter := new(Enter).
+			SetStore(run.config.Store.Clean()).
+			SetOrganization(run.config.Organization.Clean()).
+			SetDescription(description).
+			SetApplicable(run.config.Applicable)
+		if run.result.Enter, _, err = service.Create(run.ctx, enter); err != nil {
+			return err
+		}
+		if _, _, err = service.CreatePositionMany(run.ctx, run.result.Enter.GetID(), enterPositions...); err != nil {
+			return err
+		}
+	}
+
+	if lossPositions.Len() > 0 {
+		service := NewLossService(run.client)
+		loss := new(Loss).
+			SetStore(run.config.Store.Clean()).
+			SetOrganization(run.config.Organization.Clean()).
+			SetDescription(description).
+			SetApplicable(run.config.Applicable)
+		if run.result.Loss, _, err = service.Create(run.ctx, loss); err != nil {
+			return err
+		}
+		if _, _, err = service.CreatePositionMany(run.ctx, run.result.Loss.GetID(), lossPositions...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
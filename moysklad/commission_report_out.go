@@ -3,6 +3,7 @@ package moysklad
 import (
 	"context"
 	"github.com/go-resty/resty/v2"
+	"io"
 
 	"time"
 )
@@ -874,6 +875,9 @@ type CommissionReportOutService interface {
 	// Возвращает список файлов.
 	CreateFile(ctx context.Context, id string, file *File) (*Slice[File], *resty.Response, error)
 
+	// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+	UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error)
+
 	// UpdateFileMany выполняет запрос на массовое создание и/или изменение файлов сущности/документа.
 	// Принимает контекст, ID сущности/документа и множество файлов.
 	// Возвращает созданных и/или изменённых файлов.
@@ -0,0 +1,838 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ConfigAttributeTypes сущности, доп. поля которых переносятся по умолчанию.
+//
+// Доп. поля товаров, услуг и комплектов общие и переносятся вместе с [MetaTypeProduct].
+var ConfigAttributeTypes = []MetaType{
+	MetaTypeProduct, MetaTypeCounterparty, MetaTypeOrganization, MetaTypeEmployee, MetaTypeContract,
+	MetaTypeProject, MetaTypeStore, MetaTypeConsignment, MetaTypeProcessingPlan, MetaTypeBonusProgram,
+	MetaTypeCustomerOrder, MetaTypeDemand, MetaTypeInvoiceOut, MetaTypeInvoiceIn, MetaTypeSupply,
+	MetaTypePurchaseOrder, MetaTypeSalesReturn, MetaTypePurchaseReturn, MetaTypePaymentIn, MetaTypePaymentOut,
+	MetaTypeCashIn, MetaTypeCashOut, MetaTypeMove, MetaTypeEnter, MetaTypeLoss, MetaTypeInventory,
+	MetaTypeProcessing, MetaTypeProcessingOrder, MetaTypeInternalOrder, MetaTypeRetailDemand,
+	MetaTypeRetailSalesReturn, MetaTypeRetailDrawerCashIn, MetaTypeRetailDrawerCashOut, MetaTypeRetailShift,
+	MetaTypeFactureOut, MetaTypeFactureIn, MetaTypeCommissionReportIn, MetaTypeCommissionReportOut,
+	MetaTypePriceList, MetaTypePrepayment, MetaTypePrepaymentReturn, MetaTypeCounterpartyAdjustment,
+	MetaTypeProductionTask, MetaTypeBonusTransaction,
+}
+
+// ConfigStateTypes сущности, статусы которых переносятся по умолчанию.
+var ConfigStateTypes = []MetaType{
+	MetaTypeCounterparty, MetaTypeCustomerOrder, MetaTypeDemand, MetaTypeInvoiceOut, MetaTypeInvoiceIn,
+	MetaTypeSupply, MetaTypePurchaseOrder, MetaTypeSalesReturn, MetaTypePurchaseReturn, MetaTypePaymentIn,
+	MetaTypePaymentOut, MetaTypeCashIn, MetaTypeCashOut, MetaTypeMove, MetaTypeEnter, MetaTypeLoss,
+	MetaTypeInventory, MetaTypeProcessing, MetaTypeProcessingOrder, MetaTypeInternalOrder, MetaTypeFactureOut,
+	MetaTypeFactureIn, MetaTypeCommissionReportIn, MetaTypeCommissionReportOut, MetaTypePriceList,
+	MetaTypeCounterpartyAdjustment, MetaTypeProductionTask,
+}
+
+// ConfigCustomEntity Пользовательский справочник с элементами.
+type ConfigCustomEntity struct {
+	Meta     Meta                       `json:"meta"`     // Метаданные справочника
+	Name     string                     `json:"name"`     // Наименование справочника
+	Elements Slice[CustomEntityElement] `json:"elements"` // Элементы справочника
+}
+
+// ConfigStore Склад с зонами и ячейками.
+type ConfigStore struct {
+	Store *Store      `json:"store"` // Склад
+	Zones Slice[Zone] `json:"zones"` // Зоны склада
+	Slots Slice[Slot] `json:"slots"` // Ячейки склада
+}
+
+// ConfigSnapshot Снимок настроек учётной записи.
+type ConfigSnapshot struct {
+	Attributes      map[MetaType]Slice[Attribute] `json:"attributes"`      // Доп. поля по сущностям
+	States          map[MetaType]Slice[State]     `json:"states"`          // Статусы по сущностям
+	PriceTypes      Slice[PriceType]              `json:"priceTypes"`      // Типы цен
+	CustomEntities  []*ConfigCustomEntity         `json:"customEntities"`  // Пользовательские справочники
+	ExpenseItems    Slice[ExpenseItem]            `json:"expenseItems"`    // Статьи расходов
+	SalesChannels   Slice[SalesChannel]           `json:"salesChannels"`   // Каналы продаж
+	Projects        Slice[Project]                `json:"projects"`        // Проекты
+	Stores          []*ConfigStore                `json:"stores"`          // Склады с зонами и ячейками
+	Webhooks        Slice[Webhook]                `json:"webhooks"`        // Веб-хуки (кроме созданных приложениями)
+	Characteristics Slice[Characteristic]         `json:"characteristics"` // Характеристики модификаций
+}
+
+// ReadConfigSnapshot читает снимок настроек в формате JSON.
+func ReadConfigSnapshot(r io.Reader) (*ConfigSnapshot, error) {
+	snapshot := new(ConfigSnapshot)
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("config migration: %w", err)
+	}
+	return snapshot, nil
+}
+
+// WriteJSON записывает снимок настроек в формате JSON.
+func (snapshot ConfigSnapshot) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// ConfigMigrationConfig конфигурация переноса настроек.
+type ConfigMigrationConfig struct {
+	// Сущности, доп. поля которых переносятся (по умолчанию [ConfigAttributeTypes]).
+	AttributeTypes []MetaType
+
+	// Сущности, статусы которых переносятся (по умолчанию [ConfigStateTypes]).
+	StateTypes []MetaType
+}
+
+// ConfigMigrationResult Результат применения снимка настроек.
+type ConfigMigrationResult struct {
+	Created []string // Созданные объекты
+	Skipped []string // Объекты, которые не удалось сопоставить
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (result ConfigMigrationResult) String() string {
+	return Stringify(result)
+}
+
+// ConfigMigration перенос настроек между учётными записями МойСклад.
+//
+// Снимок включает доп. поля, статусы документов, типы цен, пользовательские справочники с элементами,
+// характеристики модификаций, статьи расходов, каналы продаж, проекты, склады с зонами и ячейками и веб-хуки.
+//
+// При применении объекты сопоставляются по наименованию (веб-хуки – по сущности, действию и URL);
+// отсутствующие создаются, существующие не изменяются. Ссылки в зависимых объектах
+// (справочник доп. поля, значения доп. полей, зона ячейки, родительский склад) заменяются
+// ссылками на объекты целевой учётной записи; ссылки на объекты, которые не переносятся
+// (сотрудники, отделы, контрагенты и т.д.), не копируются.
+//
+// Пример:
+//
+//	migration := moysklad.NewConfigMigration(moysklad.ConfigMigrationConfig{})
+//	snapshot, err := migration.Export(ctx, staging)
+//	result, err := migration.Apply(ctx, production, snapshot)
+type ConfigMigration struct {
+	config ConfigMigrationConfig
+}
+
+// NewConfigMigration возвращает [ConfigMigration].
+func NewConfigMigration(config ConfigMigrationConfig) *ConfigMigration {
+	if config.AttributeTypes == nil {
+		config.AttributeTypes = ConfigAttributeTypes
+	}
+	if config.StateTypes == nil {
+		config.StateTypes = ConfigStateTypes
+	}
+	return &ConfigMigration{config: config}
+}
+
+// Migrate переносит настройки учётной записи source в учётную запись target.
+func (migration *ConfigMigration) Migrate(ctx context.Context, source, target *Client) (*ConfigMigrationResult, error) {
+	snapshot, err := migration.Export(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Apply(ctx, target, snapshot)
+}
+
+// configMetadataStates статусы из метаданных сущности.
+type configMetadataStates struct {
+	States Slice[State] `json:"states"`
+}
+
+// configStates возвращает статусы сущности metaType.
+func configStates(ctx context.Context, client *Client, metaType MetaType) (Slice[State], error) {
+	path := EndpointEntity + string(metaType) + "/metadata"
+	metadata, _, err := NewRequestBuilder[configMetadataStates](client, path).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.States, nil
+}
+
+// configAttributes возвращает сервис доп. полей сущности metaType.
+func configAttributes(client *Client, metaType MetaType) *endpointAttributes {
+	return &endpointAttributes{NewEndpoint(client, EndpointEntity+string(metaType))}
+}
+
+// Export снимает настройки учётной записи клиента client.
+func (migration *ConfigMigration) Export(ctx context.Context, client *Client) (*ConfigSnapshot, error) {
+	snapshot := &ConfigSnapshot{
+		Attributes: make(map[MetaType]Slice[Attribute]),
+		States:     make(map[MetaType]Slice[State]),
+	}
+
+	for _, metaType := range migration.config.AttributeTypes {
+		list, _, err := configAttributes(client, metaType).GetAttributeList(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if list.Len() > 0 {
+			snapshot.Attributes[metaType] = list.Rows
+		}
+	}
+	for _, metaType := range migration.config.StateTypes {
+		states, err := configStates(ctx, client, metaType)
+		if err != nil {
+			return nil, err
+		}
+		if states.Len() > 0 {
+			snapshot.States[metaType] = states
+		}
+	}
+
+	companySettings := NewContextCompanySettingsService(client)
+	priceTypes, _, err := companySettings.GetPriceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.PriceTypes = Deref(priceTypes)
+
+	metadata, _, err := companySettings.GetMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customEntityService := NewCustomEntityService(client)
+	for _, customEntity := range metadata.CustomEntities {
+		elements, _, err := customEntityService.GetElementList(ctx, customEntity.Meta.GetUUIDFromHref())
+		if err != nil {
+			return nil, err
+		}
+		snapshot.CustomEntities = append(snapshot.CustomEntities, &ConfigCustomEntity{
+			Meta:     customEntity.Meta,
+			Name:     customEntity.Name,
+			Elements: elements.Rows,
+		})
+	}
+
+	expenseItems, _, err := NewExpenseItemService(client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.ExpenseItems = Deref(expenseItems)
+
+	salesChannels, _, err := NewSalesChannelService(client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.SalesChannels = Deref(salesChannels)
+
+	projects, _, err := NewProjectService(client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Projects = Deref(projects)
+
+	storeService := NewStoreService(client)
+	stores, _, err := storeService.GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, store := range Deref(stores) {
+		zones, _, err := storeService.GetZoneList(ctx, store.GetID())
+		if err != nil {
+			return nil, err
+		}
+		slots, _, err := storeService.GetSlotList(ctx, store.GetID())
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Stores = append(snapshot.Stores, &ConfigStore{Store: store, Zones: zones.Rows, Slots: slots.Rows})
+	}
+
+	variantMetadata, _, err := NewVariantService(client).GetMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Characteristics = variantMetadata.Characteristics
+
+	webhooks, _, err := NewWebhookService(client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range Deref(webhooks) {
+		// веб-хуки приложений создаются и удаляются самими приложениями
+		if webhook.AuthorApplication == nil {
+			snapshot.Webhooks.Push(webhook)
+		}
+	}
+	return snapshot, nil
+}
+
+// Apply применяет снимок настроек snapshot к учётной записи клиента client.
+func (migration *ConfigMigration) Apply(ctx context.Context, client *Client, snapshot *ConfigSnapshot) (*ConfigMigrationResult, error) {
+	apply := newConfigApply(client)
+	return apply.result, apply.run(ctx, snapshot)
+}
+
+// configApply состояние применения снимка настроек.
+type configApply struct {
+	client *Client
+	ids    map[string]string // Соответствие ID объектов исходной и целевой учётных записей
+	result *ConfigMigrationResult
+}
+
+// newConfigApply возвращает состояние применения снимка настроек к учётной записи клиента client.
+func newConfigApply(client *Client) *configApply {
+	return &configApply{client: client, ids: make(map[string]string), result: new(ConfigMigrationResult)}
+}
+
+// run применяет снимок настроек snapshot.
+func (apply *configApply) run(ctx context.Context, snapshot *ConfigSnapshot) error {
+	client := apply.client
+	steps := []func(context.Context, *ConfigSnapshot) error{
+		apply.priceTypes,
+		apply.customEntities,
+		apply.attributes,
+		apply.characteristics,
+		apply.states,
+		func(ctx context.Context, snapshot *ConfigSnapshot) error {
+			return configSyncList(ctx, apply, NewExpenseItemService(client), snapshot.ExpenseItems, "статья расходов")
+		},
+		func(ctx context.Context, snapshot *ConfigSnapshot) error {
+			return configSyncList(ctx, apply, NewSalesChannelService(client), snapshot.SalesChannels, "канал продаж")
+		},
+		func(ctx context.Context, snapshot *ConfigSnapshot) error {
+			return configSyncList(ctx, apply, NewProjectService(client), snapshot.Projects, "проект")
+		},
+		apply.stores,
+		apply.webhooks,
+	}
+	for _, step := range steps {
+		if err := step(ctx, snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configUUIDPattern шаблон ID объекта МойСклад.
+var configUUIDPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// configSkipFields поля, которые не переносятся между учётными записями.
+var configSkipFields = []string{"id", "meta", "accountId", "updated", "owner", "group", "authorApplication", "zones", "slots", "pathName", "download"}
+
+// created добавляет в результат созданный объект.
+func (apply *configApply) created(kind, name string) {
+	apply.result.Created = append(apply.result.Created, kind+": "+name)
+}
+
+// skipped добавляет в результат объект, который не удалось сопоставить.
+func (apply *configApply) skipped(format string, args ...any) {
+	apply.result.Skipped = append(apply.result.Skipped, fmt.Sprintf(format, args...))
+}
+
+// mapID запоминает соответствие объектов исходной и целевой учётных записей.
+func (apply *configApply) mapID(source, target string) {
+	if source != "" && target != "" {
+		apply.ids[source] = target
+	}
+}
+
+// remap копирует объект source исходной учётной записи в target без служебных полей,
+// заменяя ID в ссылках на ID целевой учётной записи.
+func remapConfig[T any](apply *configApply, source *T) (*T, error) {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for _, field := range configSkipFields {
+		delete(raw, field)
+	}
+	remapped, _ := apply.remapValue(raw)
+
+	if data, err = json.Marshal(remapped); err != nil {
+		return nil, err
+	}
+	target := new(T)
+	if err = json.Unmarshal(data, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// remapValue заменяет ID в ссылках значения value.
+// Возвращает false, если значение ссылается на объект, отсутствующий в целевой учётной записи.
+func (apply *configApply) remapValue(value any) (any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if href, ok := child.(string); ok && strings.HasSuffix(key, "ref") {
+				known := true
+				v[key] = configUUIDPattern.ReplaceAllStringFunc(href, func(id string) string {
+					if target, ok := apply.ids[id]; ok {
+						return target
+					}
+					known = false
+					return id
+				})
+				if !known && key == "href" {
+					return nil, false
+				}
+				continue
+			}
+			remapped, ok := apply.remapValue(child)
+			if !ok {
+				if key == "meta" {
+					return nil, false
+				}
+				delete(v, key)
+				continue
+			}
+			v[key] = remapped
+		}
+		// доп. поля без значения (значение ссылалось на непереносимый объект) не копируются
+		if attributes, ok := v["attributes"].([]any); ok {
+			filtered := attributes[:0]
+			for _, attribute := range attributes {
+				if fields, ok := attribute.(map[string]any); ok && fields["value"] == nil && fields["file"] == nil {
+					continue
+				}
+				filtered = append(filtered, attribute)
+			}
+			v["attributes"] = filtered
+		}
+		return v, true
+	case []any:
+		result := v[:0]
+		for _, child := range v {
+			if remapped, ok := apply.remapValue(child); ok {
+				result = append(result, remapped)
+			}
+		}
+		return result, true
+	}
+	return value, true
+}
+
+// priceTypes создаёт недостающие типы цен.
+func (apply *configApply) priceTypes(ctx context.Context, snapshot *ConfigSnapshot) error {
+	service := NewContextCompanySettingsService(apply.client)
+	current, _, err := service.GetPriceTypes(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*PriceType)
+	for _, priceType := range Deref(current) {
+		byName[strings.ToLower(priceType.GetName())] = priceType
+	}
+
+	priceTypes := Deref(current)
+	var missing []*PriceType
+	for _, priceType := range snapshot.PriceTypes {
+		if existing, ok := byName[strings.ToLower(priceType.GetName())]; ok {
+			apply.mapID(priceType.GetID(), existing.GetID())
+			continue
+		}
+		missing = append(missing, priceType)
+		priceTypes.Push(&PriceType{Name: priceType.Name, ExternalCode: priceType.ExternalCode})
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	updated, _, err := service.UpdatePriceTypeMany(ctx, priceTypes)
+	if err != nil {
+		return err
+	}
+	byName = make(map[string]*PriceType)
+	for _, priceType := range Deref(updated) {
+		byName[strings.ToLower(priceType.GetName())] = priceType
+	}
+	for _, priceType := range missing {
+		apply.mapID(priceType.GetID(), byName[strings.ToLower(priceType.GetName())].GetID())
+		apply.created("тип цены", priceType.GetName())
+	}
+	return nil
+}
+
+// customEntities создаёт недостающие пользовательские справочники и их элементы.
+func (apply *configApply) customEntities(ctx context.Context, snapshot *ConfigSnapshot) error {
+	metadata, _, err := NewContextCompanySettingsService(apply.client).GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string)
+	for _, customEntity := range metadata.CustomEntities {
+		byName[strings.ToLower(customEntity.Name)] = customEntity.Meta.GetUUIDFromHref()
+	}
+
+	service := NewCustomEntityService(apply.client)
+	for _, customEntity := range snapshot.CustomEntities {
+		id, ok := byName[strings.ToLower(customEntity.Name)]
+		if !ok {
+			created, _, err := service.Create(ctx, new(CustomEntity).SetName(customEntity.Name))
+			if err != nil {
+				return err
+			}
+			id = created.GetID()
+			apply.created("справочник", customEntity.Name)
+		}
+		apply.mapID(customEntity.Meta.GetUUIDFromHref(), id)
+
+		elements, _, err := service.GetElementList(ctx, id)
+		if err != nil {
+			return err
+		}
+		existing := make(map[string]string)
+		for _, element := range elements.Rows {
+			existing[strings.ToLower(element.GetName())] = element.GetID()
+		}
+		for _, element := range customEntity.Elements {
+			if elementID, ok := existing[strings.ToLower(element.GetName())]; ok {
+				apply.mapID(element.GetID(), elementID)
+				continue
+			}
+			remapped, err := remapConfig(apply, element)
+			if err != nil {
+				return err
+			}
+			created, _, err := service.CreateElement(ctx, id, remapped)
+			if err != nil {
+				return err
+			}
+			apply.mapID(element.GetID(), created.GetID())
+			apply.created("элемент справочника "+customEntity.Name, element.GetName())
+		}
+	}
+	return nil
+}
+
+// characteristics создаёт недостающие характеристики модификаций.
+func (apply *configApply) characteristics(ctx context.Context, snapshot *ConfigSnapshot) error {
+	if snapshot.Characteristics.Len() == 0 {
+		return nil
+	}
+	service := NewVariantService(apply.client)
+	metadata, _, err := service.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string)
+	for _, characteristic := range metadata.Characteristics {
+		byName[strings.ToLower(characteristic.GetName())] = configCharacteristicID(characteristic)
+	}
+
+	var missing []*Characteristic
+	for _, characteristic := range snapshot.Characteristics {
+		if id, ok := byName[strings.ToLower(characteristic.GetName())]; ok {
+			apply.mapID(configCharacteristicID(characteristic), id)
+			continue
+		}
+		missing = append(missing, &Characteristic{Name: characteristic.Name, Required: characteristic.Required})
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	created, _, err := service.CreateCharacteristicMany(ctx, missing...)
+	if err != nil {
+		return err
+	}
+	for _, characteristic := range Deref(created) {
+		byName[strings.ToLower(characteristic.GetName())] = configCharacteristicID(characteristic)
+	}
+	for _, characteristic := range snapshot.Characteristics {
+		key := strings.ToLower(characteristic.GetName())
+		if _, ok := apply.ids[configCharacteristicID(characteristic)]; !ok {
+			apply.mapID(configCharacteristicID(characteristic), byName[key])
+			apply.created("характеристика", characteristic.GetName())
+		}
+	}
+	return nil
+}
+
+// configCharacteristicID возвращает ID характеристики модификаций.
+func configCharacteristicID(characteristic *Characteristic) string {
+	if id := characteristic.GetID(); id != "" {
+		return id
+	}
+	return characteristic.GetMeta().GetUUIDFromHref()
+}
+
+// attributes создаёт недостающие доп. поля.
+func (apply *configApply) attributes(ctx context.Context, snapshot *ConfigSnapshot) error {
+	for _, metaType := range configSortedKeys(snapshot.Attributes) {
+		service := configAttributes(apply.client, metaType)
+		current, _, err := service.GetAttributeList(ctx)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]*Attribute)
+		for _, attribute := range current.Rows {
+			byName[strings.ToLower(attribute.GetName())] = attribute
+		}
+
+		var sources, missing []*Attribute
+		for _, attribute := range snapshot.Attributes[metaType] {
+			if existing, ok := byName[strings.ToLower(attribute.GetName())]; ok {
+				if existing.GetType() != attribute.GetType() {
+					apply.skipped("доп. поле %s «%s»: тип %s, в целевой учётной записи %s", metaType, attribute.GetName(), attribute.GetType(), existing.GetType())
+					continue
+				}
+				apply.mapID(attribute.GetID(), existing.GetID())
+				continue
+			}
+			remapped, err := remapConfig(apply, attribute)
+			if err != nil {
+				return err
+			}
+			if attribute.GetType() == AttributeTypeDictionaryCustom && remapped.CustomEntityMeta == nil {
+				apply.skipped("доп. поле %s «%s»: справочник не найден", metaType, attribute.GetName())
+				continue
+			}
+			sources = append(sources, attribute)
+			missing = append(missing, remapped)
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		created, _, err := service.CreateUpdateAttributeMany(ctx, missing...)
+		if err != nil {
+			return err
+		}
+		for i, attribute := range Deref(created) {
+			apply.mapID(sources[i].GetID(), attribute.GetID())
+			apply.created("доп. поле "+string(metaType), attribute.GetName())
+		}
+	}
+	return nil
+}
+
+// states создаёт недостающие статусы.
+func (apply *configApply) states(ctx context.Context, snapshot *ConfigSnapshot) error {
+	for _, metaType := range configSortedKeys(snapshot.States) {
+		current, err := configStates(ctx, apply.client, metaType)
+		if err != nil {
+			return err
+		}
+		byName := make(map[string]string)
+		for _, state := range current {
+			byName[strings.ToLower(state.GetName())] = state.GetID()
+		}
+
+		service := &endpointStates{NewEndpoint(apply.client, EndpointEntity+string(metaType))}
+		for _, state := range snapshot.States[metaType] {
+			if id, ok := byName[strings.ToLower(state.GetName())]; ok {
+				apply.mapID(state.GetID(), id)
+				continue
+			}
+			created, _, err := service.CreateState(ctx, &State{Name: state.Name, Color: state.Color, StateType: state.StateType})
+			if err != nil {
+				return err
+			}
+			apply.mapID(state.GetID(), created.GetID())
+			apply.created("статус "+string(metaType), state.GetName())
+		}
+	}
+	return nil
+}
+
+// stores создаёт недостающие склады, их зоны и ячейки.
+func (apply *configApply) stores(ctx context.Context, snapshot *ConfigSnapshot) error {
+	// родительские склады создаются раньше дочерних, чтобы ссылка на родителя была сопоставлена
+	levels := make(map[int]Slice[Store])
+	var depths []int
+	for _, store := range snapshot.Stores {
+		depth := 0
+		if pathName := store.Store.GetPathName(); pathName != "" {
+			depth = strings.Count(pathName, "/") + 1
+		}
+		if _, ok := levels[depth]; !ok {
+			depths = append(depths, depth)
+		}
+		levels[depth] = append(levels[depth], store.Store)
+	}
+	sort.Ints(depths)
+
+	service := NewStoreService(apply.client)
+	for _, depth := range depths {
+		if err := configSyncList(ctx, apply, service, levels[depth], "склад"); err != nil {
+			return err
+		}
+	}
+
+	for _, store := range snapshot.Stores {
+		storeID, ok := apply.ids[store.Store.GetID()]
+		if !ok {
+			continue
+		}
+
+		zones, _, err := service.GetZoneList(ctx, storeID)
+		if err != nil {
+			return err
+		}
+		existingZones := make(map[string]string)
+		for _, zone := range zones.Rows {
+			existingZones[strings.ToLower(zone.GetName())] = zone.GetID()
+		}
+		var sourceZones, missingZones []*Zone
+		for _, zone := range store.Zones {
+			if id, ok := existingZones[strings.ToLower(zone.GetName())]; ok {
+				apply.mapID(zone.GetID(), id)
+				continue
+			}
+			sourceZones = append(sourceZones, zone)
+			missingZones = append(missingZones, &Zone{Name: zone.Name, ExternalCode: zone.ExternalCode})
+		}
+		if len(missingZones) > 0 {
+			created, _, err := service.CreateUpdateZoneMany(ctx, storeID, missingZones...)
+			if err != nil {
+				return err
+			}
+			for i, zone := range Deref(created) {
+				apply.mapID(sourceZones[i].GetID(), zone.GetID())
+				apply.created("зона склада "+store.Store.GetName(), zone.GetName())
+			}
+		}
+
+		slots, _, err := service.GetSlotList(ctx, storeID)
+		if err != nil {
+			return err
+		}
+		existingSlots := make(map[string]bool)
+		for _, slot := range slots.Rows {
+			existingSlots[strings.ToLower(slot.GetName())] = true
+		}
+		var missingSlots []*Slot
+		for _, slot := range store.Slots {
+			if existingSlots[strings.ToLower(slot.GetName())] {
+				continue
+			}
+			remapped, err := remapConfig(apply, slot)
+			if err != nil {
+				return err
+			}
+			missingSlots = append(missingSlots, remapped)
+		}
+		slotChunks := Slice[Slot](missingSlots)
+		for _, chunk := range slotChunks.IntoChunks(MaxPositions) {
+			created, _, err := service.CreateUpdateSlotMany(ctx, storeID, chunk...)
+			if err != nil {
+				return err
+			}
+			for _, slot := range Deref(created) {
+				apply.created("ячейка склада "+store.Store.GetName(), slot.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+// webhooks создаёт недостающие веб-хуки.
+func (apply *configApply) webhooks(ctx context.Context, snapshot *ConfigSnapshot) error {
+	if snapshot.Webhooks.Len() == 0 {
+		return nil
+	}
+	service := NewWebhookService(apply.client)
+	current, _, err := service.GetListAll(ctx)
+	if err != nil {
+		return err
+	}
+	key := func(webhook *Webhook) string {
+		return string(webhook.EntityType) + "|" + string(webhook.Action) + "|" + webhook.GetURL()
+	}
+	existing := make(map[string]bool)
+	for _, webhook := range Deref(current) {
+		existing[key(webhook)] = true
+	}
+
+	missing := NewSlice[Webhook]()
+	for _, webhook := range snapshot.Webhooks {
+		if existing[key(webhook)] {
+			continue
+		}
+		missing.Push(&Webhook{
+			Action:     webhook.Action,
+			DiffType:   webhook.DiffType,
+			Enabled:    webhook.Enabled,
+			EntityType: webhook.EntityType,
+			Method:     webhook.Method,
+			URL:        webhook.URL,
+		})
+	}
+	for _, chunk := range missing.IntoChunks(MaxPositions) {
+		if _, _, err := service.CreateUpdateMany(ctx, chunk); err != nil {
+			return err
+		}
+		for _, webhook := range chunk {
+			apply.created("веб-хук "+string(webhook.EntityType), string(webhook.Action)+" "+webhook.GetURL())
+		}
+	}
+	return nil
+}
+
+// configEntity объект справочника, сопоставляемый по наименованию.
+type configEntity interface {
+	MetaIDOwner
+	GetName() string
+}
+
+// configSyncList создаёт объекты справочника entities, отсутствующие в целевой учётной записи.
+func configSyncList[T configEntity](ctx context.Context, apply *configApply, service interface {
+	GetListAll(ctx context.Context, params ...func(*Params)) (*Slice[T], *resty.Response, error)
+	CreateUpdateMany(ctx context.Context, entities Slice[T], params ...func(*Params)) (*Slice[T], *resty.Response, error)
+}, entities Slice[T], kind string) error {
+	if entities.Len() == 0 {
+		return nil
+	}
+	current, _, err := service.GetListAll(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]string)
+	for _, entity := range Deref(current) {
+		byName[strings.ToLower((*entity).GetName())] = (*entity).GetID()
+	}
+
+	var sources []*T
+	missing := NewSlice[T]()
+	for _, entity := range entities {
+		if id, ok := byName[strings.ToLower((*entity).GetName())]; ok {
+			apply.mapID((*entity).GetID(), id)
+			continue
+		}
+		remapped, err := remapConfig(apply, entity)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, entity)
+		missing.Push(remapped)
+	}
+
+	var offset int
+	for _, chunk := range missing.IntoChunks(MaxPositions) {
+		created, _, err := service.CreateUpdateMany(ctx, chunk)
+		if err != nil {
+			return err
+		}
+		for i, entity := range Deref(created) {
+			apply.mapID((*sources[offset+i]).GetID(), (*entity).GetID())
+			apply.created(kind, (*entity).GetName())
+		}
+		offset += chunk.Len()
+	}
+	return nil
+}
+
+// configSortedKeys возвращает отсортированные коды сущностей.
+func configSortedKeys[V any](values map[MetaType]V) []MetaType {
+	keys := make([]MetaType, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,313 @@
+package moysklad
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// maxURLContentSize ограничение размера содержимого, загружаемого по URL (100 МБ).
+const maxURLContentSize = 100 << 20
+
+// encodedContent содержимое файла, закодированное в base64.
+type encodedContent struct {
+	content string // содержимое в base64
+	size    int64  // размер исходного содержимого в байтах
+}
+
+// encodeContent читает r и кодирует содержимое в base64, одновременно вычисляя размер.
+//
+// Закодированное содержимое возвращается строкой, поэтому целиком находится в памяти;
+// для потоковой загрузки используется [contentBody].
+func encodeContent(r io.Reader) (*encodedContent, error) {
+	var builder strings.Builder
+	encoder := base64.NewEncoder(base64.StdEncoding, &builder)
+
+	size, err := io.Copy(encoder, r)
+	if err != nil {
+		return nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+	return &encodedContent{content: builder.String(), size: size}, nil
+}
+
+// contentBody возвращает тело запроса на добавление файла или изображения с именем filename и содержимым r.
+//
+// Тело запроса формируется по мере чтения: содержимое r кодируется в base64 и записывается в канал
+// [io.Pipe], поэтому в памяти не хранится ни исходное, ни закодированное содержимое.
+// После выполнения запроса канал необходимо закрыть.
+func contentBody(filename string, r io.Reader) *io.PipeReader {
+	pr, pw := io.Pipe()
+	go func() {
+		name, err := json.Marshal(filename)
+		if err == nil {
+			_, err = fmt.Fprintf(pw, `{"title":%s,"filename":%s,"content":"`, name, name)
+		}
+		if err == nil {
+			encoder := base64.NewEncoder(base64.StdEncoding, pw)
+			if _, err = io.Copy(encoder, r); err == nil {
+				err = encoder.Close()
+			}
+		}
+		if err == nil {
+			_, err = io.WriteString(pw, `"}`)
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// uploadContent выполняет запрос на добавление файла или изображения по адресу path с потоковой передачей содержимого r.
+func uploadContent[T any](ctx context.Context, client *Client, path, filename string, r io.Reader) (*Slice[T], *resty.Response, error) {
+	body := contentBody(filename, r)
+	defer body.Close()
+	return NewRequestBuilder[Slice[T]](client, path).SetHeader("Content-Type", "application/json").Post(ctx, body)
+}
+
+// ContentHash возвращает SHA-256 содержимого r в шестнадцатеричном виде.
+func ContentHash(r io.Reader) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// getFilenameContent возвращает имя файла filePath и его содержимое, закодированное в base64.
+func getFilenameContent(filePath string) (string, string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	encoded, err := encodeContent(f)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Base(filePath), encoded.content, nil
+}
+
+// getContentFromURL скачивает содержимое по ссылке rawURL и возвращает имя файла из ссылки
+// и содержимое, закодированное в base64.
+func getContentFromURL(ctx context.Context, rawURL string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", "", fmt.Errorf("get content %s: %s", rawURL, resp.Status)
+	}
+	if resp.ContentLength > maxURLContentSize {
+		return "", "", fmt.Errorf("get content %s: size %d exceeds %d bytes", rawURL, resp.ContentLength, maxURLContentSize)
+	}
+
+	encoded, err := encodeContent(io.LimitReader(resp.Body, maxURLContentSize+1))
+	if err != nil {
+		return "", "", err
+	}
+	if encoded.size > maxURLContentSize {
+		return "", "", fmt.Errorf("get content %s: size exceeds %d bytes", rawURL, maxURLContentSize)
+	}
+
+	var filename string
+	if u, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			filename = base
+		}
+	}
+	return filename, encoded.content, nil
+}
+
+// NewFileFromReader принимает имя файла и содержимое r и возвращает [File].
+func NewFileFromReader(filename string, r io.Reader) (*File, error) {
+	encoded, err := encodeContent(r)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Title: String(filename), Filename: String(filename), Content: String(encoded.content)}, nil
+}
+
+// NewImageFromReader принимает имя файла и содержимое изображения r и возвращает [Image].
+func NewImageFromReader(filename string, r io.Reader) (*Image, error) {
+	encoded, err := encodeContent(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Image{Title: String(filename), Filename: String(filename), Content: String(encoded.content)}, nil
+}
+
+// NewAttributeFileFromReader принимает имя файла и содержимое r и возвращает [AttributeFile]
+// для доп. поля типа [AttributeTypeFile].
+func NewAttributeFileFromReader(filename string, r io.Reader) (*AttributeFile, error) {
+	encoded, err := encodeContent(r)
+	if err != nil {
+		return nil, err
+	}
+	return &AttributeFile{Filename: filename, Content: encoded.content}, nil
+}
+
+// Download скачивает файл по ссылке href с авторизацией клиента и записывает содержимое в w.
+//
+// Ссылка на скачивание файла или изображения – [Meta.GetDownloadHref],
+// файла доп. поля – ссылка метаданных [Attribute.GetDownload].
+//
+// Если offset больше нуля, то скачивание продолжается с указанной позиции.
+// Если сервер не поддерживает частичную загрузку, то первые offset байт пропускаются.
+//
+// Возвращает количество записанных в w байт.
+func (client *Client) Download(ctx context.Context, href string, w io.Writer, offset int64) (int64, error) {
+	client.limits.Wait()
+	defer client.limits.Done()
+
+	req := client.R().SetContext(ctx).SetDoNotParseResponse(true)
+	if offset > 0 {
+		req.SetHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := req.Get(href)
+	if err != nil {
+		return 0, err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	switch resp.StatusCode() {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		if offset > 0 {
+			if _, err = io.CopyN(io.Discard, body, offset); err != nil {
+				return 0, err
+			}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// файл уже скачан полностью
+		if offset > 0 {
+			return 0, nil
+		}
+		fallthrough
+	default:
+		return 0, fmt.Errorf("download %s: %s", href, resp.Status())
+	}
+	return io.Copy(w, body)
+}
+
+// DownloadToFile скачивает файл по ссылке href с авторизацией клиента в файл filePath.
+//
+// Если файл filePath уже существует, то скачивание продолжается с его текущего размера.
+//
+// Возвращает итоговый размер файла.
+func (client *Client) DownloadToFile(ctx context.Context, href, filePath string) (int64, error) {
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	n, err := client.Download(ctx, href, f, info.Size())
+	return info.Size() + n, err
+}
+
+// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+//
+// Содержимое кодируется в base64 и передаётся потоком по мере чтения r.
+// Перед загрузкой проверяется, что количество файлов не достигло [MaxFiles];
+// в противном случае возвращается ошибка.
+func (endpoint *endpointFiles) UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error) {
+	list, resp, err := endpoint.GetFileList(ctx, id)
+	if err != nil {
+		return nil, resp, err
+	}
+	if list.Size() >= MaxFiles {
+		return nil, resp, fmt.Errorf("upload file %s: entity %s already has %d files (max %d)", filename, id, list.Size(), MaxFiles)
+	}
+
+	return uploadContent[File](ctx, endpoint.client, fmt.Sprintf(EndpointFiles, endpoint.uri, id), filename, r)
+}
+
+// UploadImage выполняет запрос на добавление изображения с именем filename и содержимым r.
+//
+// Если такое же изображение (по размеру и хешу SHA-256 содержимого) уже загружено,
+// то изображение повторно не добавляется и возвращается текущий список изображений.
+// Перед загрузкой проверяется, что количество изображений не достигло [MaxImages].
+//
+// Для вычисления хеша содержимое читается дважды: если r не реализует [io.Seeker],
+// то содержимое предварительно сохраняется во временный файл. Изображение передаётся потоком.
+func (endpoint *endpointImages) UploadImage(ctx context.Context, id, filename string, r io.Reader) (*Slice[Image], *resty.Response, error) {
+	seeker, ok := r.(io.ReadSeeker)
+	if !ok {
+		temp, err := os.CreateTemp("", "moysklad-image-*")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer os.Remove(temp.Name())
+		defer temp.Close()
+		if _, err = io.Copy(temp, r); err != nil {
+			return nil, nil, err
+		}
+		if _, err = temp.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+		seeker = temp
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, seeker)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	list, resp, err := endpoint.GetImageList(ctx, id)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, image := range list.Rows {
+		href := image.GetMeta().GetDownloadHref()
+		if int64(Deref(image.Size)) != size || href == "" {
+			continue
+		}
+		hasher := sha256.New()
+		if _, err = endpoint.client.Download(ctx, href, hasher, 0); err != nil {
+			return nil, resp, err
+		}
+		if hex.EncodeToString(hasher.Sum(nil)) == hash {
+			return &list.Rows, resp, nil
+		}
+	}
+	if list.Size() >= MaxImages {
+		return nil, resp, fmt.Errorf("upload image %s: entity %s already has %d images (max %d)", filename, id, list.Size(), MaxImages)
+	}
+
+	return uploadContent[Image](ctx, endpoint.client, fmt.Sprintf(EndpointImages, endpoint.uri, id), filename, seeker)
+}
@@ -0,0 +1,324 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Переменные окружения для [NewEnvCredentials].
+const (
+	EnvToken     = "MOYSKLAD_TOKEN"      // Токен
+	EnvTokenFile = "MOYSKLAD_TOKEN_FILE" // Путь до файла с токеном
+	EnvUsername  = "MOYSKLAD_USERNAME"   // Логин
+	EnvPassword  = "MOYSKLAD_PASSWORD"   // Пароль
+)
+
+// CredentialsProvider источник токена доступа к API МойСклад.
+//
+// Устанавливается в [Config.Credentials]. Токен запрашивается перед каждым запросом к API;
+// если сервис отвечает 401 (токен отозван или устарел), то вызывается Invalidate
+// и запрос повторяется один раз с новым токеном.
+type CredentialsProvider interface {
+	// Token возвращает действующий токен.
+	Token(ctx context.Context) (string, error)
+
+	// Invalidate сообщает, что токен token отклонён сервисом.
+	// Следующий вызов Token должен вернуть новый токен, если он может быть получен.
+	Invalidate(token string)
+}
+
+// staticCredentials постоянный токен.
+type staticCredentials string
+
+// NewStaticCredentials возвращает [CredentialsProvider] с постоянным токеном token.
+func NewStaticCredentials(token string) CredentialsProvider {
+	return staticCredentials(token)
+}
+
+// Token реализует интерфейс [CredentialsProvider].
+func (credentials staticCredentials) Token(context.Context) (string, error) {
+	return string(credentials), nil
+}
+
+// Invalidate реализует интерфейс [CredentialsProvider].
+func (staticCredentials) Invalidate(string) {}
+
+// envCredentials токен из переменной окружения.
+type envCredentials string
+
+// Token реализует интерфейс [CredentialsProvider].
+func (credentials envCredentials) Token(context.Context) (string, error) {
+	token := strings.TrimSpace(os.Getenv(string(credentials)))
+	if token == "" {
+		return "", fmt.Errorf("credentials: environment variable %s is empty", string(credentials))
+	}
+	return token, nil
+}
+
+// Invalidate реализует интерфейс [CredentialsProvider].
+func (envCredentials) Invalidate(string) {}
+
+// NewEnvCredentials возвращает [CredentialsProvider] по переменным окружения:
+//   - MOYSKLAD_TOKEN – токен (читается при каждом запросе);
+//   - MOYSKLAD_TOKEN_FILE – путь до файла с токеном (см. [NewFileCredentials]);
+//   - MOYSKLAD_USERNAME и MOYSKLAD_PASSWORD – логин и пароль (см. [NewPasswordCredentials]).
+//
+// Переменные проверяются в указанном порядке.
+func NewEnvCredentials() (CredentialsProvider, error) {
+	switch {
+	case os.Getenv(EnvToken) != "":
+		return envCredentials(EnvToken), nil
+	case os.Getenv(EnvTokenFile) != "":
+		return NewFileCredentials(os.Getenv(EnvTokenFile)), nil
+	case os.Getenv(EnvUsername) != "" && os.Getenv(EnvPassword) != "":
+		return NewPasswordCredentials(os.Getenv(EnvUsername), os.Getenv(EnvPassword)), nil
+	}
+	return nil, fmt.Errorf("credentials: none of %s, %s, %s/%s is set", EnvToken, EnvTokenFile, EnvUsername, EnvPassword)
+}
+
+// FileCredentials токен из файла (например, секрета, смонтированного в контейнер).
+//
+// Файл перечитывается при изменении времени модификации и после отклонения токена сервисом.
+type FileCredentials struct {
+	path    string
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+// NewFileCredentials возвращает [FileCredentials] для файла filePath.
+func NewFileCredentials(filePath string) *FileCredentials {
+	return &FileCredentials{path: filePath}
+}
+
+// Token реализует интерфейс [CredentialsProvider].
+func (credentials *FileCredentials) Token(context.Context) (string, error) {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+
+	info, err := os.Stat(credentials.path)
+	if err != nil {
+		return "", err
+	}
+	if credentials.token != "" && info.ModTime().Equal(credentials.modTime) {
+		return credentials.token, nil
+	}
+
+	data, err := os.ReadFile(credentials.path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("credentials: file %s is empty", credentials.path)
+	}
+	credentials.token, credentials.modTime = token, info.ModTime()
+	return token, nil
+}
+
+// Invalidate реализует интерфейс [CredentialsProvider].
+func (credentials *FileCredentials) Invalidate(token string) {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	if credentials.token == token {
+		credentials.token = ""
+	}
+}
+
+// PasswordCredentials токен, полученный по логину и паролю.
+//
+// Токен запрашивается при первом обращении и после отклонения сервисом.
+// Параллельные запросы ожидают получения одного нового токена.
+//
+// [Документация МойСклад]
+//
+// [Документация МойСклад]: https://dev.moysklad.ru/doc/api/remap/1.2/#mojsklad-json-api-obschie-swedeniq-autentifikaciq-poluchenie-nowogo-tokena
+type PasswordCredentials struct {
+	client *resty.Client
+	mu     sync.Mutex
+	token  string
+	fetch  *tokenFetch // выполняемый запрос нового токена
+}
+
+// tokenFetch запрос нового токена, результат которого ожидают параллельные вызовы Token.
+type tokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewPasswordCredentials возвращает [PasswordCredentials] для логина username и пароля password.
+func NewPasswordCredentials(username, password string) *PasswordCredentials {
+	client := resty.New().
+		SetBaseURL(baseApiURL).
+		SetBasicAuth(username, password).
+		SetHeader("Accept", "application/json;charset=utf-8").
+		SetHeader("Accept-Encoding", "gzip").
+		SetHeader("User-Agent", fmt.Sprintf("go-moysklad/%s, https://github.com/arcsub/go-moysklad", Version))
+	return &PasswordCredentials{client: client}
+}
+
+// Token реализует интерфейс [CredentialsProvider].
+//
+// Запрос токена выполняется без блокировки: параллельные вызовы ожидают его результата
+// либо отмены своего контекста.
+func (credentials *PasswordCredentials) Token(ctx context.Context) (string, error) {
+	credentials.mu.Lock()
+	if token := credentials.token; token != "" {
+		credentials.mu.Unlock()
+		return token, nil
+	}
+	fetch := credentials.fetch
+	if fetch != nil {
+		credentials.mu.Unlock()
+		select {
+		case <-fetch.done:
+			return fetch.token, fetch.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	fetch = &tokenFetch{done: make(chan struct{})}
+	credentials.fetch = fetch
+	credentials.mu.Unlock()
+
+	fetch.token, fetch.err = credentials.request(ctx)
+
+	credentials.mu.Lock()
+	credentials.fetch = nil
+	if fetch.err == nil {
+		credentials.token = fetch.token
+	}
+	credentials.mu.Unlock()
+	close(fetch.done)
+	return fetch.token, fetch.err
+}
+
+// request запрашивает новый токен.
+func (credentials *PasswordCredentials) request(ctx context.Context) (string, error) {
+	resp, err := credentials.client.R().SetContext(ctx).Post(EndpointToken)
+	if err != nil {
+		return "", err
+	}
+	token, _, err := parseResponse[Token](resp)
+	if err != nil {
+		return "", err
+	}
+	if token == nil || token.AccessToken == "" {
+		return "", fmt.Errorf("credentials: token request failed: %s", resp.Status())
+	}
+	return token.AccessToken, nil
+}
+
+// Invalidate реализует интерфейс [CredentialsProvider].
+func (credentials *PasswordCredentials) Invalidate(token string) {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	if credentials.token == token {
+		credentials.token = ""
+	}
+}
+
+// credentialsTransport добавляет токен из [CredentialsProvider] к запросам к API
+// и повторяет запрос с новым токеном после ответа 401.
+type credentialsTransport struct {
+	base        http.RoundTripper
+	credentials CredentialsProvider
+	client      *Client
+}
+
+// newCredentialsTransport оборачивает транспорт base.
+func newCredentialsTransport(base http.RoundTripper, credentials CredentialsProvider, client *Client) *credentialsTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &credentialsTransport{base: base, credentials: credentials, client: client}
+}
+
+// restyWithCredentials возвращает копию клиента source с собственным [http.Client],
+// транспорт которого передаёт токен из credentials. Клиент source не изменяется,
+// поэтому один клиент resty может использоваться несколькими клиентами с разными токенами.
+//
+// Переносятся настройки http.Client и открытые поля [resty.Client] (заголовки, параметры, повторы, сериализация).
+func restyWithCredentials(source *resty.Client, credentials CredentialsProvider, client *Client) *resty.Client {
+	httpClient := *source.GetClient()
+	httpClient.Transport = newCredentialsTransport(httpClient.Transport, credentials, client)
+
+	restyClient := resty.NewWithClient(&httpClient)
+	restyClient.Header = source.Header.Clone()
+	restyClient.QueryParam = source.QueryParam
+	restyClient.FormData = source.FormData
+	restyClient.PathParams = source.PathParams
+	restyClient.RawPathParams = source.RawPathParams
+	restyClient.Cookies = source.Cookies
+	restyClient.Error = source.Error
+	restyClient.Debug = source.Debug
+	restyClient.DisableWarn = source.DisableWarn
+	restyClient.AllowGetMethodPayload = source.AllowGetMethodPayload
+	restyClient.RetryCount = source.RetryCount
+	restyClient.RetryWaitTime = source.RetryWaitTime
+	restyClient.RetryMaxWaitTime = source.RetryMaxWaitTime
+	restyClient.RetryConditions = source.RetryConditions
+	restyClient.RetryHooks = source.RetryHooks
+	restyClient.RetryAfter = source.RetryAfter
+	restyClient.RetryResetReaders = source.RetryResetReaders
+	restyClient.JSONMarshal = source.JSONMarshal
+	restyClient.JSONUnmarshal = source.JSONUnmarshal
+	restyClient.XMLMarshal = source.XMLMarshal
+	restyClient.XMLUnmarshal = source.XMLUnmarshal
+	restyClient.ResponseBodyLimit = source.ResponseBodyLimit
+	return restyClient
+}
+
+// RoundTrip реализует интерфейс [http.RoundTripper].
+func (transport *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// токен передаётся только в API МойСклад (не при переходе по ссылкам на файлы)
+	if !strings.HasPrefix(req.URL.String(), transport.client.BaseURL) {
+		return transport.base.RoundTrip(req)
+	}
+
+	token, err := transport.credentials.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := transport.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	transport.credentials.Invalidate(token)
+	// тело запроса, которое нельзя прочитать повторно (например, потоковая загрузка файла),
+	// уже передано: запрос не повторяется, следующий запрос получит новый токен
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	newToken, err := transport.credentials.Token(req.Context())
+	if err != nil || newToken == token {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return transport.send(retry, newToken)
+}
+
+// send выполняет запрос req с токеном token.
+func (transport *credentialsTransport) send(req *http.Request, token string) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return transport.base.RoundTrip(req)
+}
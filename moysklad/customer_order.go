@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"io"
 	"net/http"
 
 	"time"
@@ -89,8 +90,8 @@ func (customerOrder CustomerOrder) AsTaskOperation() *TaskOperation {
 }
 
 // AsOperationIn реализует интерфейс [OperationInConverter].
-func (commissionReportOut CommissionReportOut) AsOperationIn() *Operation {
-	return commissionReportOut.AsOperation()
+func (customerOrder CustomerOrder) AsOperationIn() *Operation {
+	return customerOrder.AsOperation()
 }
 
 // GetOrganizationAccount возвращает Метаданные счета юрлица.
@@ -1072,6 +1073,9 @@ type CustomerOrderService interface {
 	// Возвращает список файлов.
 	CreateFile(ctx context.Context, id string, file *File) (*Slice[File], *resty.Response, error)
 
+	// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+	UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error)
+
 	// UpdateFileMany выполняет запрос на массовое создание и/или изменение файлов сущности/документа.
 	// Принимает контекст, ID сущности/документа и множество файлов.
 	// Возвращает созданных и/или изменённых файлов.
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"github.com/go-resty/resty/v2"
 
-	"io"
 	"net/http"
 	"regexp"
 	"strings"
@@ -634,53 +633,43 @@ type endpointPrintDocument struct{ Endpoint }
 
 var reContentDisposition = regexp.MustCompile(`filename="(.*)"`)
 
-func printFileFromResp(resp *resty.Response) (*PrintFile, *resty.Response, error) {
-	buf := new(bytes.Buffer)
-	if _, err := io.Copy(buf, resp.RawBody()); err != nil {
-		return nil, resp, err
-	}
-
-	var fileName string
-	headerStr := resp.Header().Get(headerContentDisposition)
-	if match := reContentDisposition.FindStringSubmatch(headerStr); len(match) > 1 {
-		fileName = match[1]
-	}
-	file := &PrintFile{buf, fileName}
-	return file, resp, nil
-}
-
 // PrintDocument выполняет запрос на печать документа.
 //
+// Если файл формируется асинхронно (например, при печати комплекта [NewPrintDocArgMany]),
+// то ссылка из заголовка Location опрашивается до готовности файла.
+//
 // [Документация МойСклад]
 //
 // [Документация МойСклад]: https://dev.moysklad.ru/doc/api/remap/1.2/documents/#dokumenty-pechat-dokumentow-zapros-na-pechat
 func (endpoint *endpointPrintDocument) PrintDocument(ctx context.Context, id string, PrintDocumentArg *PrintDocumentArg) (*PrintFile, *resty.Response, error) {
+	file := &PrintFile{Buffer: new(bytes.Buffer)}
 	path := fmt.Sprintf(EndpointExport, endpoint.uri, id)
-	_, resp, err := NewRequestBuilder[PrintFile](endpoint.client, path).SetHeader(headerGetContent, "true").Post(ctx, PrintDocumentArg)
+	fileName, resp, err := printExport(ctx, endpoint.client, path, PrintDocumentArg, file, printPollInterval, printPollTimeout)
 	if err != nil {
 		return nil, resp, err
 	}
-	return printFileFromResp(resp)
+	file.FileName = fileName
+	return file, resp, nil
 }
 
 type endpointPrintLabel struct{ Endpoint }
 
 // PrintLabel выполняет запрос на печать этикеток и ценников.
 //
+// Если файл формируется асинхронно, то ссылка из заголовка Location опрашивается до готовности файла.
+//
 // [Документация МойСклад]
 //
 // [Документация МойСклад]: https://dev.moysklad.ru/doc/api/remap/1.2/dictionaries/#suschnosti-pechat-atiketok-i-cennikow
 func (endpoint *endpointPrintLabel) PrintLabel(ctx context.Context, id string, PrintLabelArg *PrintLabelArg) (*PrintFile, *resty.Response, error) {
+	file := &PrintFile{Buffer: new(bytes.Buffer)}
 	path := fmt.Sprintf(EndpointExport, endpoint.uri, id)
-
-	_, resp, err := NewRequestBuilder[PrintFile](endpoint.client, path).
-		SetHeader(headerGetContent, "true").
-		Post(ctx, PrintLabelArg)
-
+	fileName, resp, err := printExport(ctx, endpoint.client, path, PrintLabelArg, file, printPollInterval, printPollTimeout)
 	if err != nil {
 		return nil, resp, err
 	}
-	return printFileFromResp(resp)
+	file.FileName = fileName
+	return file, resp, nil
 }
 
 type endpointPublication struct{ Endpoint }
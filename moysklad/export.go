@@ -0,0 +1,447 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ExportFormat Формат файла выгрузки.
+//
+// Возможные значения:
+//   - ExportFormatCSV   – CSV
+//   - ExportFormatJSONL – JSON Lines (один объект на строку)
+//   - ExportFormatXLSX  – книга Excel из одного листа
+type ExportFormat string
+
+const (
+	ExportFormatCSV   ExportFormat = "csv"   // CSV
+	ExportFormatJSONL ExportFormat = "jsonl" // JSON Lines
+	ExportFormatXLSX  ExportFormat = "xlsx"  // книга Excel
+)
+
+// ExportField поле выгрузки.
+//
+// Путь указывается JSON-наименованиями полей через точку. Элемент массива выбирается в квадратных скобках
+// по индексу, наименованию (name), наименованию типа цены (priceType.name) или ID, например:
+//   - agent.name
+//   - attributes[Цвет]
+//   - salePrices[Розница].value
+//   - positions[0].assortment.name
+//
+// Если путь заканчивается объектом, то выгружается его значение (value) или наименование (name).
+type ExportField struct {
+	Path  string // Путь к значению
+	Title string // Заголовок столбца (по умолчанию совпадает с путём)
+}
+
+// ExportService описывает метод сервиса, необходимый для постраничной выгрузки сущностей T.
+type ExportService[T any] interface {
+	GetList(ctx context.Context, params ...func(*Params)) (*List[T], *resty.Response, error)
+}
+
+// ExportConfig конфигурация выгрузки.
+type ExportConfig struct {
+	// Поля выгрузки.
+	Fields []ExportField
+
+	// Формат файла выгрузки.
+	Format ExportFormat
+
+	// Разделитель CSV. По умолчанию ';'.
+	Comma rune
+
+	// Наименование листа XLSX.
+	SheetName string
+
+	// Получать объекты, представленные только метаданными (без expand), отдельными запросами.
+	//
+	// Полученные объекты кэшируются на время жизни [Exporter].
+	ResolveMeta bool
+}
+
+type exportWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+// Exporter выгрузка сущностей в файл.
+type Exporter struct {
+	client *Client
+	writer exportWriter
+	cache  map[string]any
+	paths  [][]exportPathSegment
+	config ExportConfig
+	mu     sync.Mutex
+}
+
+// NewExporter возвращает [Exporter], записывающий выгрузку в w.
+//
+// Заголовок записывается сразу (кроме формата JSONL).
+// Клиент client используется для получения объектов по метаданным и может быть nil,
+// если ResolveMeta не установлен.
+func NewExporter(client *Client, w io.Writer, config ExportConfig) (*Exporter, error) {
+	if len(config.Fields) == 0 {
+		return nil, fmt.Errorf("export: fields are empty")
+	}
+	if config.ResolveMeta && client == nil {
+		return nil, fmt.Errorf("export: client is required to resolve meta")
+	}
+
+	exporter := &Exporter{client: client, config: config, cache: make(map[string]any)}
+
+	var header = make([]any, 0, len(config.Fields))
+	for _, field := range config.Fields {
+		path, err := parseExportPath(field.Path)
+		if err != nil {
+			return nil, err
+		}
+		exporter.paths = append(exporter.paths, path)
+
+		title := field.Title
+		if title == "" {
+			title = field.Path
+		}
+		header = append(header, title)
+	}
+
+	switch config.Format {
+	case ExportFormatCSV, "":
+		comma := config.Comma
+		if comma == 0 {
+			comma = ';'
+		}
+		cw := csv.NewWriter(w)
+		cw.Comma = comma
+		exporter.writer = &exportCSVWriter{cw}
+	case ExportFormatJSONL:
+		exporter.writer = &exportJSONLWriter{w: w, header: header}
+		return exporter, nil
+	case ExportFormatXLSX:
+		xw, err := newXLSXWriter(w, config.SheetName)
+		if err != nil {
+			return nil, err
+		}
+		exporter.writer = xw
+	default:
+		return nil, fmt.Errorf("export: unknown format %q", config.Format)
+	}
+
+	if err := exporter.writer.WriteRow(header); err != nil {
+		return nil, err
+	}
+
+	return exporter, nil
+}
+
+// Write выгружает одну сущность.
+func (exporter *Exporter) Write(ctx context.Context, entity any) error {
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	var data any
+	if err = json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	var values = make([]any, 0, len(exporter.paths))
+	for _, path := range exporter.paths {
+		value, err := exporter.resolve(ctx, data, path)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+
+	return exporter.writer.WriteRow(values)
+}
+
+// Close завершает запись выгрузки.
+func (exporter *Exporter) Close() error {
+	return exporter.writer.Close()
+}
+
+// ExportSlice выгружает все элементы среза entities.
+func ExportSlice[T any](ctx context.Context, exporter *Exporter, entities Slice[T]) error {
+	for _, entity := range entities {
+		if err := exporter.Write(ctx, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportList постранично запрашивает сущности сервиса service и выгружает их по мере получения,
+// не загружая весь список в память.
+func ExportList[T any](ctx context.Context, exporter *Exporter, service ExportService[T], params ...func(*Params)) error {
+	return forEachPage(ctx, service, func(rows Slice[T]) error {
+		return ExportSlice(ctx, exporter, rows)
+	}, params...)
+}
+
+// forEachPage постранично запрашивает сущности сервиса service и передаёт каждую страницу в fn.
+func forEachPage[T any](ctx context.Context, service ExportService[T], fn func(rows Slice[T]) error, params ...func(*Params)) error {
+	limit := MaxPositions
+	if len(ApplyParams(params).Expand) > 0 {
+		limit = 100
+	}
+
+	for offset := 0; ; offset += limit {
+		list, _, err := service.GetList(ctx, append(params, WithLimit(limit), WithOffset(offset))...)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(list.Rows); err != nil {
+			return err
+		}
+
+		if list.Len() < limit || offset+limit >= list.Size() {
+			return nil
+		}
+	}
+}
+
+// exportPathSegment элемент пути к значению.
+type exportPathSegment struct {
+	name   string
+	key    string
+	hasKey bool
+}
+
+// parseExportPath разбирает путь вида "a.b[key].c".
+func parseExportPath(path string) ([]exportPathSegment, error) {
+	var (
+		segments []exportPathSegment
+		current  exportPathSegment
+		sb       strings.Builder
+		inKey    bool
+	)
+
+	for _, r := range path {
+		switch {
+		case inKey && r == ']':
+			current.key, current.hasKey, inKey = sb.String(), true, false
+			sb.Reset()
+		case inKey:
+			sb.WriteRune(r)
+		case r == '[':
+			current.name, inKey = sb.String(), true
+			sb.Reset()
+		case r == '.':
+			if !current.hasKey {
+				current.name = sb.String()
+			}
+			segments = append(segments, current)
+			current = exportPathSegment{}
+			sb.Reset()
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	if inKey {
+		return nil, fmt.Errorf("export: unclosed bracket in path %q", path)
+	}
+	if !current.hasKey {
+		current.name = sb.String()
+	}
+	segments = append(segments, current)
+
+	for _, segment := range segments {
+		if segment.name == "" && !segment.hasKey {
+			return nil, fmt.Errorf("export: empty segment in path %q", path)
+		}
+	}
+
+	return segments, nil
+}
+
+// resolve возвращает значение по пути path.
+func (exporter *Exporter) resolve(ctx context.Context, data any, path []exportPathSegment) (any, error) {
+	var err error
+	for _, segment := range path {
+		if segment.name != "" {
+			if data, err = exporter.field(ctx, data, segment.name); err != nil {
+				return nil, err
+			}
+		}
+		if segment.hasKey {
+			data = exportElement(data, segment.key)
+		}
+		if data == nil {
+			return nil, nil
+		}
+	}
+
+	if object, ok := data.(map[string]any); ok {
+		if value, ok := object["value"]; ok {
+			// значение доп. поля типа справочник
+			if element, ok := value.(map[string]any); ok && element["name"] != nil {
+				return element["name"], nil
+			}
+			return exportScalar(value), nil
+		}
+		if name, ok := object["name"]; ok {
+			return name, nil
+		}
+	}
+
+	return exportScalar(data), nil
+}
+
+// field возвращает поле объекта, при необходимости получая объект по метаданным.
+func (exporter *Exporter) field(ctx context.Context, data any, name string) (any, error) {
+	object, ok := data.(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	if value, ok := object[name]; ok || !exporter.config.ResolveMeta {
+		return value, nil
+	}
+
+	// объект представлен только метаданными
+	meta, ok := object["meta"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+	href, _ := meta["href"].(string)
+	if href == "" {
+		return nil, nil
+	}
+
+	exporter.mu.Lock()
+	cached, ok := exporter.cache[href]
+	exporter.mu.Unlock()
+
+	if !ok {
+		var m Meta
+		m.SetHref(href)
+		fetched, _, err := FetchMeta[map[string]any](ctx, exporter.client, m)
+		if err != nil {
+			return nil, err
+		}
+		cached = Deref(fetched)
+
+		exporter.mu.Lock()
+		exporter.cache[href] = cached
+		exporter.mu.Unlock()
+	}
+
+	if resolved, ok := cached.(map[string]any); ok {
+		return resolved[name], nil
+	}
+
+	return nil, nil
+}
+
+// exportElement выбирает элемент массива по индексу, наименованию, наименованию типа цены или ID.
+func exportElement(data any, key string) any {
+	var rows []any
+	switch v := data.(type) {
+	case []any:
+		rows = v
+	case map[string]any:
+		// объект MetaArray
+		rows, _ = v["rows"].([]any)
+	}
+
+	if idx, err := strconv.Atoi(key); err == nil {
+		if idx >= 0 && idx < len(rows) {
+			return rows[idx]
+		}
+		return nil
+	}
+
+	for _, row := range rows {
+		object, ok := row.(map[string]any)
+		if !ok {
+			continue
+		}
+		if object["name"] == key || object["id"] == key {
+			return object
+		}
+		if priceType, ok := object["priceType"].(map[string]any); ok && priceType["name"] == key {
+			return object
+		}
+	}
+
+	return nil
+}
+
+// exportScalar приводит вложенные объекты и массивы к строке JSON.
+func exportScalar(value any) any {
+	switch value.(type) {
+	case map[string]any, []any:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+	return value
+}
+
+type exportCSVWriter struct {
+	cw *csv.Writer
+}
+
+func (writer *exportCSVWriter) WriteRow(values []any) error {
+	var record = make([]string, 0, len(values))
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			record = append(record, "")
+		case float64:
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			record = append(record, fmt.Sprint(v))
+		}
+	}
+	return writer.cw.Write(record)
+}
+
+func (writer *exportCSVWriter) Close() error {
+	writer.cw.Flush()
+	return writer.cw.Error()
+}
+
+type exportJSONLWriter struct {
+	w      io.Writer
+	header []any
+}
+
+func (writer *exportJSONLWriter) WriteRow(values []any) error {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(writer.header[i]))
+		val, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		sb.Write(key)
+		sb.WriteByte(':')
+		sb.Write(val)
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(writer.w, sb.String())
+	return err
+}
+
+func (writer *exportJSONLWriter) Close() error {
+	return nil
+}
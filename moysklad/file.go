@@ -1,6 +1,9 @@
 package moysklad
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // File Файл.
 //
@@ -96,11 +99,23 @@ func (File) MetaType() MetaType {
 
 // NewFileFromURL принимает URL путь до файла и возвращает [File].
 func NewFileFromURL(url string) (*File, error) {
-	content, err := getContentFromURL(url)
+	return NewFileFromURLWithContext(context.Background(), url)
+}
+
+// NewFileFromURLWithContext принимает контекст и URL путь до файла и возвращает [File].
+//
+// Имя файла определяется по последнему элементу пути URL.
+func NewFileFromURLWithContext(ctx context.Context, url string) (*File, error) {
+	filename, content, err := getContentFromURL(ctx, url)
 	if err != nil {
 		return nil, err
 	}
-	return &File{Content: String(content)}, nil
+	file := &File{Content: String(content)}
+	if filename != "" {
+		file.Title = String(filename)
+		file.Filename = String(filename)
+	}
+	return file, nil
 }
 
 // NewFileFromFilepath принимает путь до файла и возвращает [File].
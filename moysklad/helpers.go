@@ -2,16 +2,12 @@ package moysklad
 
 import (
 	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"reflect"
 
 	"io"
-	"os"
-	"path/filepath"
 )
 
 // Bool is a helper routine that allocates a new bool value
@@ -124,27 +120,6 @@ func Clamp[T int | float64](val, min, max T) T {
 	}
 }
 
-func getFilenameContent(filePath string) (string, string, error) {
-	b, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", "", err
-	}
-	return filepath.Base(filePath), base64.StdEncoding.EncodeToString(b), nil
-}
-
-func getContentFromURL(url string) (string, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	return base64.StdEncoding.EncodeToString(bodyBytes), nil
-}
-
 // RawMetaTyper описывает методы, необходимые для преобразования одного типа в другой.
 type RawMetaTyper interface {
 	MetaTyper
@@ -1,6 +1,9 @@
 package moysklad
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Image Изображение.
 //
@@ -90,11 +93,23 @@ func (Image) MetaType() MetaType {
 
 // NewImageFromURL принимает URL путь до изображения и возвращает [Image].
 func NewImageFromURL(url string) (*Image, error) {
-	content, err := getContentFromURL(url)
+	return NewImageFromURLWithContext(context.Background(), url)
+}
+
+// NewImageFromURLWithContext принимает контекст и URL путь до изображения и возвращает [Image].
+//
+// Имя файла определяется по последнему элементу пути URL.
+func NewImageFromURLWithContext(ctx context.Context, url string) (*Image, error) {
+	filename, content, err := getContentFromURL(ctx, url)
 	if err != nil {
 		return nil, err
 	}
-	return &Image{Content: String(content)}, nil
+	image := &Image{Content: String(content)}
+	if filename != "" {
+		image.Title = String(filename)
+		image.Filename = String(filename)
+	}
+	return image, nil
 }
 
 // NewImageFromFilepath принимает путь до изображения и возвращает [Image].
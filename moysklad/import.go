@@ -0,0 +1,1012 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportService описывает методы сервиса, необходимые для импорта сущностей T.
+//
+// Удовлетворяют сервисы товаров, модификаций, контрагентов, услуг и другие сервисы справочников.
+type ImportService[T any] interface {
+	GetListAll(ctx context.Context, params ...func(*Params)) (*Slice[T], *resty.Response, error)
+	CreateUpdateMany(ctx context.Context, entities Slice[T], params ...func(*Params)) (*Slice[T], *resty.Response, error)
+}
+
+// ImportSource описывает источник строк для импорта.
+type ImportSource interface {
+	// Header возвращает заголовки столбцов.
+	Header() []string
+
+	// Next возвращает следующую строку источника или [io.EOF], если строки закончились.
+	Next() ([]string, error)
+}
+
+type importSourceCSV struct {
+	reader *csv.Reader
+	header []string
+}
+
+// NewImportSourceCSV возвращает источник строк из CSV.
+//
+// Первая строка считается заголовком. Если comma равен 0, то в качестве разделителя используется ';'.
+func NewImportSourceCSV(r io.Reader, comma rune) (ImportSource, error) {
+	if comma == 0 {
+		comma = ';'
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// удаляем BOM, который добавляют табличные редакторы
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	return &importSourceCSV{reader, header}, nil
+}
+
+func (source *importSourceCSV) Header() []string {
+	return source.header
+}
+
+func (source *importSourceCSV) Next() ([]string, error) {
+	return source.reader.Read()
+}
+
+type importSourceRows struct {
+	rows   [][]string
+	header []string
+	idx    int
+}
+
+// NewImportSourceXLSX возвращает источник строк из листа sheet книги XLSX.
+//
+// Если sheet пустая строка, то используется первый лист книги. Первая строка листа считается заголовком.
+func NewImportSourceXLSX(r io.ReaderAt, size int64, sheet string) (ImportSource, error) {
+	rows, err := readXLSX(r, size, sheet)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("import: sheet is empty")
+	}
+	return &importSourceRows{rows: rows[1:], header: rows[0]}, nil
+}
+
+func (source *importSourceRows) Header() []string {
+	return source.header
+}
+
+func (source *importSourceRows) Next() ([]string, error) {
+	if source.idx >= len(source.rows) {
+		return nil, io.EOF
+	}
+	row := source.rows[source.idx]
+	source.idx++
+	return row, nil
+}
+
+// ImportMatchKey поле, по которому строка источника сопоставляется с существующей сущностью.
+//
+// Возможные значения:
+//   - ImportMatchByExternalCode – по внешнему коду
+//   - ImportMatchByCode         – по коду
+//   - ImportMatchByArticle      – по артикулу
+//   - ImportMatchBySyncID       – по ID синхронизации
+type ImportMatchKey string
+
+const (
+	ImportMatchByExternalCode ImportMatchKey = "externalCode" // по внешнему коду
+	ImportMatchByCode         ImportMatchKey = "code"         // по коду
+	ImportMatchByArticle      ImportMatchKey = "article"      // по артикулу
+	ImportMatchBySyncID       ImportMatchKey = "syncId"       // по ID синхронизации
+)
+
+type importColumnKind int
+
+const (
+	importColumnField importColumnKind = iota
+	importColumnAttribute
+	importColumnSalePrice
+	importColumnBarcode
+	importColumnProductFolder
+	importColumnCharacteristic
+	importColumnProduct
+)
+
+// ImportColumn описание соответствия столбца источника полю сущности.
+//
+// Создать описание можно с помощью функций [ImportField], [ImportAttribute], [ImportSalePrice],
+// [ImportBarcode], [ImportProductFolder], [ImportCharacteristic] и [ImportProduct].
+type ImportColumn struct {
+	Column      string // Наименование столбца в источнике
+	target      string
+	barcodeType BarcodeType
+	kind        importColumnKind
+}
+
+// ImportField сопоставляет столбец column с полем сущности.
+//
+// Поле указывается JSON-наименованием, для вложенных объектов через точку, например: "name", "buyPrice.value".
+func ImportField(column, field string) ImportColumn {
+	return ImportColumn{Column: column, target: field, kind: importColumnField}
+}
+
+// ImportAttribute сопоставляет столбец column с дополнительным полем с наименованием name.
+func ImportAttribute(column, name string) ImportColumn {
+	return ImportColumn{Column: column, target: name, kind: importColumnAttribute}
+}
+
+// ImportSalePrice сопоставляет столбец column с ценой продажи типа цены с наименованием priceTypeName.
+func ImportSalePrice(column, priceTypeName string) ImportColumn {
+	return ImportColumn{Column: column, target: priceTypeName, kind: importColumnSalePrice}
+}
+
+// ImportBarcode сопоставляет столбец column со штрихкодом типа barcodeType.
+//
+// Ячейка может содержать несколько штрихкодов, разделённых запятой.
+func ImportBarcode(column string, barcodeType BarcodeType) ImportColumn {
+	return ImportColumn{Column: column, barcodeType: barcodeType, kind: importColumnBarcode}
+}
+
+// ImportProductFolder сопоставляет столбец column с группой товаров.
+//
+// Ячейка содержит полный путь группы, например: "Одежда/Женская/Платья".
+// Отсутствующие группы создаются (кроме режима DryRun).
+func ImportProductFolder(column string) ImportColumn {
+	return ImportColumn{Column: column, kind: importColumnProductFolder}
+}
+
+// ImportCharacteristic сопоставляет столбец column со значением характеристики модификации с наименованием name.
+//
+// Отсутствующая характеристика создаётся (кроме режима DryRun).
+func ImportCharacteristic(column, name string) ImportColumn {
+	return ImportColumn{Column: column, target: name, kind: importColumnCharacteristic}
+}
+
+// ImportProduct сопоставляет столбец column с товаром, к которому относится модификация или упаковка.
+//
+// Товар ищется по коду, затем по артикулу, затем по внешнему коду.
+// Если значению соответствует несколько товаров, то строка не импортируется.
+func ImportProduct(column string) ImportColumn {
+	return ImportColumn{Column: column, kind: importColumnProduct}
+}
+
+// ImportConfig конфигурация импорта.
+type ImportConfig struct {
+	// Соответствие столбцов источника полям сущности.
+	Columns []ImportColumn
+
+	// Поле, по которому строки сопоставляются с существующими сущностями.
+	//
+	// Если не указано, все строки создают новые сущности.
+	// Строки с повторяющимся значением ключа не импортируются (ни одна из строк с этим значением).
+	MatchBy ImportMatchKey
+
+	// Разделитель уровней пути группы товаров. По умолчанию "/".
+	FolderSeparator string
+
+	// Цены в источнике указаны в рублях (при отправке умножаются на 100).
+	// Относится к ценам продажи и к полям значений цен, например "buyPrice.value" и "minPrice.value".
+	PricesInRubles bool
+
+	// Пробный запуск: выполняется сопоставление и проверка строк без записи изменений.
+	DryRun bool
+}
+
+// ImportAction действие, выполненное со строкой импорта.
+//
+// Возможные значения:
+//   - ImportActionCreate – создание сущности
+//   - ImportActionUpdate – изменение сущности
+//   - ImportActionError  – строка не импортирована из-за ошибки
+type ImportAction string
+
+const (
+	ImportActionCreate ImportAction = "create" // создание сущности
+	ImportActionUpdate ImportAction = "update" // изменение сущности
+	ImportActionError  ImportAction = "error"  // строка не импортирована из-за ошибки
+)
+
+// ImportRowResult результат импорта строки источника.
+type ImportRowResult struct {
+	Meta      *Meta        // Метаданные созданной или изменённой сущности
+	Err       error        // Ошибка разбора строки или выполнения запроса
+	Key       string       // Значение ключа сопоставления
+	Action    ImportAction // Выполненное действие
+	ApiErrors []ApiError   // Ошибки API МойСклад, относящиеся к строке
+	Warnings  []string     // Предупреждения
+	Row       int          // Номер строки в источнике (заголовок – строка 1)
+}
+
+// ImportReport отчёт об импорте.
+type ImportReport struct {
+	Rows   []*ImportRowResult // Результаты по строкам
+	DryRun bool               // Признак пробного запуска
+}
+
+// Count возвращает количество строк с указанным действием.
+func (report ImportReport) Count(action ImportAction) int {
+	var n int
+	for _, row := range report.Rows {
+		if row.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// HasErrors возвращает true, если хотя бы одна строка не была импортирована.
+func (report ImportReport) HasErrors() bool {
+	return report.Count(ImportActionError) > 0
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (report ImportReport) String() string {
+	return fmt.Sprintf("created: %d, updated: %d, errors: %d",
+		report.Count(ImportActionCreate), report.Count(ImportActionUpdate), report.Count(ImportActionError))
+}
+
+// Importer импорт строк табличных файлов в сущности T.
+type Importer[T MetaOwner] struct {
+	client          *Client
+	service         ImportService[T]
+	config          ImportConfig
+	attributes      map[string]*Attribute
+	priceTypes      map[string]*PriceType
+	folders         map[string]*ProductFolder
+	characteristics map[string]*Characteristic
+	products        map[string]map[string]*Meta
+}
+
+// importProductKeys поля, по которым ищется товар для столбца [ImportProduct], в порядке приоритета.
+var importProductKeys = []string{"code", "article", "externalCode"}
+
+// NewImporter возвращает [Importer] для сущностей T, использующий сервис service.
+func NewImporter[T MetaOwner](client *Client, service ImportService[T], config ImportConfig) *Importer[T] {
+	if config.FolderSeparator == "" {
+		config.FolderSeparator = "/"
+	}
+	return &Importer[T]{client: client, service: service, config: config}
+}
+
+// NewProductImporter возвращает [Importer] для товаров.
+func NewProductImporter(client *Client, config ImportConfig) *Importer[Product] {
+	return NewImporter[Product](client, NewProductService(client), config)
+}
+
+// NewVariantImporter возвращает [Importer] для модификаций.
+func NewVariantImporter(client *Client, config ImportConfig) *Importer[Variant] {
+	return NewImporter[Variant](client, NewVariantService(client), config)
+}
+
+// NewCounterpartyImporter возвращает [Importer] для контрагентов.
+func NewCounterpartyImporter(client *Client, config ImportConfig) *Importer[Counterparty] {
+	return NewImporter[Counterparty](client, NewCounterpartyService(client), config)
+}
+
+// NewServiceImporter возвращает [Importer] для услуг.
+func NewServiceImporter(client *Client, config ImportConfig) *Importer[Service] {
+	return NewImporter[Service](client, NewServiceService(client), config)
+}
+
+type importRow struct {
+	result *ImportRowResult
+	data   map[string]any
+}
+
+// Run выполняет импорт строк источника source.
+//
+// Ошибка возвращается только в случае невозможности выполнить импорт целиком
+// (ошибка чтения источника, справочников или существующих сущностей).
+// Ошибки отдельных строк содержатся в отчёте [ImportReport].
+func (importer *Importer[T]) Run(ctx context.Context, source ImportSource) (*ImportReport, error) {
+	if err := importer.prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range source.Header() {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, column := range importer.config.Columns {
+		if _, ok := columns[column.Column]; !ok {
+			return nil, fmt.Errorf("import: column %q not found in source", column.Column)
+		}
+	}
+
+	existing, err := importer.existing(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: importer.config.DryRun}
+	var (
+		rows []*importRow
+		keys = make(map[string][]int) // номера строк по значению ключа
+	)
+
+	for line := 2; ; line++ {
+		values, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := &ImportRowResult{Row: line}
+		report.Rows = append(report.Rows, result)
+
+		data, err := importer.buildRow(ctx, columns, values, result)
+		if err != nil {
+			result.Action, result.Err = ImportActionError, err
+			continue
+		}
+
+		if key := importer.config.MatchBy; key != "" {
+			result.Key, _ = data[string(key)].(string)
+		}
+
+		if result.Key != "" {
+			keys[result.Key] = append(keys[result.Key], line)
+		}
+
+		if meta, ok := existing[result.Key]; ok && result.Key != "" {
+			data["meta"] = meta
+			result.Action, result.Meta = ImportActionUpdate, meta
+		} else {
+			result.Action = ImportActionCreate
+		}
+
+		rows = append(rows, &importRow{result, data})
+	}
+
+	// строки с повторяющимся ключом не импортируются: неизвестно, какая из них верна
+	unique := rows[:0]
+	for _, row := range rows {
+		lines := keys[row.result.Key]
+		if row.result.Key == "" || len(lines) < 2 {
+			unique = append(unique, row)
+			continue
+		}
+		row.result.Action, row.result.Meta = ImportActionError, nil
+		row.result.Err = fmt.Errorf("duplicate %s %q (rows %v)", importer.config.MatchBy, row.result.Key, lines)
+	}
+	rows = unique
+
+	if importer.config.DryRun {
+		return report, nil
+	}
+
+	for start := 0; start < len(rows); start += MaxPositions {
+		end := min(start+MaxPositions, len(rows))
+		importer.upsert(ctx, rows[start:end])
+	}
+
+	return report, nil
+}
+
+// prepare загружает справочники, необходимые для сопоставления столбцов.
+func (importer *Importer[T]) prepare(ctx context.Context) error {
+	var (
+		needAttributes, needPriceTypes, needFolders, needProducts bool
+		characteristicNames                                       []string
+	)
+	for _, column := range importer.config.Columns {
+		switch column.kind {
+		case importColumnAttribute:
+			needAttributes = true
+		case importColumnSalePrice:
+			needPriceTypes = true
+		case importColumnProductFolder:
+			needFolders = true
+		case importColumnCharacteristic:
+			characteristicNames = append(characteristicNames, column.target)
+		case importColumnProduct:
+			needProducts = true
+		}
+	}
+
+	if needAttributes && importer.attributes == nil {
+		service, ok := importer.service.(interface {
+			GetAttributeList(ctx context.Context) (*List[Attribute], *resty.Response, error)
+		})
+		if !ok {
+			return fmt.Errorf("import: entity %s does not support attributes", MetaTypeFromEntity(*new(T)))
+		}
+		list, _, err := service.GetAttributeList(ctx)
+		if err != nil {
+			return err
+		}
+		importer.attributes = make(map[string]*Attribute)
+		for _, attribute := range list.Rows {
+			importer.attributes[attribute.GetName()] = attribute
+		}
+	}
+
+	if needPriceTypes && importer.priceTypes == nil {
+		priceTypes, _, err := NewContextCompanySettingsService(importer.client).GetPriceTypes(ctx)
+		if err != nil {
+			return err
+		}
+		importer.priceTypes = make(map[string]*PriceType)
+		for _, priceType := range Deref(priceTypes) {
+			importer.priceTypes[priceType.GetName()] = priceType
+		}
+	}
+
+	if needFolders && importer.folders == nil {
+		folders, _, err := NewProductFolderService(importer.client).GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		importer.folders = make(map[string]*ProductFolder)
+		for _, folder := range Deref(folders) {
+			importer.folders[importer.folderPath(folder)] = folder
+		}
+	}
+
+	if len(characteristicNames) > 0 && importer.characteristics == nil {
+		if err := importer.prepareCharacteristics(ctx, characteristicNames); err != nil {
+			return err
+		}
+	}
+
+	if needProducts && importer.products == nil {
+		products, _, err := NewProductService(importer.client).GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		importer.products = make(map[string]map[string]*Meta)
+		for _, key := range importProductKeys {
+			importer.products[key] = make(map[string]*Meta)
+		}
+		for _, product := range Deref(products) {
+			meta := product.GetMeta()
+			for key, value := range map[string]string{
+				"code": product.GetCode(), "article": product.GetArticle(), "externalCode": product.GetExternalCode(),
+			} {
+				if value == "" {
+					continue
+				}
+				if _, ok := importer.products[key][value]; ok {
+					// значению соответствует несколько товаров
+					importer.products[key][value] = nil
+					continue
+				}
+				importer.products[key][value] = &meta
+			}
+		}
+	}
+
+	return nil
+}
+
+// prepareCharacteristics загружает характеристики модификаций и создаёт отсутствующие характеристики names
+// (кроме режима DryRun).
+func (importer *Importer[T]) prepareCharacteristics(ctx context.Context, names []string) error {
+	service := NewVariantService(importer.client)
+	metadata, _, err := service.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	importer.characteristics = make(map[string]*Characteristic)
+	for _, characteristic := range metadata.Characteristics {
+		importer.characteristics[strings.ToLower(characteristic.GetName())] = characteristic
+	}
+
+	var missing []*Characteristic
+	for _, name := range names {
+		if _, ok := importer.characteristics[strings.ToLower(name)]; !ok {
+			missing = append(missing, new(Characteristic).SetName(name))
+		}
+	}
+	if importer.config.DryRun || len(missing) == 0 {
+		return nil
+	}
+
+	created, _, err := service.CreateCharacteristicMany(ctx, missing...)
+	if err != nil {
+		return err
+	}
+	for _, characteristic := range Deref(created) {
+		importer.characteristics[strings.ToLower(characteristic.GetName())] = characteristic
+	}
+	return nil
+}
+
+// productMeta возвращает метаданные товара по коду, артикулу или внешнему коду value.
+func (importer *Importer[T]) productMeta(value string) (*Meta, error) {
+	for _, key := range importProductKeys {
+		meta, ok := importer.products[key][value]
+		if !ok {
+			continue
+		}
+		if meta == nil {
+			return nil, fmt.Errorf("several products with %s %q", key, value)
+		}
+		return meta, nil
+	}
+	return nil, fmt.Errorf("product %q not found", value)
+}
+
+// existing возвращает метаданные существующих сущностей по значению ключа сопоставления.
+func (importer *Importer[T]) existing(ctx context.Context) (map[string]*Meta, error) {
+	var index = make(map[string]*Meta)
+	if importer.config.MatchBy == "" {
+		return index, nil
+	}
+
+	entities, _, err := importer.service.GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entity := range Deref(entities) {
+		field := jsonFieldValue(reflect.ValueOf(entity), string(importer.config.MatchBy))
+		if !field.IsValid() || field.Kind() != reflect.String || field.String() == "" {
+			continue
+		}
+		meta := (*entity).GetMeta()
+		index[field.String()] = &meta
+	}
+
+	return index, nil
+}
+
+// buildRow формирует JSON-представление сущности из строки источника.
+func (importer *Importer[T]) buildRow(ctx context.Context, columns map[string]int, values []string, result *ImportRowResult) (map[string]any, error) {
+	var (
+		data            = make(map[string]any)
+		entityType      = reflect.TypeOf(*new(T))
+		attributes      []any
+		salePrices      []any
+		characteristics []any
+		barcodes        Slice[Barcode]
+	)
+
+	for _, column := range importer.config.Columns {
+		var cell string
+		if idx := columns[column.Column]; idx < len(values) {
+			cell = strings.TrimSpace(values[idx])
+		}
+		if cell == "" {
+			continue
+		}
+
+		switch column.kind {
+		case importColumnField:
+			path := strings.Split(column.target, ".")
+			typ, ok := jsonPathType(entityType, path)
+			if !ok {
+				return nil, fmt.Errorf("column %q: unknown field %q", column.Column, column.target)
+			}
+			value, err := convertCell(typ, cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", column.Column, err)
+			}
+			if price, ok := value.(float64); ok && importer.config.PricesInRubles && isPriceValue(entityType, path) {
+				value = price * 100
+			}
+			setJSONPath(data, path, value)
+
+		case importColumnAttribute:
+			attribute, ok := importer.attributes[column.target]
+			if !ok {
+				return nil, fmt.Errorf("column %q: attribute %q not found", column.Column, column.target)
+			}
+			value, err := convertAttributeValue(attribute.Type, cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", column.Column, err)
+			}
+			attributes = append(attributes, map[string]any{"meta": attribute.Meta, "value": value})
+
+		case importColumnSalePrice:
+			priceType, ok := importer.priceTypes[column.target]
+			if !ok {
+				return nil, fmt.Errorf("column %q: price type %q not found", column.Column, column.target)
+			}
+			value, err := parseNumber(cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", column.Column, err)
+			}
+			if importer.config.PricesInRubles {
+				value *= 100
+			}
+			salePrices = append(salePrices, map[string]any{"value": value, "priceType": priceType.Clean()})
+
+		case importColumnBarcode:
+			for _, code := range strings.Split(cell, ",") {
+				if code = strings.TrimSpace(code); code != "" {
+					barcodes.Push(newBarcode(column.barcodeType, code))
+				}
+			}
+
+		case importColumnProductFolder:
+			folder, err := importer.productFolder(ctx, cell, result)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", column.Column, err)
+			}
+			if folder != nil {
+				data["productFolder"] = folder.Clean()
+			}
+
+		case importColumnCharacteristic:
+			characteristic := map[string]any{"name": column.target, "value": cell}
+			if known, ok := importer.characteristics[strings.ToLower(column.target)]; ok {
+				characteristic["meta"] = known.GetMeta()
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("characteristic %q will be created", column.target))
+			}
+			characteristics = append(characteristics, characteristic)
+
+		case importColumnProduct:
+			meta, err := importer.productMeta(cell)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %w", column.Column, err)
+			}
+			data["product"] = map[string]any{"meta": meta}
+		}
+	}
+
+	if len(attributes) > 0 {
+		data["attributes"] = attributes
+	}
+	if len(salePrices) > 0 {
+		data["salePrices"] = salePrices
+	}
+	if len(barcodes) > 0 {
+		data["barcodes"] = barcodes
+	}
+	if len(characteristics) > 0 {
+		data["characteristics"] = characteristics
+	}
+
+	// проверяем, что строка приводится к типу сущности
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, new(T)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// folderPath возвращает полный путь группы товаров.
+func (importer *Importer[T]) folderPath(folder *ProductFolder) string {
+	if pathName := folder.GetPathName(); pathName != "" {
+		return pathName + importer.config.FolderSeparator + folder.GetName()
+	}
+	return folder.GetName()
+}
+
+// productFolder возвращает группу товаров по пути, создавая отсутствующие уровни.
+func (importer *Importer[T]) productFolder(ctx context.Context, path string, result *ImportRowResult) (*ProductFolder, error) {
+	var (
+		sep    = importer.config.FolderSeparator
+		parent *ProductFolder
+		full   string
+	)
+
+	for _, name := range strings.Split(path, sep) {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if full != "" {
+			full += sep
+		}
+		full += name
+
+		if folder, ok := importer.folders[full]; ok {
+			parent = folder
+			continue
+		}
+
+		if importer.config.DryRun {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("product folder %q will be created", full))
+			return nil, nil
+		}
+
+		folder := &ProductFolder{Name: String(name)}
+		if parent != nil {
+			folder.ProductFolder = NewNullValue(parent)
+		}
+		created, _, err := NewProductFolderService(importer.client).Create(ctx, folder)
+		if err != nil {
+			return nil, err
+		}
+		importer.folders[full] = created
+		parent = created
+	}
+
+	return parent, nil
+}
+
+// upsert отправляет строки одним запросом и заполняет результаты по строкам.
+func (importer *Importer[T]) upsert(ctx context.Context, rows []*importRow) {
+	var entities = make(Slice[T], 0, len(rows))
+	for _, row := range rows {
+		entity := new(T)
+		b, _ := json.Marshal(row.data)
+		_ = json.Unmarshal(b, entity)
+		entities = append(entities, entity)
+	}
+
+	_, resp, err := importer.service.CreateUpdateMany(ctx, entities)
+
+	var raw []json.RawMessage
+	if resp != nil {
+		_ = json.Unmarshal(resp.Body(), &raw)
+	}
+
+	// сервис возвращает массив той же длины, где на месте ошибочных объектов находятся ошибки
+	if len(raw) != len(rows) {
+		if err == nil {
+			err = fmt.Errorf("import: unexpected response length %d, want %d", len(raw), len(rows))
+		}
+		for _, row := range rows {
+			row.result.Action, row.result.Err = ImportActionError, err
+		}
+		return
+	}
+
+	for i, row := range rows {
+		var element struct {
+			Meta   *Meta      `json:"meta"`
+			Errors []ApiError `json:"errors"`
+		}
+		if err := json.Unmarshal(raw[i], &element); err != nil {
+			row.result.Action, row.result.Err = ImportActionError, err
+			continue
+		}
+		if len(element.Errors) > 0 {
+			row.result.Action, row.result.ApiErrors = ImportActionError, element.Errors
+			row.result.Err = ApiErrors{ApiErrors: NewSliceFrom(element.Errors)}
+			continue
+		}
+		row.result.Meta = element.Meta
+	}
+}
+
+// newBarcode возвращает штрихкод типа barcodeType через соответствующий конструктор.
+func newBarcode(barcodeType BarcodeType, value string) *Barcode {
+	switch barcodeType {
+	case BarcodeEAN13:
+		return NewBarcodeEAN13(value)
+	case BarcodeEAN8:
+		return NewBarcodeEAN8(value)
+	case BarcodeCode128:
+		return NewBarcodeCode128(value)
+	case BarcodeGTIN:
+		return NewBarcodeGTIN(value)
+	case BarcodeUPC:
+		return NewBarcodeUPC(value)
+	}
+	return &Barcode{barcodeType, value}
+}
+
+// jsonFieldValue возвращает значение поля структуры v с JSON-наименованием name.
+func jsonFieldValue(v reflect.Value, name string) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if jsonFieldName(v.Type().Field(i)) == name {
+			field := v.Field(i)
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					return reflect.Value{}
+				}
+				field = field.Elem()
+			}
+			return field
+		}
+	}
+
+	return reflect.Value{}
+}
+
+// jsonFieldName возвращает JSON-наименование поля структуры.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// indirectType разыменовывает указатели и обёртки [NullValue].
+func indirectType(typ reflect.Type) reflect.Type {
+	for {
+		switch {
+		case typ.Kind() == reflect.Ptr:
+			typ = typ.Elem()
+		case typ.Kind() == reflect.Struct && strings.HasPrefix(typ.Name(), "NullValue["):
+			field, _ := typ.FieldByName("value")
+			typ = field.Type
+		default:
+			return typ
+		}
+	}
+}
+
+// priceValueTypes типы цен, значения которых передаются в копейках.
+var priceValueTypes = map[reflect.Type]bool{
+	reflect.TypeOf(BuyPrice{}):  true,
+	reflect.TypeOf(MinPrice{}):  true,
+	reflect.TypeOf(SalePrice{}): true,
+}
+
+// isPriceValue возвращает true, если путь path указывает на значение цены.
+func isPriceValue(typ reflect.Type, path []string) bool {
+	if len(path) < 2 || path[len(path)-1] != "value" {
+		return false
+	}
+	parent, ok := jsonPathType(typ, path[:len(path)-1])
+	return ok && priceValueTypes[parent]
+}
+
+// jsonPathType возвращает тип поля по пути из JSON-наименований.
+func jsonPathType(typ reflect.Type, path []string) (reflect.Type, bool) {
+	for _, name := range path {
+		typ = indirectType(typ)
+		if typ.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		var found bool
+		for i := 0; i < typ.NumField(); i++ {
+			if field := typ.Field(i); jsonFieldName(field) == name {
+				typ, found = field.Type, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return indirectType(typ), true
+}
+
+// setJSONPath устанавливает значение value по пути path, создавая вложенные объекты.
+func setJSONPath(data map[string]any, path []string, value any) {
+	for _, name := range path[:len(path)-1] {
+		next, ok := data[name].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			data[name] = next
+		}
+		data = next
+	}
+	data[path[len(path)-1]] = value
+}
+
+// convertCell приводит значение ячейки к JSON-значению поля типа typ.
+func convertCell(typ reflect.Type, cell string) (any, error) {
+	if typ == reflect.TypeOf(Timestamp{}) {
+		t, err := parseTime(cell)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(TimestampFormat), nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return cell, nil
+	case reflect.Bool:
+		return parseBool(cell)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, err := parseNumber(cell)
+		return int64(f), err
+	case reflect.Float32, reflect.Float64:
+		return parseNumber(cell)
+	case reflect.Slice:
+		if indirectType(typ.Elem()).Kind() == reflect.String {
+			var values []string
+			for _, value := range strings.Split(cell, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					values = append(values, value)
+				}
+			}
+			return values, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported field type %s", typ)
+}
+
+// convertAttributeValue приводит значение ячейки к значению доп. поля типа attributeType.
+//
+// Для доп. полей типа справочник значение ячейки должно содержать ссылку (href) на элемент справочника.
+func convertAttributeValue(attributeType AttributeType, cell string) (any, error) {
+	switch attributeType {
+	case AttributeTypeString, AttributeTypeText, AttributeTypeLink:
+		return cell, nil
+	case AttributeTypeDouble:
+		return parseNumber(cell)
+	case AttributeTypeLong:
+		f, err := parseNumber(cell)
+		return int64(f), err
+	case AttributeTypeBoolean:
+		return parseBool(cell)
+	case AttributeTypeTime:
+		t, err := parseTime(cell)
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(TimestampFormat), nil
+	case AttributeTypeFile:
+		return nil, fmt.Errorf("attribute type %s is not supported", attributeType)
+	default:
+		if !strings.HasPrefix(cell, "http") {
+			return nil, fmt.Errorf("attribute type %s requires href of the element", attributeType)
+		}
+		metaType := MetaType(attributeType)
+		return MetaWrapper{Meta: *new(Meta).SetHref(cell).SetType(metaType).SetMediaType(ApplicationJson)}, nil
+	}
+}
+
+// parseNumber разбирает число, допуская запятую в качестве десятичного разделителя и пробелы между разрядами.
+func parseNumber(s string) (float64, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(strings.TrimSpace(s))
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseBool разбирает логическое значение.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes", "y", "да", "+":
+		return true, nil
+	case "", "0", "false", "no", "n", "нет", "-":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", s)
+}
+
+var parseTimeLayouts = []string{
+	TimestampFormat,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+	time.RFC3339,
+}
+
+// parseTime разбирает дату в одном из распространённых форматов.
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range parseTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
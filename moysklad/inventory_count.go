@@ -0,0 +1,459 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// InventoryScan отсканированный штрихкод.
+type InventoryScan struct {
+	Barcode  string  // Штрихкод
+	Quantity float64 // Количество (если не указано, то 1)
+}
+
+// ReadInventoryScansCSV читает отсканированные штрихкоды из CSV.
+//
+// Первый столбец содержит штрихкод, второй (необязательный) – количество.
+// Если количество в первой строке не является числом, то она считается заголовком и пропускается.
+// Разделитель comma по умолчанию ';'.
+func ReadInventoryScansCSV(r io.Reader, comma rune) ([]InventoryScan, error) {
+	if comma == 0 {
+		comma = ';'
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var scans []InventoryScan
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return scans, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) == 0 {
+			continue
+		}
+
+		barcode := strings.TrimSpace(strings.TrimPrefix(record[0], "\ufeff"))
+		if barcode == "" {
+			continue
+		}
+
+		scan := InventoryScan{Barcode: barcode, Quantity: 1}
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			quantity, err := parseNumber(record[1])
+			if err != nil {
+				// заголовок
+				if line == 1 {
+					continue
+				}
+				return nil, fmt.Errorf("inventory: line %d: invalid quantity %q", line, record[1])
+			}
+			scan.Quantity = quantity
+		}
+
+		scans = append(scans, scan)
+	}
+}
+
+// InventoryCountConfig конфигурация инвентаризации по отсканированным штрихкодам.
+type InventoryCountConfig struct {
+	// Склад (обязательное поле).
+	Store *Store
+
+	// Юрлицо (обязательное поле).
+	Organization *Organization
+
+	// Шаблон инвентаризации для заполнения дополнительных полей (наименование, комментарий, доп. поля и т.д.).
+	//
+	// Склад, юрлицо и позиции устанавливаются из конфигурации и результатов сканирования.
+	Inventory *Inventory
+
+	// Проводить ли созданные Оприходование и Списание.
+	Applicable bool
+}
+
+// InventoryCountPosition позиция инвентаризации, собранная из отсканированных штрихкодов.
+type InventoryCountPosition struct {
+	Assortment         *AssortmentPosition // Товар/Модификация/Серия
+	Barcodes           []string            // Отсканированные штрихкоды позиции
+	Quantity           float64             // Фактический остаток
+	CalculatedQuantity float64             // Расчётный остаток (заполняется после пересчёта)
+	CorrectionAmount   float64             // Разница между фактическим и расчётным остатком (заполняется после пересчёта)
+}
+
+// InventoryCount результат инвентаризации по отсканированным штрихкодам.
+type InventoryCount struct {
+	Inventory *Inventory               // Созданная Инвентаризация
+	Positions []InventoryCountPosition // Позиции инвентаризации
+	Unknown   []InventoryScan          // Штрихкоды, которые не удалось найти в ассортименте
+	Enter     *Enter                   // Оприходование излишков (заполняется после утверждения)
+	Loss      *Loss                    // Списание недостачи (заполняется после утверждения)
+}
+
+// HasUnknown возвращает true, если есть штрихкоды, которые не удалось найти в ассортименте.
+func (count InventoryCount) HasUnknown() bool {
+	return len(count.Unknown) > 0
+}
+
+// WriteUnknownCSV записывает отчёт по неизвестным штрихкодам в формате CSV (штрихкод;количество).
+func (count InventoryCount) WriteUnknownCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	if err := cw.Write([]string{"barcode", "quantity"}); err != nil {
+		return err
+	}
+	for _, scan := range count.Unknown {
+		if err := cw.Write([]string{scan.Barcode, fmt.Sprint(scan.Quantity)}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (count InventoryCount) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "позиций: %d, неизвестных штрихкодов: %d", len(count.Positions), len(count.Unknown))
+	for _, position := range count.Positions {
+		name := position.Assortment.Name
+		if name == "" {
+			name = position.Assortment.Meta.GetUUIDFromHref()
+		}
+		fmt.Fprintf(&sb, "\n%s: факт %g, расчёт %g, разница %+g", name, position.Quantity, position.CalculatedQuantity, position.CorrectionAmount)
+	}
+	for _, scan := range count.Unknown {
+		fmt.Fprintf(&sb, "\nнеизвестный штрихкод %s: %g", scan.Barcode, scan.Quantity)
+	}
+	return sb.String()
+}
+
+// InventoryCounter инвентаризация по отсканированным штрихкодам.
+//
+// Порядок работы:
+//  1. [InventoryCounter.Count] находит штрихкоды в ассортименте (включая штрихкоды упаковок),
+//     суммирует количество, создаёт Инвентаризацию с позициями и пересчитывает расчётные остатки.
+//  2. [InventoryCounter.Approve] после проверки результата создаёт Оприходование излишков
+//     и Списание недостачи на основании Инвентаризации.
+type InventoryCounter struct {
+	client *Client
+	config InventoryCountConfig
+}
+
+// NewInventoryCounter возвращает [InventoryCounter].
+func NewInventoryCounter(client *Client, config InventoryCountConfig) *InventoryCounter {
+	return &InventoryCounter{client: client, config: config}
+}
+
+// inventoryBarcodesPerRequest количество штрихкодов в одном запросе поиска по ассортименту.
+const inventoryBarcodesPerRequest = 50
+
+// Resolve находит отсканированные штрихкоды в ассортименте и суммирует количество по позициям.
+//
+// Если штрихкод принадлежит упаковке, то количество умножается на количество товаров в упаковке.
+// Возвращает позиции и штрихкоды, которые не удалось найти.
+func (counter *InventoryCounter) Resolve(ctx context.Context, scans []InventoryScan) ([]InventoryCountPosition, []InventoryScan, error) {
+	// суммируем количество по штрихкодам, сохраняя порядок сканирования
+	var barcodes []string
+	quantities := make(map[string]float64)
+	for _, scan := range scans {
+		barcode := strings.TrimSpace(scan.Barcode)
+		if barcode == "" {
+			continue
+		}
+		quantity := scan.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if _, ok := quantities[barcode]; !ok {
+			barcodes = append(barcodes, barcode)
+		}
+		quantities[barcode] += quantity
+	}
+
+	type match struct {
+		assortment *AssortmentPosition
+		multiplier float64
+	}
+
+	matches := make(map[string]match)
+	packs := make(map[string]map[string]float64)
+	service := NewAssortmentService(counter.client)
+	chunks := NewSliceFrom(barcodes)
+	for _, chunk := range chunks.IntoChunks(inventoryBarcodesPerRequest) {
+		params := []func(*Params){WithFilterEquals("archived", "false")}
+		for _, barcode := range chunk {
+			params = append(params, WithFilterEquals("barcode", *barcode))
+		}
+
+		rows, _, err := service.GetListAll(ctx, params...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, assortment := range rows {
+			var productPacks map[string]float64
+			if variant := assortment.AsVariant(); assortment.IsVariant() && variant != nil {
+				if productPacks, err = counter.productPacks(ctx, variant, packs); err != nil {
+					return nil, nil, err
+				}
+			}
+			for barcode, multiplier := range assortmentBarcodes(assortment, productPacks) {
+				if _, ok := quantities[barcode]; ok {
+					if _, found := matches[barcode]; !found {
+						matches[barcode] = match{assortment, multiplier}
+					}
+				}
+			}
+		}
+	}
+
+	var (
+		positions []InventoryCountPosition
+		unknown   []InventoryScan
+		index     = make(map[string]int)
+	)
+	for _, barcode := range barcodes {
+		m, ok := matches[barcode]
+		if !ok {
+			unknown = append(unknown, InventoryScan{Barcode: barcode, Quantity: quantities[barcode]})
+			continue
+		}
+
+		href := m.assortment.Meta.GetHref()
+		i, ok := index[href]
+		if !ok {
+			i = len(positions)
+			index[href] = i
+			positions = append(positions, InventoryCountPosition{Assortment: m.assortment})
+		}
+		positions[i].Barcodes = append(positions[i].Barcodes, barcode)
+		positions[i].Quantity += quantities[barcode] * m.multiplier
+	}
+
+	return positions, unknown, nil
+}
+
+// productPacks возвращает количество товаров в упаковках товара-родителя модификации по ID упаковки.
+//
+// Упаковки загружаются только если хотя бы одна родительская упаковка модификации
+// передана ссылкой без количества; загруженные упаковки кэшируются в cache по ID товара.
+func (counter *InventoryCounter) productPacks(ctx context.Context, variant *Variant, cache map[string]map[string]float64) (map[string]float64, error) {
+	var incomplete bool
+	for _, pack := range variant.Packs {
+		if pack.GetParentPack().GetQuantity() == 0 {
+			incomplete = true
+			break
+		}
+	}
+	if !incomplete {
+		return nil, nil
+	}
+
+	productID := variant.GetProduct().GetMeta().GetUUIDFromHref()
+	if packs, ok := cache[productID]; ok {
+		return packs, nil
+	}
+
+	product, _, err := NewProductService(counter.client).GetByID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make(map[string]float64)
+	for _, pack := range product.Packs {
+		packs[pack.GetID()] = pack.GetQuantity()
+	}
+	cache[productID] = packs
+	return packs, nil
+}
+
+// assortmentBarcodes возвращает штрихкоды позиции ассортимента и её упаковок
+// с количеством единиц товара, соответствующим штрихкоду.
+//
+// Если родительская упаковка модификации передана ссылкой без количества,
+// количество берётся из productPacks по ID упаковки товара-родителя.
+func assortmentBarcodes(assortment *AssortmentPosition, productPacks map[string]float64) map[string]float64 {
+	barcodes := make(map[string]float64)
+	for _, barcode := range assortment.Barcodes {
+		barcodes[barcode.Value] = 1
+	}
+
+	switch {
+	case assortment.IsProduct():
+		if product := assortment.AsProduct(); product != nil {
+			for _, pack := range product.Packs {
+				for _, barcode := range pack.Barcodes {
+					barcodes[barcode.Value] = pack.GetQuantity()
+				}
+			}
+		}
+	case assortment.IsVariant():
+		if variant := assortment.AsVariant(); variant != nil {
+			for _, pack := range variant.Packs {
+				parent := pack.GetParentPack()
+				quantity := parent.GetQuantity()
+				if quantity == 0 {
+					packID := parent.GetID()
+					if packID == "" {
+						packID = parent.GetMeta().GetUUIDFromHref()
+					}
+					quantity = productPacks[packID]
+				}
+				if quantity == 0 {
+					continue
+				}
+				for _, barcode := range pack.Barcodes {
+					barcodes[barcode.Value] = quantity
+				}
+			}
+		}
+	}
+
+	return barcodes
+}
+
+// Count создаёт Инвентаризацию по отсканированным штрихкодам и пересчитывает расчётные остатки.
+//
+// Инвентаризация создаётся, даже если часть штрихкодов не найдена: они возвращаются в поле Unknown.
+// Если не найден ни один штрихкод, то Инвентаризация не создаётся.
+func (counter *InventoryCounter) Count(ctx context.Context, scans []InventoryScan) (*InventoryCount, error) {
+	if counter.config.Store == nil || counter.config.Organization == nil {
+		return nil, fmt.Errorf("inventory: store and organization are required")
+	}
+
+	positions, unknown, err := counter.Resolve(ctx, scans)
+	if err != nil {
+		return nil, err
+	}
+
+	count := &InventoryCount{Positions: positions, Unknown: unknown}
+	if len(positions) == 0 {
+		return count, nil
+	}
+
+	inventory := new(Inventory)
+	if counter.config.Inventory != nil {
+		*inventory = *counter.config.Inventory
+	}
+	inventory.SetStore(counter.config.Store.Clean()).SetOrganization(counter.config.Organization.Clean())
+	inventory.Positions = nil
+
+	service := NewInventoryService(counter.client)
+	if inventory, _, err = service.Create(ctx, inventory); err != nil {
+		return nil, err
+	}
+	count.Inventory = inventory
+
+	var inventoryPositions Slice[InventoryPosition]
+	for _, position := range positions {
+		inventoryPositions.Push(new(InventoryPosition).SetAssortment(position.Assortment).SetQuantity(position.Quantity))
+	}
+
+	if _, _, err = service.CreatePositionMany(ctx, inventory.GetID(), inventoryPositions...); err != nil {
+		return count, err
+	}
+
+	if err = counter.Recalculate(ctx, count); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Recalculate пересчитывает расчётные остатки Инвентаризации и обновляет позиции результата.
+func (counter *InventoryCounter) Recalculate(ctx context.Context, count *InventoryCount) error {
+	if count.Inventory == nil {
+		return fmt.Errorf("inventory: inventory is not created")
+	}
+
+	id := count.Inventory.GetID()
+	service := NewInventoryService(counter.client)
+	if _, _, err := service.Recalculate(ctx, id); err != nil {
+		return err
+	}
+
+	rows, _, err := service.GetPositionListAll(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]int)
+	for i, position := range count.Positions {
+		index[position.Assortment.Meta.GetUUIDFromHref()] = i
+	}
+
+	for _, row := range Deref(rows) {
+		i, ok := index[row.GetAssortment().Meta.GetUUIDFromHref()]
+		if !ok {
+			continue
+		}
+		count.Positions[i].CalculatedQuantity = row.GetCalculatedQuantity()
+		count.Positions[i].CorrectionAmount = row.GetCorrectionAmount()
+	}
+
+	sort.SliceStable(count.Positions, func(i, j int) bool {
+		return count.Positions[i].Assortment.Name < count.Positions[j].Assortment.Name
+	})
+
+	return nil
+}
+
+// Approve создаёт Оприходование излишков и Списание недостачи на основании Инвентаризации.
+//
+// Документ создаётся только при наличии соответствующих расхождений.
+func (counter *InventoryCounter) Approve(ctx context.Context, count *InventoryCount) error {
+	if count.Inventory == nil {
+		return fmt.Errorf("inventory: inventory is not created")
+	}
+
+	var surplus, shortage bool
+	for _, position := range count.Positions {
+		surplus = surplus || position.CorrectionAmount > 0
+		shortage = shortage || position.CorrectionAmount < 0
+	}
+
+	if surplus {
+		enterService := NewEnterService(counter.client)
+		enter, _, err := enterService.TemplateBased(ctx, count.Inventory)
+		if err != nil {
+			return err
+		}
+		if enter.GetPositions().Len() > 0 {
+			enter.SetApplicable(counter.config.Applicable)
+			if count.Enter, _, err = enterService.Create(ctx, enter); err != nil {
+				return err
+			}
+		}
+	}
+
+	if shortage {
+		lossService := NewLossService(counter.client)
+		loss, _, err := lossService.TemplateBased(ctx, count.Inventory)
+		if err != nil {
+			return err
+		}
+		if loss.GetPositions().Len() > 0 {
+			loss.SetApplicable(counter.config.Applicable)
+			if count.Loss, _, err = lossService.Create(ctx, loss); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
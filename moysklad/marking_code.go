@@ -0,0 +1,560 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// GS разделитель групп (FNC1) в коде GS1 DataMatrix.
+const GS = "\x1d"
+
+// Идентификаторы применения (AI) GS1, используемые в кодах маркировки.
+const (
+	AISSCC       = "00"   // Серийный код транспортной упаковки (SSCC)
+	AIGTIN       = "01"   // GTIN
+	AISerial     = "21"   // Серийный номер
+	AIKeyID      = "91"   // Идентификатор ключа проверки
+	AICrypto     = "92"   // Код проверки (криптоподпись)
+	AICryptoTail = "93"   // Код проверки (криптохвост)
+	AIPrice      = "8005" // Максимальная розничная цена
+)
+
+// gs1FixedLengths длины значений AI фиксированной длины (без учёта самого AI).
+var gs1FixedLengths = map[string]int{
+	"00": 18, "01": 14, "02": 14, "11": 6, "13": 6, "15": 6, "17": 6,
+	"3100": 6, "3101": 6, "3102": 6, "3103": 6, "3104": 6, "3105": 6,
+}
+
+// gs1VariableAIs известные AI переменной длины, значение которых завершается символом [GS].
+var gs1VariableAIs = []string{"8005", "240", "10", "21", "91", "92", "93"}
+
+// MarkingCode Разобранный код маркировки «Честный ЗНАК» (GS1 DataMatrix).
+type MarkingCode struct {
+	GTIN       string            // GTIN (14 цифр)
+	Serial     string            // Серийный номер
+	SSCC       string            // SSCC транспортной упаковки (18 цифр)
+	KeyID      string            // Идентификатор ключа проверки (AI 91)
+	Crypto     string            // Код проверки (AI 92) или криптохвост (AI 93)
+	CryptoTail bool              // Признак того, что Crypto является криптохвостом (AI 93)
+	Price      string            // Максимальная розничная цена в копейках (AI 8005, табачная продукция)
+	Extra      map[string]string // Прочие AI
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (markingCode MarkingCode) String() string {
+	return Stringify(markingCode)
+}
+
+// markingCodeSymbology префиксы идентификаторов символики, добавляемые сканерами.
+var markingCodeSymbology = []string{"]d2", "]C1", "]Q3", "]e0"}
+
+// markingCodeTail шаблоны окончания кода после серийного номера, используемые при отсутствии разделителей [GS].
+var markingCodeTail = regexp.MustCompile(`^(|93.{4}|91.{4}92.{44}|91.{4}92.{88}|8005\d{6}93.{4})$`)
+
+// markingCodeSerialLengths длины серийных номеров кодов маркировки по товарным группам.
+var markingCodeSerialLengths = []int{13, 7, 6, 8, 11, 12, 20}
+
+// ParseMarkingCode разбирает код маркировки.
+//
+// Поддерживаются коды с разделителями [GS] (в том числе с префиксом символики сканера),
+// запись с AI в скобках ("(01)…(21)…"), коды без разделителей [GS] с длинами серийного номера,
+// принятыми в «Честном ЗНАКе», а также коды пачек табачной продукции из 25 и 29 символов.
+func ParseMarkingCode(raw string) (*MarkingCode, error) {
+	code := strings.TrimRight(strings.TrimSpace(raw), "\r\n")
+	for _, symbology := range markingCodeSymbology {
+		code = strings.TrimPrefix(code, symbology)
+	}
+	code = strings.TrimPrefix(code, GS)
+	if code == "" {
+		return nil, fmt.Errorf("marking code: empty code")
+	}
+
+	if strings.HasPrefix(code, "(") {
+		return parseMarkingCodeBrackets(code)
+	}
+
+	// пачка табачной продукции: GTIN + серийный номер (7) + МРЦ (4) [+ код проверки (4)]
+	if (len(code) == 25 || len(code) == 29) && isDigits(code[:14]) && !strings.HasPrefix(code, AIGTIN) {
+		markingCode := &MarkingCode{GTIN: code[:14], Serial: code[14:21], Price: code[21:25]}
+		if len(code) == 29 {
+			markingCode.Crypto, markingCode.CryptoTail = code[25:], true
+		}
+		return markingCode, nil
+	}
+
+	if !strings.Contains(code, GS) && len(code) > 18 && strings.HasPrefix(code, AIGTIN) && code[16:18] == AISerial {
+		code = splitMarkingCode(code)
+	}
+
+	values := make(map[string]string)
+	for code != "" {
+		ai, value, rest, err := readGS1Element(code)
+		if err != nil {
+			return nil, err
+		}
+		values[ai] = value
+		code = rest
+	}
+	return newMarkingCode(values)
+}
+
+// splitMarkingCode расставляет разделители [GS] в коде без разделителей,
+// подбирая длину серийного номера так, чтобы окончание кода соответствовало одному из известных шаблонов.
+func splitMarkingCode(code string) string {
+	head, rest := code[:18], code[18:]
+	for _, length := range markingCodeSerialLengths {
+		if len(rest) < length || !markingCodeTail.MatchString(rest[length:]) {
+			continue
+		}
+		serial, tail := rest[:length], rest[length:]
+		switch {
+		case strings.HasPrefix(tail, AIKeyID):
+			tail = GS + tail[:6] + GS + tail[6:]
+		case strings.HasPrefix(tail, AIPrice):
+			tail = GS + tail[:10] + GS + tail[10:]
+		case tail != "":
+			tail = GS + tail
+		}
+		return head + serial + tail
+	}
+	return code
+}
+
+// readGS1Element читает очередной элемент GS1 и возвращает AI, значение и остаток строки.
+func readGS1Element(code string) (string, string, string, error) {
+	for ai, length := range gs1FixedLengths {
+		if strings.HasPrefix(code, ai) {
+			if len(code) < len(ai)+length {
+				return "", "", "", fmt.Errorf("marking code: AI %s value is too short", ai)
+			}
+			value := code[len(ai) : len(ai)+length]
+			return ai, value, strings.TrimPrefix(code[len(ai)+length:], GS), nil
+		}
+	}
+	for _, ai := range gs1VariableAIs {
+		if strings.HasPrefix(code, ai) {
+			value, rest, _ := strings.Cut(code[len(ai):], GS)
+			return ai, value, rest, nil
+		}
+	}
+	return "", "", "", fmt.Errorf("marking code: unknown AI at %q", code)
+}
+
+// markingCodeBrackets шаблон элемента GS1 в записи с AI в скобках.
+var markingCodeBrackets = regexp.MustCompile(`\((\d{2,4})\)([^(]*)`)
+
+// parseMarkingCodeBrackets разбирает код в записи с AI в скобках.
+func parseMarkingCodeBrackets(code string) (*MarkingCode, error) {
+	values := make(map[string]string)
+	for _, match := range markingCodeBrackets.FindAllStringSubmatch(code, -1) {
+		values[match[1]] = strings.TrimSuffix(match[2], GS)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("marking code: invalid code %q", code)
+	}
+	return newMarkingCode(values)
+}
+
+// newMarkingCode формирует [MarkingCode] из значений AI.
+func newMarkingCode(values map[string]string) (*MarkingCode, error) {
+	markingCode := &MarkingCode{
+		GTIN:   values[AIGTIN],
+		Serial: values[AISerial],
+		SSCC:   values[AISSCC],
+		KeyID:  values[AIKeyID],
+		Price:  values[AIPrice],
+	}
+	if crypto, ok := values[AICryptoTail]; ok {
+		markingCode.Crypto, markingCode.CryptoTail = crypto, true
+	} else {
+		markingCode.Crypto = values[AICrypto]
+	}
+	for ai, value := range values {
+		switch ai {
+		case AIGTIN, AISerial, AISSCC, AIKeyID, AICrypto, AICryptoTail, AIPrice:
+		default:
+			if markingCode.Extra == nil {
+				markingCode.Extra = make(map[string]string)
+			}
+			markingCode.Extra[ai] = value
+		}
+	}
+	if markingCode.GTIN == "" && markingCode.SSCC == "" {
+		return nil, fmt.Errorf("marking code: neither GTIN nor SSCC found")
+	}
+	return markingCode, nil
+}
+
+// ParseMarkingCode1162 разбирает код маркировки в формате тега 1162.
+//
+// Код в формате тега 1162 – шестнадцатеричная запись: код типа маркировки (2 байта),
+// GTIN (6 байт) и серийный номер (ASCII).
+func ParseMarkingCode1162(cis1162 string) (*MarkingCode, error) {
+	data, err := hex.DecodeString(strings.TrimSpace(cis1162))
+	if err != nil {
+		return nil, fmt.Errorf("marking code: invalid tag 1162 value: %w", err)
+	}
+	if len(data) <= 8 {
+		return nil, fmt.Errorf("marking code: tag 1162 value is too short")
+	}
+
+	gtin := new(big.Int).SetBytes(data[2:8])
+	return &MarkingCode{
+		GTIN:   fmt.Sprintf("%014d", gtin.Int64()),
+		Serial: string(data[8:]),
+	}, nil
+}
+
+// markingCodeType1162 код типа маркировки для тега 1162 (DataMatrix).
+var markingCodeType1162 = []byte{0x44, 0x4d}
+
+// Cis возвращает код маркировки в стандартном формате.
+//
+// Для кода товара или упаковки это идентификационная часть кода (GTIN и серийный номер) без кода проверки,
+// для транспортной упаковки – SSCC.
+func (markingCode MarkingCode) Cis() string {
+	if markingCode.GTIN == "" {
+		return AISSCC + markingCode.SSCC
+	}
+	if len(markingCode.Price) == 4 {
+		// код пачки табачной продукции передаётся в исходном виде
+		return markingCode.GTIN + markingCode.Serial + markingCode.Price
+	}
+	return AIGTIN + markingCode.GTIN + AISerial + markingCode.Serial
+}
+
+// Cis1162 возвращает код маркировки в формате тега 1162 (для транспортной упаковки – пустая строка).
+func (markingCode MarkingCode) Cis1162() string {
+	gtin, ok := new(big.Int).SetString(markingCode.GTIN, 10)
+	if !ok {
+		return ""
+	}
+	data := make([]byte, 8, 8+len(markingCode.Serial))
+	copy(data, markingCodeType1162)
+	gtin.FillBytes(data[2:8])
+	data = append(data, markingCode.Serial...)
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// GS1 возвращает полный код маркировки с разделителями [GS], пригодный для печати в DataMatrix.
+func (markingCode MarkingCode) GS1() string {
+	if markingCode.GTIN == "" {
+		return AISSCC + markingCode.SSCC
+	}
+
+	var sb strings.Builder
+	sb.WriteString(AIGTIN + markingCode.GTIN + AISerial + markingCode.Serial)
+	if len(markingCode.Price) == 4 {
+		return markingCode.Cis() + markingCode.Crypto
+	}
+	if markingCode.Price != "" {
+		sb.WriteString(GS + AIPrice + markingCode.Price)
+	}
+	if markingCode.KeyID != "" {
+		sb.WriteString(GS + AIKeyID + markingCode.KeyID)
+	}
+	if markingCode.Crypto != "" {
+		if markingCode.CryptoTail {
+			sb.WriteString(GS + AICryptoTail + markingCode.Crypto)
+		} else {
+			sb.WriteString(GS + AICrypto + markingCode.Crypto)
+		}
+	}
+	return sb.String()
+}
+
+// Validate проверяет структуру кода маркировки для указанного типа кода.
+//
+// Для кодов товаров и потребительских упаковок обязательны GTIN с верной контрольной цифрой и серийный номер,
+// для транспортной упаковки допускается также SSCC с верной контрольной цифрой.
+func (markingCode MarkingCode) Validate(trackingCodeType TrackingCodeType) error {
+	if markingCode.SSCC != "" {
+		if trackingCodeType != TrackingCodeTypeTransportPack {
+			return fmt.Errorf("marking code: SSCC %s is allowed only for %s", markingCode.SSCC, TrackingCodeTypeTransportPack)
+		}
+		if len(markingCode.SSCC) != 18 || !isDigits(markingCode.SSCC) {
+			return fmt.Errorf("marking code: SSCC %q must contain 18 digits", markingCode.SSCC)
+		}
+		check, _ := BarcodeCheckDigit(markingCode.SSCC[:17])
+		if int(markingCode.SSCC[17]-'0') != check {
+			return fmt.Errorf("marking code: SSCC %s has invalid check digit", markingCode.SSCC)
+		}
+		return nil
+	}
+
+	switch trackingCodeType {
+	case TrackingCodeTypeTrackingCode, TrackingCodeTypeConsumerPack, TrackingCodeTypeTransportPack:
+	default:
+		return fmt.Errorf("marking code: unknown tracking code type %q", trackingCodeType)
+	}
+
+	if err := NewBarcodeGTIN(markingCode.GTIN).Validate(); err != nil || len(markingCode.GTIN) != 14 {
+		return fmt.Errorf("marking code: invalid GTIN %q", markingCode.GTIN)
+	}
+	if markingCode.Serial == "" {
+		return fmt.Errorf("marking code: empty serial number")
+	}
+	for i := 0; i < len(markingCode.Serial); i++ {
+		if markingCode.Serial[i] < '!' || markingCode.Serial[i] > '~' {
+			return fmt.Errorf("marking code: serial number %q contains invalid character", markingCode.Serial)
+		}
+	}
+	if markingCode.KeyID != "" && len(markingCode.KeyID) != 4 {
+		return fmt.Errorf("marking code: key id %q must contain 4 characters", markingCode.KeyID)
+	}
+	if markingCode.CryptoTail && len(markingCode.Crypto) != 4 {
+		return fmt.Errorf("marking code: crypto tail %q must contain 4 characters", markingCode.Crypto)
+	}
+	if !markingCode.CryptoTail && markingCode.Crypto != "" && len(markingCode.Crypto) != 44 && len(markingCode.Crypto) != 88 {
+		return fmt.Errorf("marking code: crypto %q must contain 44 or 88 characters", markingCode.Crypto)
+	}
+	return nil
+}
+
+// TrackingCode возвращает [TrackingCode] указанного типа с кодом в стандартном формате.
+func (markingCode MarkingCode) TrackingCode(trackingCodeType TrackingCodeType) *TrackingCode {
+	return new(TrackingCode).SetCis(markingCode.Cis()).SetType(trackingCodeType)
+}
+
+// MarkingCode разбирает код маркировки из поля cis, а при его отсутствии – из cis_1162.
+func (trackingCode TrackingCode) MarkingCode() (*MarkingCode, error) {
+	if cis := trackingCode.GetCis(); cis != "" {
+		return ParseMarkingCode(cis)
+	}
+	if cis1162 := trackingCode.GetCis1162(); cis1162 != "" {
+		return ParseMarkingCode1162(cis1162)
+	}
+	return nil, fmt.Errorf("marking code: tracking code has neither cis nor cis_1162")
+}
+
+// Validate проверяет структуру кода маркировки и вложенных кодов согласно их типам.
+func (trackingCode TrackingCode) Validate() error {
+	markingCode, err := trackingCode.MarkingCode()
+	if err != nil {
+		return err
+	}
+	if err = markingCode.Validate(trackingCode.Type); err != nil {
+		return err
+	}
+	if trackingCode.Type == TrackingCodeTypeTrackingCode && trackingCode.TrackingCodes.Len() > 0 {
+		return fmt.Errorf("marking code: %s cannot contain nested codes", trackingCode.GetCis())
+	}
+	for _, nested := range trackingCode.TrackingCodes {
+		if err = nested.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewTrackingCodeFromScan разбирает отсканированный код маркировки и возвращает [TrackingCode] указанного типа.
+func NewTrackingCodeFromScan(raw string, trackingCodeType TrackingCodeType) (*TrackingCode, error) {
+	markingCode, err := ParseMarkingCode(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err = markingCode.Validate(trackingCodeType); err != nil {
+		return nil, err
+	}
+	return markingCode.TrackingCode(trackingCodeType), nil
+}
+
+// TrackingCodeAggregation Иерархия агрегации кодов маркировки (короба → упаковки → единицы товара).
+//
+// Коды добавляются парами «родитель – вложенные коды» в любом порядке, например,
+// по мере сканирования коробов и их содержимого. Тип кодов определяется при построении:
+// коды без вложенных кодов – [TrackingCodeTypeTrackingCode], упаковки, содержащие только единицы товара,
+// – [TrackingCodeTypeConsumerPack], упаковки с вложенными упаковками и SSCC – [TrackingCodeTypeTransportPack].
+type TrackingCodeAggregation struct {
+	codes    map[string]*MarkingCode
+	children map[string][]string
+	parents  map[string]string
+	order    []string
+}
+
+// NewTrackingCodeAggregation возвращает пустую [TrackingCodeAggregation].
+func NewTrackingCodeAggregation() *TrackingCodeAggregation {
+	return &TrackingCodeAggregation{
+		codes:    make(map[string]*MarkingCode),
+		children: make(map[string][]string),
+		parents:  make(map[string]string),
+	}
+}
+
+// add разбирает и запоминает код, возвращая его в стандартном формате.
+func (aggregation *TrackingCodeAggregation) add(raw string) (string, error) {
+	markingCode, err := ParseMarkingCode(raw)
+	if err != nil {
+		return "", err
+	}
+	cis := markingCode.Cis()
+	if _, ok := aggregation.codes[cis]; !ok {
+		aggregation.codes[cis] = markingCode
+		aggregation.order = append(aggregation.order, cis)
+	}
+	return cis, nil
+}
+
+// Add добавляет в иерархию код parent с вложенными кодами children.
+//
+// Если parent пустой, то коды children добавляются на верхний уровень.
+// Возвращает ошибку, если вложенный код уже входит в другую упаковку или образует цикл.
+func (aggregation *TrackingCodeAggregation) Add(parent string, children ...string) error {
+	var parentCis string
+	if parent != "" {
+		var err error
+		if parentCis, err = aggregation.add(parent); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range children {
+		cis, err := aggregation.add(child)
+		if err != nil {
+			return err
+		}
+		if parentCis == "" {
+			continue
+		}
+		if current, ok := aggregation.parents[cis]; ok {
+			if current != parentCis {
+				return fmt.Errorf("marking code: %s is already aggregated into %s", cis, current)
+			}
+			continue
+		}
+		for ancestor := parentCis; ancestor != ""; ancestor = aggregation.parents[ancestor] {
+			if ancestor == cis {
+				return fmt.Errorf("marking code: aggregating %s into %s creates a cycle", cis, parentCis)
+			}
+		}
+		aggregation.parents[cis] = parentCis
+		aggregation.children[parentCis] = append(aggregation.children[parentCis], cis)
+	}
+	return nil
+}
+
+// TrackingCodes возвращает коды верхнего уровня с вложенными кодами.
+func (aggregation *TrackingCodeAggregation) TrackingCodes() Slice[TrackingCode] {
+	trackingCodes := NewSlice[TrackingCode]()
+	for _, cis := range aggregation.order {
+		if _, ok := aggregation.parents[cis]; !ok {
+			trackingCodes.Push(aggregation.build(cis))
+		}
+	}
+	return trackingCodes
+}
+
+// build строит [TrackingCode] с вложенными кодами.
+func (aggregation *TrackingCodeAggregation) build(cis string) *TrackingCode {
+	children := aggregation.children[cis]
+	trackingCodeType := TrackingCodeTypeTrackingCode
+	if len(children) > 0 || aggregation.codes[cis].SSCC != "" {
+		trackingCodeType = TrackingCodeTypeConsumerPack
+		if aggregation.codes[cis].SSCC != "" {
+			trackingCodeType = TrackingCodeTypeTransportPack
+		}
+	}
+
+	trackingCode := aggregation.codes[cis].TrackingCode(trackingCodeType)
+	for _, child := range children {
+		nested := aggregation.build(child)
+		if nested.Type != TrackingCodeTypeTrackingCode {
+			trackingCode.Type = TrackingCodeTypeTransportPack
+		}
+		trackingCode.SetTrackingCodes(nested)
+	}
+	return trackingCode
+}
+
+// FlattenTrackingCodes возвращает коды единиц товара, содержащиеся в кодах и их вложенных упаковках.
+//
+// Упаковки без вложенных кодов не раскрываются и в результат не входят.
+func FlattenTrackingCodes(trackingCodes ...*TrackingCode) Slice[TrackingCode] {
+	units := NewSlice[TrackingCode]()
+	for _, trackingCode := range trackingCodes {
+		if trackingCode == nil {
+			continue
+		}
+		if trackingCode.Type == TrackingCodeTypeTrackingCode || (trackingCode.Type == "" && trackingCode.TrackingCodes.Len() == 0) {
+			units.Push(trackingCode)
+			continue
+		}
+		nested := FlattenTrackingCodes(trackingCode.TrackingCodes...)
+		units.Push(nested...)
+	}
+	return units
+}
+
+// gtin14 приводит штрихкод GS1 к GTIN-14. Для штрихкодов других типов возвращает пустую строку.
+func gtin14(barcode *Barcode) string {
+	if barcode == nil || barcode.Type == BarcodeCode128 || !isDigits(barcode.Value) || len(barcode.Value) > 14 {
+		return ""
+	}
+	return strings.Repeat("0", 14-len(barcode.Value)) + barcode.Value
+}
+
+// CheckTrackingCodesGTIN проверяет, что GTIN кодов единиц товара совпадает с одним из штрихкодов GS1 товара.
+//
+// Коды упаковок не проверяются, так как упаковки имеют собственный GTIN.
+func CheckTrackingCodesGTIN(barcodes Slice[Barcode], trackingCodes ...*TrackingCode) error {
+	gtins := make(map[string]struct{})
+	for _, barcode := range barcodes {
+		if gtin := gtin14(barcode); gtin != "" {
+			gtins[gtin] = struct{}{}
+		}
+	}
+	if len(gtins) == 0 {
+		return fmt.Errorf("marking code: assortment has no GS1 barcodes")
+	}
+
+	for _, trackingCode := range FlattenTrackingCodes(trackingCodes...) {
+		markingCode, err := trackingCode.MarkingCode()
+		if err != nil {
+			return err
+		}
+		if _, ok := gtins[markingCode.GTIN]; !ok {
+			return fmt.Errorf("marking code: GTIN %s of %s does not match assortment barcodes", markingCode.GTIN, trackingCode.GetCis())
+		}
+	}
+	return nil
+}
+
+// PositionTrackingCodeService описывает метод сервиса документа, необходимый для сохранения кодов маркировки позиции.
+type PositionTrackingCodeService interface {
+	CreateUpdatePositionTrackingCodeMany(ctx context.Context, id string, positionID string, trackingCodes ...*TrackingCode) (*Slice[TrackingCode], *resty.Response, error)
+}
+
+// AttachPositionTrackingCodes проверяет коды маркировки и сохраняет их в позиции документа.
+//
+// Коды проверяются на соответствие типу, а если передан товар позиции (assortment),
+// то GTIN кодов единиц товара сверяется со штрихкодами товара.
+// Коды сохраняются частями по [MaxPositions].
+func AttachPositionTrackingCodes(ctx context.Context, service PositionTrackingCodeService, id, positionID string, assortment *AssortmentPosition, trackingCodes ...*TrackingCode) (*Slice[TrackingCode], error) {
+	for _, trackingCode := range trackingCodes {
+		if err := trackingCode.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if assortment != nil {
+		if err := CheckTrackingCodesGTIN(assortment.Barcodes, trackingCodes...); err != nil {
+			return nil, err
+		}
+	}
+
+	saved := NewSlice[TrackingCode]()
+	codes := NewSlice[TrackingCode]()
+	codes.Push(trackingCodes...)
+	for _, chunk := range codes.IntoChunks(MaxPositions) {
+		result, _, err := service.CreateUpdatePositionTrackingCodeMany(ctx, id, positionID, chunk...)
+		if err != nil {
+			return nil, err
+		}
+		saved.Push(*result...)
+	}
+	return &saved, nil
+}
@@ -547,6 +547,8 @@ func MetaTypeFromEntity(v any) MetaType {
 		metaType = MetaTypePaymentIn
 	case PaymentOut:
 		metaType = MetaTypePaymentOut
+	case Payroll:
+		metaType = MetaTypePayroll
 	case PersonalDiscount:
 		metaType = MetaTypePersonalDiscount
 	case Prepayment:
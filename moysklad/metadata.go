@@ -54,6 +54,7 @@ type Metadata struct {
 	CashIn                    MetaAttributesSharedWrapper           `json:"cashin"`
 	Contract                  MetaAttributesSharedWrapper           `json:"contract"`
 	PaymentIn                 MetaAttributesSharedWrapper           `json:"paymentin"`
+	Payroll                   MetaAttributesStatesSharedWrapper     `json:"payroll"`
 	PriceList                 MetaAttributesSharedWrapper           `json:"pricelist"`
 	BonusTransaction          MetaAttributesSharedWrapper           `json:"bonustransaction"`
 	Store                     MetaAttributesSharedWrapper           `json:"store"`
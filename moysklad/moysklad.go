@@ -1,6 +1,7 @@
 package moysklad
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/ratelimit"
@@ -25,9 +26,9 @@ const (
 	headerRateLimit              = "X-RateLimit-Limit"                      // Количество запросов, которые равномерно можно сделать в течение интервала до появления 429 ошибки.
 	headerRateRemaining          = "X-RateLimit-Remaining"                  // Число запросов, которые можно отправить до получения 429 ошибки.
 	headerRetryTimeInterval      = "X-Lognex-Retry-TimeInterval"            // Интервал в миллисекундах, в течение которого можно сделать эти запросы
+	MaxFiles                     = 100                                      // Максимальное количество файлов сущности или документа
+	MaxImages                    = 10                                       // Максимальное количество изображений товара, комплекта или модификации
 
-	//MaxFiles                = 100                           // Максимальное количество файлов
-	//MaxImages               = 10                            // Максимальное количество изображений
 	//headerRateReset         = "X-Lognex-Reset"              // Время до сброса ограничения в миллисекундах. Равно нулю, если ограничение не установлено.
 	//headerRetryAfter        = "X-Lognex-Retry-After"        // Время до сброса ограничения в миллисекундах.
 )
@@ -36,12 +37,28 @@ const (
 type Client struct {
 	nextReqTime time.Time
 	*resty.Client
-	limits *queryLimits
-	mu     sync.Mutex
+	limits    *queryLimits
+	validator RequestValidator
+	mu        sync.Mutex
+}
+
+// RequestValidator проверка тела запроса на создание или изменение перед отправкой.
+type RequestValidator interface {
+	// Validate проверяет тело body запроса method по адресу uri (относительно базового адреса API).
+	// Если возвращается ошибка, то запрос не отправляется.
+	Validate(ctx context.Context, method, uri string, body any) error
+}
+
+// SetRequestValidator устанавливает проверку тела запросов POST и PUT перед отправкой.
+//
+// Передача nil отключает проверку.
+func (client *Client) SetRequestValidator(validator RequestValidator) *Client {
+	client.validator = validator
+	return client
 }
 
 // Config конфигурация клиента.
-// Обязательно указывать либо токен (в приоритете), либо логин и пароль.
+// Обязательно указывать либо источник токена, либо токен, либо логин и пароль.
 //
 // # Пример:
 //
@@ -56,6 +73,14 @@ type Config struct {
 	// Устанавливает заранее инициализированный клиент [http.Client].
 	HTTPClient *http.Client
 
+	// Источник токена (в приоритете перед Token, Username и Password).
+	//
+	// Позволяет получать токен по логину и паролю, из переменных окружения или файла
+	// и заменять отозванный токен без потери выполняемых запросов.
+	// Если указан RestyClient, то используется его копия с собственным [http.Client]:
+	// исходный клиент не изменяется, обработчики запросов и ответов в копию не переносятся.
+	Credentials CredentialsProvider
+
 	// Токен (в приоритете).
 	Token string
 
@@ -85,22 +110,37 @@ type Config struct {
 
 // apply применяет конфигурацию к клиенту.
 func (config Config) apply(client *Client) {
-	if config.HTTPClient != nil {
-		client.Client = resty.NewWithClient(config.HTTPClient)
+	httpClient := config.HTTPClient
+	if config.Credentials != nil && config.RestyClient == nil {
+		// копия клиента, чтобы не изменять транспорт общего http.Client (пул соединений сохраняется)
+		if httpClient == nil {
+			httpClient = new(http.Client)
+		} else {
+			copied := *httpClient
+			httpClient = &copied
+		}
+		httpClient.Transport = newCredentialsTransport(httpClient.Transport, config.Credentials, client)
 	}
 
-	if config.RestyClient != nil {
+	switch {
+	case config.RestyClient != nil && config.Credentials != nil:
+		client.Client = restyWithCredentials(config.RestyClient, config.Credentials, client)
+	case config.RestyClient != nil:
 		client.Client = config.RestyClient
-	} else {
+	case httpClient != nil:
+		client.Client = resty.NewWithClient(httpClient)
+	default:
 		client.Client = resty.New()
 	}
 
-	if config.Username != "" && config.Password != "" {
-		client.SetBasicAuth(config.Username, config.Password)
-	}
+	if config.Credentials == nil {
+		if config.Username != "" && config.Password != "" {
+			client.SetBasicAuth(config.Username, config.Password)
+		}
 
-	if config.Token != "" {
-		client.SetAuthToken(config.Token)
+		if config.Token != "" {
+			client.SetAuthToken(config.Token)
+		}
 	}
 
 	if config.DisabledWebhookContent {
@@ -151,13 +191,37 @@ func New(config Config) *Client {
 type queryLimits struct {
 	rl       ratelimit.Limiter // Лимитатор для контроля частоты запросов
 	queryBuf chan struct{}     // Буфер для управления параллельными запросами
+	global   chan struct{}     // Общий буфер параллельных запросов пула клиентов (может отсутствовать)
+	stats    *clientStats      // Статистика запросов клиента пула (может отсутствовать)
 }
 
 func (queryLimits *queryLimits) Wait() {
+	start := time.Now()
+	if queryLimits.stats != nil {
+		queryLimits.stats.waiting.Add(1)
+	}
+
 	queryLimits.queryBuf <- struct{}{}
 	queryLimits.rl.Take()
+	if queryLimits.global != nil {
+		queryLimits.global <- struct{}{}
+	}
+
+	if queryLimits.stats != nil {
+		queryLimits.stats.waiting.Add(-1)
+		queryLimits.stats.inFlight.Add(1)
+		queryLimits.stats.waitTime.Add(int64(time.Since(start)))
+		queryLimits.stats.lastUsed.Store(time.Now().UnixNano())
+	}
 }
 
 func (queryLimits *queryLimits) Done() {
+	if queryLimits.stats != nil {
+		queryLimits.stats.inFlight.Add(-1)
+		queryLimits.stats.lastUsed.Store(time.Now().UnixNano())
+	}
+	if queryLimits.global != nil {
+		<-queryLimits.global
+	}
 	<-queryLimits.queryBuf
 }
@@ -92,6 +92,7 @@ type OperationInConverter interface {
 //   - InvoiceIn (Счет поставщика)
 //   - PurchaseOrder (Заказ поставщику)
 //   - CommissionReportOut (Выданный отчет комиссионера)
+//   - Payroll (Начисление зарплаты)
 type OperationOutConverter interface {
 	AsOperationOut() *Operation
 }
@@ -180,6 +181,11 @@ func (operation Operation) IsCommissionReportOut() bool {
 	return operation.Meta.GetType() == MetaTypeCommissionReportOut
 }
 
+// IsPayroll возвращает true, если объект имеет код сущности [MetaTypePayroll].
+func (operation Operation) IsPayroll() bool {
+	return operation.Meta.GetType() == MetaTypePayroll
+}
+
 // AsCustomerOrder пытается привести объект к типу [CustomerOrder].
 //
 // Метод гарантирует преобразование в необходимый тип только при идентичных [MetaType].
@@ -279,6 +285,15 @@ func (operation Operation) AsCommissionReportOut() *CommissionReportOut {
 	return UnmarshalAsType[CommissionReportOut](operation)
 }
 
+// AsPayroll пытается привести объект к типу [Payroll].
+//
+// Метод гарантирует преобразование в необходимый тип только при идентичных [MetaType].
+//
+// Возвращает [Payroll] или nil в случае неудачи.
+func (operation Operation) AsPayroll() *Payroll {
+	return UnmarshalAsType[Payroll](operation)
+}
+
 // Operations список операций.
 type Operations Slice[Operation]
 
@@ -342,6 +357,11 @@ func (operations Operations) FilterCommissionReportOut() Slice[CommissionReportO
 	return filterType[CommissionReportOut](operations)
 }
 
+// FilterPayroll фильтрует список по типу [Payroll].
+func (operations Operations) FilterPayroll() Slice[Payroll] {
+	return filterType[Payroll](operations)
+}
+
 // FilterRetailShift фильтрует список по типу [RetailShift].
 func (operations Operations) FilterRetailShift() Slice[RetailShift] {
 	return filterType[RetailShift](operations)
@@ -7,6 +7,7 @@ package moysklad
 // [Документация МойСклад]: https://dev.moysklad.ru/doc/api/remap/1.2/dictionaries/#suschnosti-towar-towary-atributy-wlozhennyh-suschnostej-upakowki-towara
 type Pack struct {
 	ID       *string        `json:"id,omitempty"`       // ID упаковки товара
+	Meta     *Meta          `json:"meta,omitempty"`     // Метаданные упаковки товара (только в ссылке на родительскую упаковку модификации)
 	Quantity *float64       `json:"quantity,omitempty"` // Количество Товаров в упаковке данного вида
 	Uom      *Uom           `json:"uom,omitempty"`      // Единица измерения
 	Barcodes Slice[Barcode] `json:"barcodes,omitempty"` // Штрихкоды
@@ -17,6 +18,11 @@ func (pack Pack) GetID() string {
 	return Deref(pack.ID)
 }
 
+// GetMeta возвращает Метаданные упаковки товара.
+func (pack Pack) GetMeta() Meta {
+	return Deref(pack.Meta)
+}
+
 // GetQuantity возвращает Количество Товаров в упаковке данного вида.
 func (pack Pack) GetQuantity() float64 {
 	return Deref(pack.Quantity)
@@ -193,7 +193,7 @@ func WithMomentFrom(momentFrom time.Time) func(*Params) {
 // momentTo=value
 func WithMomentTo(momentTo time.Time) func(*Params) {
 	return func(params *Params) {
-		params.MomentFrom = momentTo.Format(time.DateTime)
+		params.MomentTo = momentTo.Format(time.DateTime)
 	}
 }
 
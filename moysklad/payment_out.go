@@ -3,6 +3,7 @@ package moysklad
 import (
 	"context"
 	"github.com/go-resty/resty/v2"
+	"io"
 
 	"time"
 )
@@ -106,6 +107,7 @@ func (paymentOut PaymentOut) GetName() string {
 //   - InvoiceIn (Счет поставщика)
 //   - PurchaseOrder (Заказ поставщику)
 //   - CommissionReportOut (Выданный отчет комиссионера)
+//   - Payroll (Начисление зарплаты)
 func (paymentOut PaymentOut) GetOperations() Operations {
 	return paymentOut.Operations
 }
@@ -299,6 +301,7 @@ func (paymentOut *PaymentOut) SetName(name string) *PaymentOut {
 //   - InvoiceIn (Счет поставщика)
 //   - PurchaseOrder (Заказ поставщику)
 //   - CommissionReportOut (Выданный отчет комиссионера)
+//   - Payroll (Начисление зарплаты)
 //
 // Принимает множество объектов, реализующих интерфейс [OperationOutConverter].
 func (paymentOut *PaymentOut) SetOperations(operations ...OperationOutConverter) *PaymentOut {
@@ -682,6 +685,9 @@ type PaymentOutService interface {
 	// Возвращает список файлов.
 	CreateFile(ctx context.Context, id string, file *File) (*Slice[File], *resty.Response, error)
 
+	// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+	UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error)
+
 	// UpdateFileMany выполняет запрос на массовое создание и/или изменение файлов сущности/документа.
 	// Принимает контекст, ID сущности/документа и множество файлов.
 	// Возвращает созданных и/или изменённых файлов.
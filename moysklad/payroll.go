@@ -1,6 +1,10 @@
 package moysklad
 
 import (
+	"context"
+	"github.com/go-resty/resty/v2"
+	"io"
+
 	"time"
 )
 
@@ -8,24 +12,40 @@ import (
 //
 // Код сущности: payroll
 type Payroll struct {
-	Meta         *Meta            `json:"meta,omitempty"`         // Метаданные Начисления зарплаты
-	ID           *string          `json:"id,omitempty"`           // ID Начисления зарплаты
-	AccountID    *string          `json:"accountId,omitempty"`    // ID учётной записи
-	Owner        *Employee        `json:"owner,omitempty"`        // Метаданные владельца (Сотрудника)
-	Shared       *bool            `json:"shared,omitempty"`       // Общий доступ
-	Group        *Group           `json:"group,omitempty"`        // Отдел сотрудника
-	Updated      *Timestamp       `json:"updated,omitempty"`      // Момент последнего обновления Начисления зарплаты
-	Name         *string          `json:"name,omitempty"`         // Наименование Начисления зарплаты
-	ExternalCode *string          `json:"externalCode,omitempty"` // Внешний код Начисления зарплаты
-	Moment       *Timestamp       `json:"moment,omitempty"`       // Дата документа
-	Applicable   *bool            `json:"applicable,omitempty"`   // Отметка о проведении
-	Sum          *float64         `json:"sum,omitempty"`          // Сумма в копейках
-	Organization *Organization    `json:"organization,omitempty"` // Метаданные юрлица
-	Created      *Timestamp       `json:"created,omitempty"`      // Момент создания
-	Printed      *bool            `json:"printed,omitempty"`      // Напечатан ли документ
-	Published    *bool            `json:"published,omitempty"`    // Опубликован ли документ
-	Files        *MetaArray[File] `json:"files,omitempty"`        // Метаданные массива Файлов (Максимальное количество файлов - 100)
-	State        *State           `json:"state,omitempty"`        // Метаданные статуса Начисления зарплаты
+	Meta         *Meta             `json:"meta,omitempty"`         // Метаданные Начисления зарплаты
+	ID           *string           `json:"id,omitempty"`           // ID Начисления зарплаты
+	AccountID    *string           `json:"accountId,omitempty"`    // ID учётной записи
+	Owner        *Employee         `json:"owner,omitempty"`        // Метаданные владельца (Сотрудника)
+	Shared       *bool             `json:"shared,omitempty"`       // Общий доступ
+	Group        *Group            `json:"group,omitempty"`        // Отдел сотрудника
+	Updated      *Timestamp        `json:"updated,omitempty"`      // Момент последнего обновления Начисления зарплаты
+	Deleted      *Timestamp        `json:"deleted,omitempty"`      // Момент последнего удаления Начисления зарплаты
+	Name         *string           `json:"name,omitempty"`         // Наименование Начисления зарплаты
+	Description  *string           `json:"description,omitempty"`  // Комментарий Начисления зарплаты
+	ExternalCode *string           `json:"externalCode,omitempty"` // Внешний код Начисления зарплаты
+	Moment       *Timestamp        `json:"moment,omitempty"`       // Дата документа
+	Applicable   *bool             `json:"applicable,omitempty"`   // Отметка о проведении
+	Sum          *float64          `json:"sum,omitempty"`          // Сумма в копейках
+	PayedSum     *float64          `json:"payedSum,omitempty"`     // Сумма исходящих платежей по Начислению зарплаты
+	Organization *Organization     `json:"organization,omitempty"` // Метаданные юрлица
+	Created      *Timestamp        `json:"created,omitempty"`      // Момент создания
+	Printed      *bool             `json:"printed,omitempty"`      // Напечатан ли документ
+	Published    *bool             `json:"published,omitempty"`    // Опубликован ли документ
+	Files        *MetaArray[File]  `json:"files,omitempty"`        // Метаданные массива Файлов (Максимальное количество файлов - 100)
+	State        *NullValue[State] `json:"state,omitempty"`        // Метаданные статуса Начисления зарплаты
+	SyncID       *string           `json:"syncId,omitempty"`       // ID синхронизации
+	Payments     Slice[Payment]    `json:"payments,omitempty"`     // Список ссылок на связанные платежи
+	Attributes   Slice[Attribute]  `json:"attributes,omitempty"`   // Список метаданных доп. полей
+}
+
+// Clean возвращает указатель на объект с единственным заполненным полем [Meta].
+//
+// Метод позволяет избавиться от лишних данных при передаче запроса.
+func (payroll Payroll) Clean() *Payroll {
+	if payroll.Meta == nil {
+		return nil
+	}
+	return &Payroll{Meta: payroll.Meta}
 }
 
 // AsTaskOperation реализует интерфейс [TaskOperationConverter].
@@ -33,6 +53,16 @@ func (payroll Payroll) AsTaskOperation() *TaskOperation {
 	return &TaskOperation{Meta: payroll.Meta}
 }
 
+// AsOperation реализует интерфейс [OperationConverter].
+func (payroll Payroll) AsOperation() *Operation {
+	return newOperation(payroll)
+}
+
+// AsOperationOut реализует интерфейс [OperationOutConverter].
+func (payroll Payroll) AsOperationOut() *Operation {
+	return payroll.AsOperation()
+}
+
 // GetMeta возвращает Метаданные Начисления зарплаты.
 func (payroll Payroll) GetMeta() Meta {
 	return Deref(payroll.Meta)
@@ -68,11 +98,21 @@ func (payroll Payroll) GetUpdated() time.Time {
 	return Deref(payroll.Updated).Time()
 }
 
+// GetDeleted возвращает Момент последнего удаления Начисления зарплаты.
+func (payroll Payroll) GetDeleted() time.Time {
+	return Deref(payroll.Deleted).Time()
+}
+
 // GetName возвращает Наименование Начисления зарплаты.
 func (payroll Payroll) GetName() string {
 	return Deref(payroll.Name)
 }
 
+// GetDescription возвращает Комментарий Начисления зарплаты.
+func (payroll Payroll) GetDescription() string {
+	return Deref(payroll.Description)
+}
+
 // GetExternalCode возвращает Внешний код Начисления зарплаты.
 func (payroll Payroll) GetExternalCode() string {
 	return Deref(payroll.ExternalCode)
@@ -93,6 +133,11 @@ func (payroll Payroll) GetSum() float64 {
 	return Deref(payroll.Sum)
 }
 
+// GetPayedSum возвращает Сумму исходящих платежей по Начислению зарплаты.
+func (payroll Payroll) GetPayedSum() float64 {
+	return Deref(payroll.PayedSum)
+}
+
 // GetOrganization возвращает Метаданные юрлица.
 func (payroll Payroll) GetOrganization() Organization {
 	return Deref(payroll.Organization)
@@ -120,7 +165,124 @@ func (payroll Payroll) GetFiles() MetaArray[File] {
 
 // GetState возвращает Метаданные статуса Начисления зарплаты.
 func (payroll Payroll) GetState() State {
-	return Deref(payroll.State)
+	return Deref(payroll.State).getValue()
+}
+
+// GetSyncID возвращает ID синхронизации.
+func (payroll Payroll) GetSyncID() string {
+	return Deref(payroll.SyncID)
+}
+
+// GetPayments возвращает Список ссылок на связанные платежи.
+func (payroll Payroll) GetPayments() Slice[Payment] {
+	return payroll.Payments
+}
+
+// GetAttributes возвращает Список метаданных доп. полей.
+func (payroll Payroll) GetAttributes() Slice[Attribute] {
+	return payroll.Attributes
+}
+
+// SetMeta устанавливает Метаданные Начисления зарплаты.
+func (payroll *Payroll) SetMeta(meta *Meta) *Payroll {
+	payroll.Meta = meta
+	return payroll
+}
+
+// SetOwner устанавливает Метаданные владельца (Сотрудника).
+func (payroll *Payroll) SetOwner(owner *Employee) *Payroll {
+	if owner != nil {
+		payroll.Owner = owner.Clean()
+	}
+	return payroll
+}
+
+// SetShared устанавливает флаг общего доступа.
+func (payroll *Payroll) SetShared(shared bool) *Payroll {
+	payroll.Shared = &shared
+	return payroll
+}
+
+// SetGroup устанавливает Метаданные отдела сотрудника.
+func (payroll *Payroll) SetGroup(group *Group) *Payroll {
+	if group != nil {
+		payroll.Group = group.Clean()
+	}
+	return payroll
+}
+
+// SetName устанавливает Наименование Начисления зарплаты.
+func (payroll *Payroll) SetName(name string) *Payroll {
+	payroll.Name = &name
+	return payroll
+}
+
+// SetDescription устанавливает Комментарий Начисления зарплаты.
+func (payroll *Payroll) SetDescription(description string) *Payroll {
+	payroll.Description = &description
+	return payroll
+}
+
+// SetExternalCode устанавливает Внешний код Начисления зарплаты.
+func (payroll *Payroll) SetExternalCode(externalCode string) *Payroll {
+	payroll.ExternalCode = &externalCode
+	return payroll
+}
+
+// SetMoment устанавливает Дату документа.
+func (payroll *Payroll) SetMoment(moment time.Time) *Payroll {
+	payroll.Moment = NewTimestamp(moment)
+	return payroll
+}
+
+// SetApplicable устанавливает Отметку о проведении.
+func (payroll *Payroll) SetApplicable(applicable bool) *Payroll {
+	payroll.Applicable = &applicable
+	return payroll
+}
+
+// SetSum устанавливает Сумму в копейках.
+func (payroll *Payroll) SetSum(sum float64) *Payroll {
+	payroll.Sum = &sum
+	return payroll
+}
+
+// SetOrganization устанавливает Метаданные юрлица.
+func (payroll *Payroll) SetOrganization(organization *Organization) *Payroll {
+	if organization != nil {
+		payroll.Organization = organization.Clean()
+	}
+	return payroll
+}
+
+// SetFiles устанавливает Метаданные массива Файлов.
+//
+// Принимает множество объектов [File].
+func (payroll *Payroll) SetFiles(files ...*File) *Payroll {
+	payroll.Files = NewMetaArrayFrom(files)
+	return payroll
+}
+
+// SetState устанавливает Метаданные статуса Начисления зарплаты.
+//
+// Передача nil передаёт сброс значения (null).
+func (payroll *Payroll) SetState(state *State) *Payroll {
+	payroll.State = NewNullValue(state)
+	return payroll
+}
+
+// SetSyncID устанавливает ID синхронизации.
+func (payroll *Payroll) SetSyncID(syncID string) *Payroll {
+	payroll.SyncID = &syncID
+	return payroll
+}
+
+// SetAttributes устанавливает Список метаданных доп. полей.
+//
+// Принимает множество объектов [Attribute].
+func (payroll *Payroll) SetAttributes(attributes ...*Attribute) *Payroll {
+	payroll.Attributes.Push(attributes...)
+	return payroll
 }
 
 // String реализует интерфейс [fmt.Stringer].
@@ -132,3 +294,212 @@ func (payroll Payroll) String() string {
 func (Payroll) MetaType() MetaType {
 	return MetaTypePayroll
 }
+
+// Update shortcut
+func (payroll *Payroll) Update(ctx context.Context, client *Client, params ...func(*Params)) (*Payroll, *resty.Response, error) {
+	return NewPayrollService(client).Update(ctx, payroll.GetID(), payroll, params...)
+}
+
+// Create shortcut
+func (payroll *Payroll) Create(ctx context.Context, client *Client, params ...func(*Params)) (*Payroll, *resty.Response, error) {
+	return NewPayrollService(client).Create(ctx, payroll, params...)
+}
+
+// Delete shortcut
+func (payroll *Payroll) Delete(ctx context.Context, client *Client) (bool, *resty.Response, error) {
+	return NewPayrollService(client).Delete(ctx, payroll)
+}
+
+// PayrollService методы сервиса для работы с начислениями зарплаты.
+type PayrollService interface {
+	// GetList выполняет запрос на получение списка начислений зарплаты.
+	// Принимает контекст и опционально объект параметров запроса Params.
+	// Возвращает объект List.
+	GetList(ctx context.Context, params ...func(*Params)) (*List[Payroll], *resty.Response, error)
+
+	// GetListAll выполняет запрос на получение всех начислений зарплаты в виде списка.
+	// Принимает контекст и опционально объект параметров запроса Params.
+	// Возвращает список объектов.
+	GetListAll(ctx context.Context, params ...func(*Params)) (*Slice[Payroll], *resty.Response, error)
+
+	// Create выполняет запрос на создание начисления зарплаты.
+	// Обязательные поля для заполнения:
+	//	- organization (Метаданные юрлица)
+	// Принимает контекст, начисление зарплаты и опционально объект параметров запроса Params.
+	// Возвращает созданное начисление зарплаты.
+	Create(ctx context.Context, payroll *Payroll, params ...func(*Params)) (*Payroll, *resty.Response, error)
+
+	// CreateUpdateMany выполняет запрос на массовое создание и/или изменение начислений зарплаты.
+	// Изменяемые начисления зарплаты должны содержать идентификатор в виде метаданных.
+	// Принимает контекст, список начислений зарплаты и опционально объект параметров запроса Params.
+	// Возвращает список созданных и/или изменённых начислений зарплаты.
+	CreateUpdateMany(ctx context.Context, payrollList Slice[Payroll], params ...func(*Params)) (*Slice[Payroll], *resty.Response, error)
+
+	// DeleteByID выполняет запрос на удаление начисления зарплаты по ID.
+	// Принимает контекст и ID начисления зарплаты.
+	// Возвращает «true» в случае успешного удаления начисления зарплаты.
+	DeleteByID(ctx context.Context, id string) (bool, *resty.Response, error)
+
+	// Delete выполняет запрос на удаление начисления зарплаты.
+	// Принимает контекст и начисление зарплаты.
+	// Возвращает «true» в случае успешного удаления начисления зарплаты.
+	Delete(ctx context.Context, entity *Payroll) (bool, *resty.Response, error)
+
+	// DeleteMany выполняет запрос на массовое удаление начислений зарплаты.
+	// Принимает контекст и множество начислений зарплаты.
+	// Возвращает объект DeleteManyResponse, содержащий информацию об успешном удалении или ошибку.
+	DeleteMany(ctx context.Context, entities ...*Payroll) (*DeleteManyResponse, *resty.Response, error)
+
+	// GetMetadata выполняет запрос на получение метаданных начислений зарплаты.
+	// Принимает контекст.
+	// Возвращает объект метаданных MetaAttributesStatesSharedWrapper.
+	GetMetadata(ctx context.Context) (*MetaAttributesStatesSharedWrapper, *resty.Response, error)
+
+	// GetAttributeList выполняет запрос на получение списка доп полей.
+	// Принимает контекст.
+	// Возвращает объект List.
+	GetAttributeList(ctx context.Context) (*List[Attribute], *resty.Response, error)
+
+	// GetAttributeByID выполняет запрос на получение отдельного доп поля по ID.
+	// Принимает контекст и ID доп поля.
+	// Возвращает найденное доп поле.
+	GetAttributeByID(ctx context.Context, id string) (*Attribute, *resty.Response, error)
+
+	// CreateAttribute выполняет запрос на создание доп поля.
+	// Принимает контекст и доп поле.
+	// Возвращает созданное доп поле.
+	CreateAttribute(ctx context.Context, attribute *Attribute) (*Attribute, *resty.Response, error)
+
+	// CreateUpdateAttributeMany выполняет запрос на массовое создание и/или изменение доп полей.
+	// Изменяемые доп поля должны содержать идентификатор в виде метаданных.
+	// Принимает контекст и множество доп полей.
+	// Возвращает список созданных и/или изменённых доп полей.
+	CreateUpdateAttributeMany(ctx context.Context, attributes ...*Attribute) (*Slice[Attribute], *resty.Response, error)
+
+	// UpdateAttribute выполняет запрос на изменения доп поля.
+	// Принимает контекст, ID доп поля и доп поле.
+	// Возвращает изменённое доп поле.
+	UpdateAttribute(ctx context.Context, id string, attribute *Attribute) (*Attribute, *resty.Response, error)
+
+	// DeleteAttribute выполняет запрос на удаление доп поля.
+	// Принимает контекст и ID доп поля.
+	// Возвращает «true» в случае успешного удаления доп поля.
+	DeleteAttribute(ctx context.Context, id string) (bool, *resty.Response, error)
+
+	// DeleteAttributeMany выполняет запрос на массовое удаление доп полей.
+	// Принимает контекст и множество доп полей.
+	// Возвращает объект DeleteManyResponse, содержащий информацию об успешном удалении или ошибку.
+	DeleteAttributeMany(ctx context.Context, attributes ...*Attribute) (*DeleteManyResponse, *resty.Response, error)
+
+	// Template выполняет запрос на получение предзаполненного начисления зарплаты со стандартными полями без связи с какими-либо другими документами.
+	// Принимает контекст.
+	// Возвращает предзаполненное начисление зарплаты.
+	Template(ctx context.Context) (*Payroll, *resty.Response, error)
+
+	// GetByID выполняет запрос на получение отдельного начисления зарплаты по ID.
+	// Принимает контекст, ID начисления зарплаты и опционально объект параметров запроса Params.
+	// Возвращает найденное начисление зарплаты.
+	GetByID(ctx context.Context, id string, params ...func(*Params)) (*Payroll, *resty.Response, error)
+
+	// Update выполняет запрос на изменение начисления зарплаты.
+	// Принимает контекст, начисление зарплаты и опционально объект параметров запроса Params.
+	// Возвращает изменённое начисление зарплаты.
+	Update(ctx context.Context, id string, payroll *Payroll, params ...func(*Params)) (*Payroll, *resty.Response, error)
+
+	// GetPublicationList выполняет запрос на получение списка публикаций.
+	// Принимает контекст и ID документа.
+	// Возвращает объект List.
+	GetPublicationList(ctx context.Context, id string) (*List[Publication], *resty.Response, error)
+
+	// GetPublicationByID выполняет запрос на получение отдельной публикации по ID.
+	// Принимает контекст, ID документа и ID публикации.
+	// Возвращает найденную публикацию.
+	GetPublicationByID(ctx context.Context, id string, publicationID string) (*Publication, *resty.Response, error)
+
+	// Publish выполняет запрос на создание публикации.
+	// Принимает контекст, ID документа и шаблон (CustomTemplate или EmbeddedTemplate)
+	// Возвращает созданную публикацию.
+	Publish(ctx context.Context, id string, template TemplateConverter) (*Publication, *resty.Response, error)
+
+	// DeletePublication выполняет запрос на удаление публикации.
+	// Принимает контекст, ID документа и ID публикации.
+	// Возвращает «true» в случае успешного удаления публикации.
+	DeletePublication(ctx context.Context, id string, publicationID string) (bool, *resty.Response, error)
+
+	// GetBySyncID выполняет запрос на получение отдельного документа по syncID.
+	// Принимает контекст и syncID документа.
+	// Возвращает найденный документ.
+	GetBySyncID(ctx context.Context, syncID string) (*Payroll, *resty.Response, error)
+
+	// DeleteBySyncID выполняет запрос на удаление документа по syncID.
+	// Принимает контекст и syncID документа.
+	// Возвращает «true» в случае успешного удаления документа.
+	DeleteBySyncID(ctx context.Context, syncID string) (bool, *resty.Response, error)
+
+	// MoveToTrash выполняет запрос на перемещение документа с указанным ID в корзину.
+	// Принимает контекст и ID документа.
+	// Возвращает «true» в случае успешного перемещения в корзину.
+	MoveToTrash(ctx context.Context, id string) (bool, *resty.Response, error)
+
+	// GetStateByID выполняет запрос на получение статуса документа по ID.
+	// Принимает контекст и ID статуса.
+	// Возвращает найденный статус.
+	GetStateByID(ctx context.Context, id string) (*State, *resty.Response, error)
+
+	// CreateState выполняет запрос на создание статуса документа.
+	// Принимает контекст и статус.
+	// Возвращает созданный статус.
+	CreateState(ctx context.Context, state *State) (*State, *resty.Response, error)
+
+	// UpdateState выполняет запрос на изменение статуса документа.
+	// Принимает контекст, ID статуса и статус.
+	// Возвращает изменённый статус.
+	UpdateState(ctx context.Context, id string, state *State) (*State, *resty.Response, error)
+
+	// CreateUpdateStateMany выполняет запрос на массовое создание и/или изменение статусов документа.
+	// Принимает контекст и множество статусов.
+	// Возвращает список созданных и/или изменённых статусов.
+	CreateUpdateStateMany(ctx context.Context, states ...*State) (*Slice[State], *resty.Response, error)
+
+	// DeleteState выполняет запрос на удаление статуса документа.
+	// Принимает контекст и ID статуса.
+	// Возвращает «true» в случае успешного удаления статуса.
+	DeleteState(ctx context.Context, id string) (bool, *resty.Response, error)
+
+	// GetFileList выполняет запрос на получение файлов в виде списка.
+	// Принимает контекст и ID сущности/документа.
+	// Возвращает объект List.
+	GetFileList(ctx context.Context, id string) (*List[File], *resty.Response, error)
+
+	// CreateFile выполняет запрос на добавление файла.
+	// Принимает контекст, ID сущности/документа и файл.
+	// Возвращает список файлов.
+	CreateFile(ctx context.Context, id string, file *File) (*Slice[File], *resty.Response, error)
+
+	// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+	UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error)
+
+	// UpdateFileMany выполняет запрос на массовое создание и/или изменение файлов сущности/документа.
+	// Принимает контекст, ID сущности/документа и множество файлов.
+	// Возвращает созданных и/или изменённых файлов.
+	UpdateFileMany(ctx context.Context, id string, files ...*File) (*Slice[File], *resty.Response, error)
+
+	// DeleteFile выполняет запрос на удаление файла сущности/документа.
+	// Принимает контекст, ID сущности/документа и ID файла.
+	// Возвращает «true» в случае успешного удаления файла.
+	DeleteFile(ctx context.Context, id string, fileID string) (bool, *resty.Response, error)
+
+	// DeleteFileMany выполняет запрос на массовое удаление файлов сущности/документа.
+	// Принимает контекст, ID сущности/документа и множество файлов.
+	// Возвращает объект DeleteManyResponse, содержащий информацию об успешном удалении или ошибку.
+	DeleteFileMany(ctx context.Context, id string, files ...*File) (*DeleteManyResponse, *resty.Response, error)
+}
+
+const (
+	EndpointPayroll = EndpointEntity + string(MetaTypePayroll)
+)
+
+// NewPayrollService принимает [Client] и возвращает сервис для работы с начислениями зарплаты.
+func NewPayrollService(client *Client) PayrollService {
+	return newMainService[Payroll, any, MetaAttributesStatesSharedWrapper, any](client, EndpointPayroll)
+}
@@ -0,0 +1,370 @@
+package moysklad
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	printPollInterval = time.Second     // Интервал опроса готовности файла по умолчанию
+	printPollTimeout  = 5 * time.Minute // Время ожидания формирования файла по умолчанию
+)
+
+// printFileName возвращает имя файла из заголовка Content-Disposition.
+func printFileName(header http.Header) string {
+	disposition := header.Get(headerContentDisposition)
+	if _, params, err := mime.ParseMediaType(disposition); err == nil && params["filename"] != "" {
+		return path.Base(params["filename"])
+	}
+	if match := reContentDisposition.FindStringSubmatch(disposition); len(match) > 1 {
+		return path.Base(match[1])
+	}
+	return ""
+}
+
+// printGet выполняет запрос на получение файла по ссылке href.
+//
+// Авторизация клиента передаётся только при запросе к API МойСклад.
+func printGet(ctx context.Context, client *Client, href string) (*http.Response, error) {
+	if u, err := url.Parse(href); err == nil && (!u.IsAbs() || strings.HasPrefix(href, client.BaseURL)) {
+		client.limits.Wait()
+		defer client.limits.Done()
+
+		resp, err := client.R().SetContext(ctx).SetDoNotParseResponse(true).Get(href)
+		if err != nil {
+			return nil, err
+		}
+		return resp.RawResponse, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetClient().Do(req)
+}
+
+// printError возвращает ошибку из ответа resp с неуспешным статусом.
+func printError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if apiErrors, ok := parseApiErrors(body); ok {
+		return apiErrors
+	}
+	return fmt.Errorf("print: %s %s", resp.Request.URL, resp.Status)
+}
+
+// parseApiErrors разбирает тело ответа с ошибками API.
+func parseApiErrors(body []byte) (ApiErrors, bool) {
+	var apiErrors ApiErrors
+	if err := json.Unmarshal(body, &apiErrors); err != nil || len(apiErrors.ApiErrors) == 0 {
+		return apiErrors, false
+	}
+	return apiErrors, true
+}
+
+// printExport выполняет запрос на печать по адресу path с телом body и записывает полученный файл в w.
+//
+// Запрос выполняется с заголовком X-Lognex-Get-Content, поэтому готовый файл возвращается сразу.
+// Если сервис формирует файл асинхронно (ответ со ссылкой в заголовке Location),
+// то ссылка опрашивается с интервалом pollInterval до готовности файла, но не дольше pollTimeout.
+//
+// Возвращает имя файла из заголовка Content-Disposition и ответ на запрос печати.
+func printExport(ctx context.Context, client *Client, path string, body any, w io.Writer, pollInterval, pollTimeout time.Duration) (string, *resty.Response, error) {
+	client.limits.Wait()
+	restyResp, err := client.R().SetContext(ctx).SetDoNotParseResponse(true).
+		SetHeader(headerGetContent, "true").SetBody(body).Post(path)
+	client.limits.Done()
+	if err != nil {
+		return "", restyResp, err
+	}
+
+	resp := restyResp.RawResponse
+	location := resp.Header.Get("Location")
+	deadline := time.Now().Add(pollTimeout)
+	polling := false
+	for {
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			defer resp.Body.Close()
+			filename := printFileName(resp.Header)
+			_, err = io.Copy(w, resp.Body)
+			return filename, restyResp, err
+
+		case location != "" && (resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusAccepted ||
+			resp.StatusCode == http.StatusSeeOther || resp.StatusCode == http.StatusFound ||
+			polling && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent)):
+			if next := resp.Header.Get("Location"); next != "" {
+				location = next
+			}
+			resp.Body.Close()
+			if time.Now().After(deadline) {
+				return "", restyResp, fmt.Errorf("print: %s: file is not ready after %s", location, pollTimeout)
+			}
+			if polling {
+				select {
+				case <-ctx.Done():
+					return "", restyResp, ctx.Err()
+				case <-time.After(pollInterval):
+				}
+			}
+			polling = true
+			if resp, err = printGet(ctx, client, location); err != nil {
+				return "", restyResp, err
+			}
+
+		default:
+			defer resp.Body.Close()
+			return "", restyResp, printError(resp)
+		}
+	}
+}
+
+// PrintJob Задание на печать документа либо этикеток и ценников.
+type PrintJob struct {
+	Type     MetaType          // Код сущности/документа
+	ID       string            // ID сущности/документа
+	Document *PrintDocumentArg // Параметры печати документа
+	Label    *PrintLabelArg    // Параметры печати этикеток и ценников (количество может превышать MaxPrintCount)
+	FileName string            // Имя файла (по умолчанию – из заголовка Content-Disposition)
+}
+
+// NewPrintJob возвращает [PrintJob] для печати документа document с параметрами arg.
+func NewPrintJob(document MetaOwner, arg *PrintDocumentArg) *PrintJob {
+	meta := document.GetMeta()
+	return &PrintJob{Type: meta.GetType(), ID: meta.GetUUIDFromHref(), Document: arg}
+}
+
+// NewPrintLabelJob возвращает [PrintJob] для печати этикеток и ценников товара entity с параметрами arg.
+func NewPrintLabelJob(entity MetaOwner, arg *PrintLabelArg) *PrintJob {
+	meta := entity.GetMeta()
+	return &PrintJob{Type: meta.GetType(), ID: meta.GetUUIDFromHref(), Label: arg}
+}
+
+// PrintResult Файл, полученный в результате печати.
+type PrintResult struct {
+	Job      *PrintJob // Задание на печать
+	FileName string    // Имя файла в архиве
+	Size     int64     // Размер файла в байтах
+}
+
+// PrintBatchConfig конфигурация пакетной печати.
+type PrintBatchConfig struct {
+	// Количество одновременно выполняемых заданий (по умолчанию MaxQueriesPerUser).
+	Concurrency int
+
+	// Интервал опроса готовности асинхронно формируемого файла (по умолчанию 1 секунда).
+	PollInterval time.Duration
+
+	// Время ожидания формирования файла (по умолчанию 5 минут).
+	PollTimeout time.Duration
+
+	// Каталог для временных файлов (по умолчанию системный).
+	TempDir string
+}
+
+// PrintBatch пакетная печать документов и этикеток.
+//
+// Задания выполняются параллельно в пределах ограничений клиента на количество запросов.
+// Полученные файлы сохраняются во временные файлы и записываются в архив ZIP в порядке заданий
+// (объединение файлов в один PDF не поддерживается). Имена файлов берутся из заголовка
+// Content-Disposition; повторяющиеся имена дополняются порядковым номером.
+// Печать этикеток с количеством больше [MaxPrintCount] разбивается на несколько запросов.
+//
+// Пример:
+//
+//	batch := moysklad.NewPrintBatch(client, moysklad.PrintBatchConfig{})
+//	var jobs []*moysklad.PrintJob
+//	for _, demand := range demands {
+//		jobs = append(jobs, moysklad.NewPrintJob(demand, moysklad.NewPrintDocArgOne(template, moysklad.PDF)))
+//	}
+//	results, err := batch.WriteZIP(ctx, file, jobs...)
+type PrintBatch struct {
+	client *Client
+	config PrintBatchConfig
+}
+
+// NewPrintBatch возвращает [PrintBatch].
+func NewPrintBatch(client *Client, config PrintBatchConfig) *PrintBatch {
+	if config.Concurrency <= 0 {
+		config.Concurrency = MaxQueriesPerUser
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = printPollInterval
+	}
+	if config.PollTimeout <= 0 {
+		config.PollTimeout = printPollTimeout
+	}
+	return &PrintBatch{client: client, config: config}
+}
+
+// printPart часть результата печати задания.
+type printPart struct {
+	job    *PrintJob
+	index  int      // номер части (для этикеток с количеством больше MaxPrintCount)
+	parts  int      // количество частей задания
+	body   any      // тело запроса
+	file   *os.File // временный файл с результатом
+	name   string   // имя файла из ответа
+	result *PrintResult
+}
+
+// split разбивает задания на запросы.
+func (batch *PrintBatch) split(jobs []*PrintJob) ([]*printPart, error) {
+	var parts []*printPart
+	for _, job := range jobs {
+		switch {
+		case job.Document != nil:
+			parts = append(parts, &printPart{job: job, parts: 1, body: job.Document})
+		case job.Label != nil:
+			count := job.Label.Count
+			n := (count + MaxPrintCount - 1) / MaxPrintCount
+			if n < 1 {
+				n = 1
+			}
+			for i := 0; i < n; i++ {
+				label := *job.Label
+				label.Count = min(MaxPrintCount, count-i*MaxPrintCount)
+				parts = append(parts, &printPart{job: job, index: i, parts: n, body: &label})
+			}
+		default:
+			return nil, fmt.Errorf("print: job %s/%s has no print arguments", job.Type, job.ID)
+		}
+	}
+	return parts, nil
+}
+
+// run выполняет задания и возвращает части результата во временных файлах.
+func (batch *PrintBatch) run(ctx context.Context, jobs []*PrintJob) ([]*printPart, error) {
+	parts, err := batch.split(jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, batch.config.Concurrency)
+	)
+	for _, part := range parts {
+		wg.Add(1)
+		go func(part *printPart) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := func() error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				file, err := os.CreateTemp(batch.config.TempDir, "moysklad-print-*")
+				if err != nil {
+					return err
+				}
+				part.file = file
+				uri := fmt.Sprintf(EndpointExport, EndpointEntity+string(part.job.Type), part.job.ID)
+				part.name, _, err = printExport(ctx, batch.client, uri, part.body, file, batch.config.PollInterval, batch.config.PollTimeout)
+				if err != nil {
+					return fmt.Errorf("print %s/%s: %w", part.job.Type, part.job.ID, err)
+				}
+				_, err = file.Seek(0, io.SeekStart)
+				return err
+			}()
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(part)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		printCleanup(parts)
+		return nil, firstErr
+	}
+	printNames(parts)
+	return parts, nil
+}
+
+// printNames присваивает частям результата уникальные имена файлов в порядке заданий.
+func printNames(parts []*printPart) {
+	used := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		name := part.job.FileName
+		if name == "" {
+			name = part.name
+		}
+		if name == "" {
+			name = string(part.job.Type) + "-" + part.job.ID
+		}
+		ext := path.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		if part.parts > 1 {
+			base = fmt.Sprintf("%s-%d", base, part.index+1)
+		}
+		name = base + ext
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		used[name] = true
+
+		var size int64
+		if info, err := part.file.Stat(); err == nil {
+			size = info.Size()
+		}
+		part.result = &PrintResult{Job: part.job, FileName: name, Size: size}
+	}
+}
+
+// printCleanup удаляет временные файлы.
+func printCleanup(parts []*printPart) {
+	for _, part := range parts {
+		if part.file != nil {
+			part.file.Close()
+			os.Remove(part.file.Name())
+		}
+	}
+}
+
+// WriteZIP выполняет задания на печать jobs и записывает полученные файлы в архив ZIP w.
+func (batch *PrintBatch) WriteZIP(ctx context.Context, w io.Writer, jobs ...*PrintJob) ([]*PrintResult, error) {
+	parts, err := batch.run(ctx, jobs)
+	if err != nil {
+		return nil, err
+	}
+	defer printCleanup(parts)
+
+	archive := zip.NewWriter(w)
+	results := make([]*PrintResult, 0, len(parts))
+	for _, part := range parts {
+		entry, err := archive.Create(part.result.FileName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.Copy(entry, part.file); err != nil {
+			return nil, err
+		}
+		results = append(results, part.result)
+	}
+	return results, archive.Close()
+}
@@ -3,6 +3,7 @@ package moysklad
 import (
 	"context"
 	"github.com/go-resty/resty/v2"
+	"io"
 
 	"time"
 )
@@ -851,6 +852,9 @@ type ProductService interface {
 	// Возвращает список изображений.
 	CreateImage(ctx context.Context, id string, image *Image) (*Slice[Image], *resty.Response, error)
 
+	// UploadImage выполняет запрос на добавление изображения с именем filename и содержимым r.
+	UploadImage(ctx context.Context, id, filename string, r io.Reader) (*Slice[Image], *resty.Response, error)
+
 	// UpdateImageMany выполняет запрос на обновления изображений.
 	// Принимает контекст, ID товара и изображение.
 	// Если необходимо оставить некоторые Изображения, то необходимо передать эти изображения.
@@ -907,6 +911,9 @@ type ProductService interface {
 	// Возвращает список файлов.
 	CreateFile(ctx context.Context, id string, file *File) (*Slice[File], *resty.Response, error)
 
+	// UploadFile выполняет запрос на добавление файла с именем filename и содержимым r.
+	UploadFile(ctx context.Context, id, filename string, r io.Reader) (*Slice[File], *resty.Response, error)
+
 	// UpdateFileMany выполняет запрос на массовое создание и/или изменение файлов сущности/документа.
 	// Принимает контекст, ID сущности/документа и множество файлов.
 	// Возвращает созданных и/или изменённых файлов.
@@ -0,0 +1,322 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// AgingBucket Интервал просрочки дебиторской задолженности.
+//
+// Возможные значения:
+//   - AgingBucket0to30   – до 30 дней (включая задолженность, срок оплаты которой не наступил)
+//   - AgingBucket31to60  – от 31 до 60 дней
+//   - AgingBucket61to90  – от 61 до 90 дней
+//   - AgingBucketOver90  – более 90 дней
+type AgingBucket int
+
+const (
+	AgingBucket0to30  AgingBucket = iota // До 30 дней
+	AgingBucket31to60                    // От 31 до 60 дней
+	AgingBucket61to90                    // От 61 до 90 дней
+	AgingBucketOver90                    // Более 90 дней
+)
+
+// AgingBuckets количество интервалов просрочки.
+const AgingBuckets = 4
+
+// NewAgingBucket возвращает интервал для количества дней просрочки days.
+func NewAgingBucket(days int) AgingBucket {
+	switch {
+	case days <= 30:
+		return AgingBucket0to30
+	case days <= 60:
+		return AgingBucket31to60
+	case days <= 90:
+		return AgingBucket61to90
+	}
+	return AgingBucketOver90
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (agingBucket AgingBucket) String() string {
+	switch agingBucket {
+	case AgingBucket0to30:
+		return "0–30"
+	case AgingBucket31to60:
+		return "31–60"
+	case AgingBucket61to90:
+		return "61–90"
+	}
+	return "90+"
+}
+
+// ReceivablesAgingDocument Документ с неоплаченной задолженностью.
+type ReceivablesAgingDocument struct {
+	Meta   Meta        // Метаданные документа (Счёт покупателю или Отгрузка)
+	Name   string      // Номер документа
+	Moment time.Time   // Дата документа
+	Due    time.Time   // Срок оплаты
+	Debt   float64     // Сумма задолженности в копейках: отгружено, но не оплачено
+	Days   int         // Количество дней просрочки (отрицательное, если срок оплаты не наступил)
+	Bucket AgingBucket // Интервал просрочки
+}
+
+// ReceivablesAgingRow Задолженность контрагента в разрезе юрлица, договора и владельца документов.
+type ReceivablesAgingRow struct {
+	Counterparty *Agent                      // Контрагент
+	Organization *Organization               // Юрлицо
+	Contract     *Contract                   // Договор
+	Owner        *Employee                   // Владелец документов
+	Buckets      [AgingBuckets]float64       // Задолженность по интервалам просрочки в копейках
+	Total        float64                     // Общая задолженность в копейках
+	Documents    []*ReceivablesAgingDocument // Документы с задолженностью
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (row ReceivablesAgingRow) String() string {
+	return Stringify(row)
+}
+
+// ReceivablesAgingReport Отчёт о дебиторской задолженности по срокам.
+type ReceivablesAgingReport struct {
+	AsOf        time.Time              // Дата отчёта
+	Rows        []*ReceivablesAgingRow // Задолженность по контрагентам
+	Totals      [AgingBuckets]float64  // Итого по интервалам просрочки в копейках
+	Total       float64                // Итого задолженность в копейках
+	Balance     map[string]float64     // Долг контрагентов по отчёту Показатели контрагентов в копейках. Ключ – ID контрагента
+	Unallocated map[string]float64     // Разница между долгом по балансу и задолженностью по документам (только без отбора по юрлицу). Ключ – ID контрагента
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (report ReceivablesAgingReport) String() string {
+	return Stringify(report)
+}
+
+// WriteCSV записывает отчёт в формате CSV с разделителем «;». Суммы указываются в рублях.
+func (report ReceivablesAgingReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+
+	header := []string{"Контрагент", "ИНН", "Юрлицо", "Договор", "Сотрудник"}
+	for bucket := AgingBucket0to30; bucket <= AgingBucketOver90; bucket++ {
+		header = append(header, bucket.String())
+	}
+	header = append(header, "Итого", "Не распределено")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	amount := func(kopecks float64) string {
+		return strconv.FormatFloat(math.Round(kopecks)/100, 'f', 2, 64)
+	}
+	// нераспределённая сумма относится к контрагенту и выводится в первой его строке
+	seen := make(map[string]bool)
+	for _, row := range report.Rows {
+		var inn string
+		if counterparty := row.Counterparty.AsCounterparty(); counterparty != nil {
+			inn = counterparty.GetINN()
+		}
+		record := []string{
+			row.Counterparty.GetName(),
+			inn,
+			row.Organization.GetName(),
+			row.Contract.GetName(),
+			row.Owner.GetName(),
+		}
+		for _, value := range row.Buckets {
+			record = append(record, amount(value))
+		}
+		var unallocated string
+		if id := row.Counterparty.GetID(); !seen[id] {
+			seen[id] = true
+			unallocated = amount(report.Unallocated[id])
+		}
+		record = append(record, amount(row.Total), unallocated)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	total := []string{"Итого", "", "", "", ""}
+	for _, value := range report.Totals {
+		total = append(total, amount(value))
+	}
+	total = append(total, amount(report.Total), "")
+	if err := cw.Write(total); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReceivablesAgingConfig конфигурация отчёта о дебиторской задолженности.
+type ReceivablesAgingConfig struct {
+	// Дата отчёта (по умолчанию текущий момент).
+	AsOf time.Time
+
+	// Юрлицо. Если не указано, то отчёт строится по всем юрлицам.
+	Organization *Organization
+}
+
+// ReceivablesAging построение отчёта о дебиторской задолженности по срокам (aging).
+//
+// Задолженность по Счёту покупателю – отгруженная, но не оплаченная сумма; срок оплаты –
+// планируемая дата оплаты счёта (или дата счёта, если она не указана).
+// Отгрузки, не связанные со счетами, учитываются по неоплаченной сумме со сроком оплаты на дату отгрузки.
+// Для сверки в отчёт включается долг по балансу контрагента и его разница с задолженностью по документам
+// (например, платежи, не привязанные к документам).
+//
+// Пример:
+//
+//	report, err := moysklad.NewReceivablesAging(client, moysklad.ReceivablesAgingConfig{}).Build(ctx)
+//	err = report.WriteCSV(file)
+type ReceivablesAging struct {
+	client *Client
+	config ReceivablesAgingConfig
+}
+
+// NewReceivablesAging возвращает [ReceivablesAging].
+func NewReceivablesAging(client *Client, config ReceivablesAgingConfig) *ReceivablesAging {
+	return &ReceivablesAging{client: client, config: config}
+}
+
+// Build строит отчёт о дебиторской задолженности.
+func (aging *ReceivablesAging) Build(ctx context.Context) (*ReceivablesAgingReport, error) {
+	asOf := aging.config.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+
+	params := []func(*Params){
+		WithExpand("agent", "organization", "contract", "owner"),
+		WithFilterEquals("applicable", "true"),
+		WithFilterLesserOrEquals("moment", asOf.Format(TimestampFormat)),
+	}
+	if aging.config.Organization != nil {
+		params = append(params, WithFilterObject(aging.config.Organization))
+	}
+
+	report := &ReceivablesAgingReport{
+		AsOf:        asOf,
+		Balance:     make(map[string]float64),
+		Unallocated: make(map[string]float64),
+	}
+	rows := make(map[string]*ReceivablesAgingRow)
+	add := func(agent *Agent, organization *Organization, contract *Contract, owner *Employee, document *ReceivablesAgingDocument) {
+		if agent == nil || !agent.IsCounterparty() || document.Debt <= 0 {
+			return
+		}
+		if organization == nil {
+			organization = new(Organization)
+		}
+		if owner == nil {
+			owner = new(Employee)
+		}
+		document.Days = int(math.Floor(asOf.Sub(document.Due).Hours() / 24))
+		document.Bucket = NewAgingBucket(document.Days)
+
+		key := agent.GetMeta().GetHref() + "|" + organization.GetMeta().GetHref() + "|" + contract.GetMeta().GetHref() + "|" + owner.GetMeta().GetHref()
+		row, ok := rows[key]
+		if !ok {
+			row = &ReceivablesAgingRow{Counterparty: agent, Organization: organization, Contract: contract, Owner: owner}
+			rows[key] = row
+			report.Rows = append(report.Rows, row)
+		}
+		row.Buckets[document.Bucket] += document.Debt
+		row.Total += document.Debt
+		row.Documents = append(row.Documents, document)
+	}
+
+	invoices, _, err := NewInvoiceOutService(aging.client).GetListAll(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+	for _, invoice := range Deref(invoices) {
+		due := invoice.GetPaymentPlannedMoment()
+		if due.IsZero() {
+			due = invoice.GetMoment()
+		}
+		contract := Deref(invoice.Contract).getValue()
+		add(invoice.Agent, invoice.Organization, &contract, invoice.Owner, &ReceivablesAgingDocument{
+			Meta:   invoice.GetMeta(),
+			Name:   invoice.GetName(),
+			Moment: invoice.GetMoment(),
+			Due:    due,
+			Debt:   math.Min(invoice.GetShippedSum(), invoice.GetSum()) - invoice.GetPayedSum(),
+		})
+	}
+
+	demands, _, err := NewDemandService(aging.client).GetListAll(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+	for _, demand := range Deref(demands) {
+		if demand.InvoicesOut.Len() > 0 {
+			continue
+		}
+		contract := Deref(demand.Contract).getValue()
+		add(demand.Agent, demand.Organization, &contract, demand.Owner, &ReceivablesAgingDocument{
+			Meta:   demand.GetMeta(),
+			Name:   demand.GetName(),
+			Moment: demand.GetMoment(),
+			Due:    demand.GetMoment(),
+			Debt:   demand.GetSum() - demand.GetPayedSum(),
+		})
+	}
+
+	err = forEachPage(ctx, NewReportCounterpartyService(aging.client), func(rows Slice[ReportCounterparty]) error {
+		for _, row := range rows {
+			if row.Balance < 0 {
+				report.Balance[row.Counterparty.ID] = -row.Balance
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range report.Rows {
+		for i, value := range row.Buckets {
+			report.Totals[i] += value
+		}
+		report.Total += row.Total
+	}
+	// баланс контрагента учитывает все юрлица, поэтому сверка выполняется только по полному отчёту
+	if aging.config.Organization == nil {
+		debts := make(map[string]float64)
+		for _, row := range report.Rows {
+			debts[row.Counterparty.GetID()] += row.Total
+		}
+		for id, balance := range report.Balance {
+			debts[id] -= balance
+		}
+		for id, debt := range debts {
+			if math.Abs(debt) >= 1 {
+				report.Unallocated[id] = -debt
+			}
+		}
+	}
+
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		for _, pair := range [][2]string{
+			{a.Counterparty.GetName(), b.Counterparty.GetName()},
+			{a.Organization.GetName(), b.Organization.GetName()},
+			{a.Contract.GetName(), b.Contract.GetName()},
+			{a.Owner.GetName(), b.Owner.GetName()},
+		} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return false
+	})
+	return report, nil
+}
@@ -0,0 +1,362 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ReorderConfig конфигурация планирования закупок.
+type ReorderConfig struct {
+	// Юрлицо, от имени которого создаются заказы поставщикам.
+	Organization *Organization
+
+	// Склады, для которых планируется пополнение. Если не указаны, то используются все склады.
+	Stores []*Store
+
+	// Период анализа продаж в днях (по умолчанию 30).
+	SalesDays int
+
+	// Срок поставки в днях (по умолчанию 7).
+	LeadDays int
+
+	// Запас в днях продаж, на который заказывается товар сверх срока поставки (по умолчанию 14).
+	CoverDays int
+
+	// Дополнительные параметры отбора товаров, например фильтр по группе.
+	ProductParams []func(*Params)
+}
+
+// withDefaults возвращает конфигурацию с заполненными значениями по умолчанию.
+func (config ReorderConfig) withDefaults() ReorderConfig {
+	if config.SalesDays <= 0 {
+		config.SalesDays = 30
+	}
+	if config.LeadDays <= 0 {
+		config.LeadDays = 7
+	}
+	if config.CoverDays <= 0 {
+		config.CoverDays = 14
+	}
+	return config
+}
+
+// ReorderLine Строка плана закупок: товар на складе.
+type ReorderLine struct {
+	Product        *Product      // Товар
+	Store          *Store        // Склад
+	Supplier       *Counterparty // Поставщик
+	Stock          float64       // Физический остаток
+	Reserve        float64       // Резерв
+	InTransit      float64       // Ожидание
+	MinimumBalance float64       // Доля неснижаемого остатка товара, приходящаяся на склад
+	DailySales     float64       // Средние продажи в день за период анализа
+	ReorderPoint   float64       // Точка заказа: неснижаемый остаток и продажи за срок поставки
+	Quantity       float64       // Количество к заказу
+	Price          float64       // Закупочная цена в копейках
+}
+
+// Available возвращает доступный остаток с учётом резерва и ожидания.
+func (line ReorderLine) Available() float64 {
+	return line.Stock - line.Reserve + line.InTransit
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (line ReorderLine) String() string {
+	return Stringify(line)
+}
+
+// ReorderPlan План закупок.
+type ReorderPlan struct {
+	Lines   []*ReorderLine   // Строки к заказу
+	Skipped []string         // Товары, требующие пополнения, для которых не указан поставщик
+	Orders  []*PurchaseOrder // Заказы поставщикам (по одному на поставщика и склад)
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (plan ReorderPlan) String() string {
+	return Stringify(plan)
+}
+
+// ReorderPlanner планировщик закупок.
+//
+// Для каждого товара и склада вычисляется точка заказа – неснижаемый остаток плюс средние продажи
+// за срок поставки. Если доступный остаток (остаток - резерв + ожидание) не превышает точку заказа,
+// то товар заказывается до уровня: неснижаемый остаток плюс продажи за срок поставки и запас [ReorderConfig.CoverDays].
+// Средние продажи вычисляются по отчёту "Прибыльность по товарам" за последние [ReorderConfig.SalesDays] дней с учётом возвратов.
+//
+// Неснижаемый остаток товара общий для учётной записи, поэтому он распределяется между складами,
+// на которых товар есть (остаток, резерв или ожидание) или продавался, пропорционально продажам
+// (при отсутствии продаж – поровну). Если таких складов нет, то неснижаемый остаток относится к первому складу.
+//
+// Пример:
+//
+//	planner := moysklad.NewReorderPlanner(client, moysklad.ReorderConfig{Organization: organization})
+//	plan, err := planner.Plan(ctx) // предварительный просмотр
+//	err = planner.Create(ctx, plan) // создание заказов поставщикам
+type ReorderPlanner struct {
+	client *Client
+	config ReorderConfig
+}
+
+// NewReorderPlanner возвращает [ReorderPlanner].
+func NewReorderPlanner(client *Client, config ReorderConfig) *ReorderPlanner {
+	return &ReorderPlanner{client: client, config: config.withDefaults()}
+}
+
+// reorderStock остатки товара на складе.
+type reorderStock struct {
+	stock     float64
+	reserve   float64
+	inTransit float64
+}
+
+// Plan формирует план закупок и черновики заказов поставщикам без сохранения в МойСклад.
+func (planner *ReorderPlanner) Plan(ctx context.Context) (*ReorderPlan, error) {
+	stores, err := planner.stores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stock, err := planner.stock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	products, _, err := NewProductService(planner.client).GetListAll(ctx, planner.config.ProductParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	sales := make(map[string]map[string]float64, len(stores))
+	for _, store := range stores {
+		if sales[store.GetID()], err = planner.sales(ctx, store); err != nil {
+			return nil, err
+		}
+	}
+	minimums := reorderMinimums(stores, Deref(products), stock, sales)
+
+	config := planner.config
+	plan := new(ReorderPlan)
+	for _, store := range stores {
+		for _, product := range Deref(products) {
+			productID := product.GetID()
+			current := stock[store.GetID()][productID]
+			line := &ReorderLine{
+				Product:        product,
+				Store:          store,
+				Stock:          current.stock,
+				Reserve:        current.reserve,
+				InTransit:      current.inTransit,
+				MinimumBalance: minimums[store.GetID()][productID],
+				DailySales:     sales[store.GetID()][productID] / float64(config.SalesDays),
+				Price:          product.GetBuyPrice().GetValue(),
+			}
+			line.ReorderPoint = line.MinimumBalance + line.DailySales*float64(config.LeadDays)
+			if line.ReorderPoint <= 0 || line.Available() > line.ReorderPoint {
+				continue
+			}
+
+			target := line.ReorderPoint + line.DailySales*float64(config.CoverDays)
+			line.Quantity = math.Ceil(target - line.Available())
+			if line.Quantity <= 0 {
+				continue
+			}
+
+			supplier := product.GetSupplier()
+			if supplier.GetMeta().GetHref() == "" {
+				plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s (%s)", product.GetName(), store.GetName()))
+				continue
+			}
+			line.Supplier = &supplier
+			plan.Lines = append(plan.Lines, line)
+		}
+	}
+
+	sort.SliceStable(plan.Lines, func(i, j int) bool {
+		a, b := plan.Lines[i], plan.Lines[j]
+		if a.Supplier.GetName() != b.Supplier.GetName() {
+			return a.Supplier.GetName() < b.Supplier.GetName()
+		}
+		if a.Store.GetName() != b.Store.GetName() {
+			return a.Store.GetName() < b.Store.GetName()
+		}
+		return a.Product.GetName() < b.Product.GetName()
+	})
+
+	plan.Orders = planner.orders(plan.Lines)
+	return plan, nil
+}
+
+// Create сохраняет заказы поставщикам плана в МойСклад.
+//
+// Заказы создаются непроведёнными; в плане они заменяются созданными.
+func (planner *ReorderPlanner) Create(ctx context.Context, plan *ReorderPlan) error {
+	service := NewPurchaseOrderService(planner.client)
+	for i, order := range plan.Orders {
+		created, _, err := service.Create(ctx, order)
+		if err != nil {
+			return err
+		}
+		plan.Orders[i] = created
+	}
+	return nil
+}
+
+// Run формирует план закупок и создаёт заказы поставщикам.
+func (planner *ReorderPlanner) Run(ctx context.Context) (*ReorderPlan, error) {
+	plan, err := planner.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return plan, planner.Create(ctx, plan)
+}
+
+// orders группирует строки плана в заказы поставщикам по поставщику и складу.
+func (planner *ReorderPlanner) orders(lines []*ReorderLine) []*PurchaseOrder {
+	var orders []*PurchaseOrder
+	index := make(map[string]*PurchaseOrder)
+	positions := make(map[*PurchaseOrder][]*PurchaseOrderPosition)
+
+	delivery := time.Now().AddDate(0, 0, planner.config.LeadDays)
+	for _, line := range lines {
+		key := line.Supplier.GetMeta().GetHref() + "|" + line.Store.GetMeta().GetHref()
+		order, ok := index[key]
+		if !ok {
+			order = new(PurchaseOrder).
+				SetAgent(line.Supplier).
+				SetStore(line.Store).
+				SetApplicable(false).
+				SetDeliveryPlannedMoment(delivery).
+				SetDescription(fmt.Sprintf("Автозаказ: продажи за %d дн., срок поставки %d дн., запас %d дн.",
+					planner.config.SalesDays, planner.config.LeadDays, planner.config.CoverDays))
+			if planner.config.Organization != nil {
+				order.SetOrganization(planner.config.Organization)
+			}
+			index[key] = order
+			orders = append(orders, order)
+		}
+		positions[order] = append(positions[order], new(PurchaseOrderPosition).
+			SetAssortment(line.Product).
+			SetQuantity(line.Quantity).
+			SetPrice(line.Price))
+	}
+
+	for _, order := range orders {
+		order.SetPositions(positions[order]...)
+	}
+	return orders
+}
+
+// reorderMinimums распределяет неснижаемый остаток товаров products между складами stores
+// и возвращает долю каждого склада по ID склада и товара.
+func reorderMinimums(stores []*Store, products []*Product, stock map[string]map[string]reorderStock, sales map[string]map[string]float64) map[string]map[string]float64 {
+	result := make(map[string]map[string]float64, len(stores))
+	for _, store := range stores {
+		result[store.GetID()] = make(map[string]float64)
+	}
+	if len(stores) == 0 {
+		return result
+	}
+
+	for _, product := range products {
+		minimum := product.GetMinimumBalance()
+		if minimum <= 0 {
+			continue
+		}
+		productID := product.GetID()
+
+		var (
+			carriers []string
+			total    float64
+		)
+		for _, store := range stores {
+			storeID := store.GetID()
+			_, stocked := stock[storeID][productID]
+			sold := max(sales[storeID][productID], 0)
+			if stocked || sold > 0 {
+				carriers = append(carriers, storeID)
+				total += sold
+			}
+		}
+		if len(carriers) == 0 {
+			carriers = []string{stores[0].GetID()}
+		}
+
+		for _, storeID := range carriers {
+			share := 1 / float64(len(carriers))
+			if total > 0 {
+				share = max(sales[storeID][productID], 0) / total
+			}
+			result[storeID][productID] = minimum * share
+		}
+	}
+	return result
+}
+
+// stores возвращает склады из конфигурации или все склады.
+func (planner *ReorderPlanner) stores(ctx context.Context) ([]*Store, error) {
+	if len(planner.config.Stores) > 0 {
+		return planner.config.Stores, nil
+	}
+	stores, _, err := NewStoreService(planner.client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Deref(stores), nil
+}
+
+// stock возвращает остатки, резерв и ожидание по складам и товарам.
+func (planner *ReorderPlanner) stock(ctx context.Context) (map[string]map[string]reorderStock, error) {
+	result := make(map[string]map[string]reorderStock)
+	service := NewReportStockService(planner.client)
+	for _, stockType := range []StockType{StockDefault, StockReserve, StockInTransit} {
+		rows, _, err := service.GetCurrentByStore(ctx, WithStockType(stockType))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range Deref(rows) {
+			byProduct, ok := result[row.StoreID]
+			if !ok {
+				byProduct = make(map[string]reorderStock)
+				result[row.StoreID] = byProduct
+			}
+			current := byProduct[row.AssortmentID]
+			switch stockType {
+			case StockDefault:
+				current.stock = row.Stock
+			case StockReserve:
+				current.reserve = row.Reserve
+			case StockInTransit:
+				current.inTransit = row.InTransit
+			}
+			byProduct[row.AssortmentID] = current
+		}
+	}
+	return result, nil
+}
+
+// sales возвращает количество продаж за вычетом возвратов по товарам на складе за период анализа.
+func (planner *ReorderPlanner) sales(ctx context.Context, store *Store) (map[string]float64, error) {
+	now := time.Now()
+	params := []func(*Params){
+		WithMomentFrom(now.AddDate(0, 0, -planner.config.SalesDays)),
+		WithMomentTo(now),
+		WithFilterObject(store),
+	}
+
+	sales := make(map[string]float64)
+	service := NewReportProfitService(planner.client)
+	for offset := 0; ; offset += MaxPositions {
+		list, _, err := service.GetByProduct(ctx, append(params, WithLimit(MaxPositions), WithOffset(offset))...)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range list.Rows {
+			sales[row.Assortment.Meta.GetUUIDFromHref()] += row.SellQuantity - row.ReturnQuantity
+		}
+		if list.Len() < MaxPositions || offset+MaxPositions >= list.Size() {
+			return sales, nil
+		}
+	}
+}
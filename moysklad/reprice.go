@@ -0,0 +1,792 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"math"
+	"sort"
+	"strings"
+)
+
+// RepriceBase База расчёта цены.
+//
+// Возможные значения:
+//   - RepriceBaseBuyPrice  – закупочная цена
+//   - RepriceBaseMinPrice  – минимальная цена
+//   - RepriceBaseSalePrice – цена продажи другого типа (указывается в поле BasePriceType правила)
+type RepriceBase string
+
+const (
+	RepriceBaseBuyPrice  RepriceBase = "buyPrice"  // Закупочная цена
+	RepriceBaseMinPrice  RepriceBase = "minPrice"  // Минимальная цена
+	RepriceBaseSalePrice RepriceBase = "salePrice" // Цена продажи другого типа
+)
+
+// RepriceRoundingMode Направление округления цены.
+//
+// Возможные значения:
+//   - RepriceRoundingNearest – до ближайшего значения (по умолчанию)
+//   - RepriceRoundingUp      – в большую сторону
+//   - RepriceRoundingDown    – в меньшую сторону
+type RepriceRoundingMode string
+
+const (
+	RepriceRoundingNearest RepriceRoundingMode = "nearest" // До ближайшего значения
+	RepriceRoundingUp      RepriceRoundingMode = "up"      // В большую сторону
+	RepriceRoundingDown    RepriceRoundingMode = "down"    // В меньшую сторону
+)
+
+// RepriceRounding Округление цены.
+//
+// Цена приводится к виду k × Step + Ending. Например, для цен, оканчивающихся на 9 рублей,
+// указывается Step = 1000 и Ending = 900 (значения в копейках).
+type RepriceRounding struct {
+	Step   float64             // Шаг округления в копейках (например, 100 – до рубля). Если не указан, то цена округляется до копейки
+	Ending float64             // Окончание цены в копейках внутри шага
+	Mode   RepriceRoundingMode // Направление округления
+}
+
+// Round округляет цену value (в копейках).
+func (rounding RepriceRounding) Round(value float64) float64 {
+	if rounding.Step <= 0 {
+		return math.Round(value)
+	}
+
+	const eps = 1e-9
+	k := (value - rounding.Ending) / rounding.Step
+	switch rounding.Mode {
+	case RepriceRoundingUp:
+		k = math.Ceil(k - eps)
+	case RepriceRoundingDown:
+		k = math.Floor(k + eps)
+	default:
+		k = math.Round(k)
+	}
+
+	result := k*rounding.Step + rounding.Ending
+	if result <= 0 && value > 0 {
+		result = math.Ceil((value-rounding.Ending)/rounding.Step-eps)*rounding.Step + rounding.Ending
+	}
+
+	return math.Round(result)
+}
+
+// RepriceRule Правило расчёта цены продажи.
+//
+// Условия применения (группа, доп. поле, типы сущностей) необязательны: правило без условий применяется
+// ко всем сущностям. Для каждого типа цены применяется первое подходящее правило,
+// поэтому более частные правила следует указывать раньше общих.
+//
+// Пример правила "Розница = закупка × 1.4 с округлением до 9 рублей" для группы "Одежда":
+//
+//	RepriceRule{
+//		PriceType: "Цена продажи",
+//		Base:      RepriceBaseBuyPrice,
+//		Markup:    1.4,
+//		Rounding:  RepriceRounding{Step: 1000, Ending: 900, Mode: RepriceRoundingUp},
+//		Folder:    "Одежда",
+//	}
+type RepriceRule struct {
+	PriceType      string          // Наименование рассчитываемого типа цены
+	Base           RepriceBase     // База расчёта
+	BasePriceType  string          // Наименование типа цены – базы расчёта (для RepriceBaseSalePrice)
+	Markup         float64         // Множитель базы (по умолчанию 1)
+	Addition       float64         // Надбавка в копейках, прибавляемая после умножения
+	Rounding       RepriceRounding // Округление
+	IgnoreMinPrice bool            // Не ограничивать рассчитанную цену минимальной ценой
+	Folder         string          // Условие: путь группы (включая вложенные группы)
+	Attribute      string          // Условие: наименование доп. поля
+	AttributeValue string          // Условие: значение доп. поля (для справочников – наименование элемента)
+	Types          []MetaType      // Условие: типы сущностей
+}
+
+// RepricerConfig конфигурация переоценки.
+type RepricerConfig struct {
+	// Правила расчёта цен.
+	Rules []RepriceRule
+
+	// Типы сущностей для переоценки: [MetaTypeProduct], [MetaTypeVariant], [MetaTypeService], [MetaTypeBundle].
+	// По умолчанию все перечисленные.
+	Types []MetaType
+
+	// Параметры выборки Товаров, Услуг и Комплектов (например, фильтр).
+	//
+	// Модификации выбираются по Товарам, отобранным с этими параметрами.
+	Params []func(*Params)
+
+	// Параметры выборки отдельных типов сущностей. Заменяют Params для указанного типа.
+	TypeParams map[MetaType][]func(*Params)
+
+	// Разделитель уровней в пути группы. По умолчанию "/".
+	FolderSeparator string
+
+	// Шаблон Прайс-листа. Если указан, то при применении переоценки создаётся Прайс-лист с новыми ценами.
+	//
+	// Столбцы Прайс-листа соответствуют изменённым типам цен.
+	PriceList *PriceList
+}
+
+// RepriceChange изменение цены продажи.
+type RepriceChange struct {
+	Entity    Meta    // Метаданные Товара/Модификации/Услуги/Комплекта
+	Name      string  // Наименование
+	PriceType string  // Наименование типа цены
+	Rule      int     // Индекс применённого правила
+	Base      float64 // Значение базы расчёта в копейках
+	Old       float64 // Текущая цена в копейках
+	New       float64 // Новая цена в копейках
+	Limited   bool    // Цена ограничена минимальной ценой
+}
+
+// RepricePlan план переоценки.
+type RepricePlan struct {
+	Changes  []RepriceChange // Изменения цен
+	Warnings []string        // Предупреждения (например, нулевая база расчёта)
+	items    map[string]*repriceItem
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (plan RepricePlan) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "изменений цен: %d", len(plan.Changes))
+	for _, change := range plan.Changes {
+		fmt.Fprintf(&sb, "\n%s [%s]: %.2f → %.2f", change.Name, change.PriceType, change.Old/100, change.New/100)
+		if change.Limited {
+			sb.WriteString(" (минимальная цена)")
+		}
+	}
+	for _, warning := range plan.Warnings {
+		sb.WriteString("\n")
+		sb.WriteString(warning)
+	}
+	return sb.String()
+}
+
+// RepriceResult результат применения переоценки.
+type RepriceResult struct {
+	Updated   int             // Количество изменённых сущностей
+	Failed    []*RepriceError // Сущности, цены которых не удалось изменить
+	PriceList *PriceList      // Созданный Прайс-лист
+}
+
+// RepriceError ошибка изменения цен сущности.
+type RepriceError struct {
+	Entity    Meta       // Метаданные Товара/Модификации/Услуги/Комплекта
+	Name      string     // Наименование
+	Err       error      // Ошибка разбора ответа
+	ApiErrors []ApiError // Ошибки API МойСклад, относящиеся к сущности
+}
+
+// Error реализует интерфейс error.
+func (e RepriceError) Error() string {
+	entity := e.Entity.GetHref()
+	if e.Name != "" {
+		entity = fmt.Sprintf("%s (%s)", e.Name, entity)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", entity, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", entity, ApiErrors{ApiErrors: NewSliceFrom(e.ApiErrors)})
+}
+
+// Repricer переоценка Товаров, Модификаций, Услуг и Комплектов по правилам.
+//
+// [Repricer.Preview] рассчитывает новые цены и возвращает план без изменения данных,
+// [Repricer.Apply] применяет план через массовое изменение сущностей.
+type Repricer struct {
+	client     *Client
+	config     RepricerConfig
+	priceTypes map[string]*PriceType
+	folders    map[string]string
+}
+
+// NewRepricer возвращает [Repricer].
+func NewRepricer(client *Client, config RepricerConfig) *Repricer {
+	if len(config.Types) == 0 {
+		config.Types = []MetaType{MetaTypeProduct, MetaTypeVariant, MetaTypeService, MetaTypeBundle}
+	}
+	if config.FolderSeparator == "" {
+		config.FolderSeparator = "/"
+	}
+	return &Repricer{client: client, config: config}
+}
+
+// repriceItem сущность, участвующая в переоценке.
+type repriceItem struct {
+	meta       Meta
+	name       string
+	metaType   MetaType
+	folder     string
+	attributes Slice[Attribute]
+	buyPrice   float64
+	minPrice   float64
+	salePrices Slice[SalePrice]
+	parent     *repriceItem
+	changes    map[string]float64
+}
+
+// salePrice возвращает цену продажи типа priceType (для модификации – с учётом цены товара).
+func (item *repriceItem) salePrice(priceType string) (*SalePrice, float64) {
+	for _, salePrice := range item.salePrices {
+		if salePrice.GetPriceType().GetName() == priceType {
+			if value := salePrice.GetValue(); value != 0 || item.parent == nil {
+				return salePrice, value
+			}
+			_, value := item.parent.salePrice(priceType)
+			return salePrice, value
+		}
+	}
+	if item.parent != nil {
+		_, value := item.parent.salePrice(priceType)
+		return nil, value
+	}
+	return nil, 0
+}
+
+// base возвращает значение базы расчёта правила.
+func (item *repriceItem) base(rule RepriceRule) float64 {
+	var value float64
+	switch rule.Base {
+	case RepriceBaseBuyPrice:
+		value = item.buyPrice
+		if value == 0 && item.parent != nil {
+			value = item.parent.buyPrice
+		}
+	case RepriceBaseMinPrice:
+		value = item.minPrice
+		if value == 0 && item.parent != nil {
+			value = item.parent.minPrice
+		}
+	case RepriceBaseSalePrice:
+		// новая цена базового типа, если она рассчитана раньше
+		if changed, ok := item.changes[rule.BasePriceType]; ok {
+			return changed
+		}
+		_, value = item.salePrice(rule.BasePriceType)
+	}
+	return value
+}
+
+// matches проверяет условия применения правила.
+func (repricer *Repricer) matches(rule RepriceRule, item *repriceItem) bool {
+	if len(rule.Types) > 0 {
+		var ok bool
+		for _, metaType := range rule.Types {
+			ok = ok || metaType == item.metaType
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if rule.Folder != "" {
+		folder := strings.Trim(rule.Folder, repricer.config.FolderSeparator)
+		if item.folder != folder && !strings.HasPrefix(item.folder, folder+repricer.config.FolderSeparator) {
+			return false
+		}
+	}
+
+	if rule.Attribute != "" {
+		attributes := item.attributes
+		if item.parent != nil {
+			attributes = item.parent.attributes
+		}
+		var ok bool
+		for _, attribute := range attributes {
+			if attribute.GetName() == rule.Attribute {
+				ok = repriceAttributeValue(attribute) == rule.AttributeValue
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// repriceAttributeValue возвращает значение доп. поля в виде строки.
+func repriceAttributeValue(attribute *Attribute) string {
+	switch value := attribute.GetValue().(type) {
+	case nil:
+		return ""
+	case map[string]any:
+		// элемент справочника
+		return fmt.Sprint(value["name"])
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Preview рассчитывает новые цены и возвращает план переоценки.
+func (repricer *Repricer) Preview(ctx context.Context) (*RepricePlan, error) {
+	if err := repricer.prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range repricer.config.Rules {
+		if _, ok := repricer.priceTypes[rule.PriceType]; !ok {
+			return nil, fmt.Errorf("reprice: rule %d: unknown price type %q", i, rule.PriceType)
+		}
+		if rule.Base == RepriceBaseSalePrice {
+			if _, ok := repricer.priceTypes[rule.BasePriceType]; !ok {
+				return nil, fmt.Errorf("reprice: rule %d: unknown base price type %q", i, rule.BasePriceType)
+			}
+		}
+	}
+
+	items, err := repricer.items(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RepricePlan{items: make(map[string]*repriceItem)}
+	for _, item := range items {
+		item.changes = make(map[string]float64)
+		applied := make(map[string]bool)
+
+		for i, rule := range repricer.config.Rules {
+			if applied[rule.PriceType] || !repricer.matches(rule, item) {
+				continue
+			}
+			applied[rule.PriceType] = true
+
+			base := item.base(rule)
+			if base == 0 {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s [%s]: база расчёта %s равна нулю", item.name, rule.PriceType, rule.Base))
+				continue
+			}
+
+			markup := rule.Markup
+			if markup == 0 {
+				markup = 1
+			}
+			value := rule.Rounding.Round(base*markup + rule.Addition)
+
+			var limited bool
+			if minPrice := item.minPrice; !rule.IgnoreMinPrice && minPrice > 0 && value < minPrice {
+				rounding := rule.Rounding
+				rounding.Mode = RepriceRoundingUp
+				value, limited = rounding.Round(minPrice), true
+			}
+
+			item.changes[rule.PriceType] = value
+
+			old, current := item.salePrice(rule.PriceType)
+			if old != nil && current == value {
+				continue
+			}
+
+			plan.Changes = append(plan.Changes, RepriceChange{
+				Entity:    item.meta,
+				Name:      item.name,
+				PriceType: rule.PriceType,
+				Rule:      i,
+				Base:      base,
+				Old:       current,
+				New:       value,
+				Limited:   limited,
+			})
+			plan.items[item.meta.GetHref()] = item
+		}
+	}
+
+	return plan, nil
+}
+
+// prepare загружает типы цен и группы товаров.
+func (repricer *Repricer) prepare(ctx context.Context) error {
+	if repricer.priceTypes == nil {
+		priceTypes, _, err := NewContextCompanySettingsService(repricer.client).GetPriceTypes(ctx)
+		if err != nil {
+			return err
+		}
+		repricer.priceTypes = make(map[string]*PriceType)
+		for _, priceType := range Deref(priceTypes) {
+			repricer.priceTypes[priceType.GetName()] = priceType
+		}
+	}
+
+	if repricer.folders == nil {
+		folders, _, err := NewProductFolderService(repricer.client).GetListAll(ctx)
+		if err != nil {
+			return err
+		}
+		repricer.folders = make(map[string]string)
+		for _, folder := range Deref(folders) {
+			path := folder.GetName()
+			if pathName := folder.GetPathName(); pathName != "" {
+				path = pathName + repricer.config.FolderSeparator + path
+			}
+			repricer.folders[folder.GetMeta().GetUUIDFromHref()] = path
+		}
+	}
+
+	return nil
+}
+
+// folder возвращает путь группы.
+func (repricer *Repricer) folder(folder ProductFolder) string {
+	if folder.Meta == nil {
+		return ""
+	}
+	return repricer.folders[folder.GetMeta().GetUUIDFromHref()]
+}
+
+// hasType возвращает true, если тип сущностей участвует в переоценке.
+func (repricer *Repricer) hasType(metaType MetaType) bool {
+	for _, t := range repricer.config.Types {
+		if t == metaType {
+			return true
+		}
+	}
+	return false
+}
+
+// items загружает сущности, участвующие в переоценке.
+func (repricer *Repricer) items(ctx context.Context) ([]*repriceItem, error) {
+	var (
+		items    []*repriceItem
+		products = make(map[string]*repriceItem)
+	)
+
+	// товары загружаются и для модификаций: группа, доп. поля и цены наследуются от товара
+	if repricer.hasType(MetaTypeProduct) || repricer.hasType(MetaTypeVariant) {
+		rows, _, err := NewProductService(repricer.client).GetListAll(ctx, repricer.params(MetaTypeProduct)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, product := range Deref(rows) {
+			item := &repriceItem{
+				meta:       product.GetMeta(),
+				name:       product.GetName(),
+				metaType:   MetaTypeProduct,
+				folder:     repricer.folder(product.GetProductFolder()),
+				attributes: product.Attributes,
+				buyPrice:   product.GetBuyPrice().GetValue(),
+				minPrice:   product.GetMinPrice().GetValue(),
+				salePrices: product.SalePrices,
+			}
+			products[item.meta.GetUUIDFromHref()] = item
+			if repricer.hasType(MetaTypeProduct) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	if repricer.hasType(MetaTypeVariant) {
+		variants, err := repricer.variants(ctx, products)
+		if err != nil {
+			return nil, err
+		}
+		for _, variant := range variants {
+			item := &repriceItem{
+				meta:       variant.GetMeta(),
+				name:       variant.GetName(),
+				metaType:   MetaTypeVariant,
+				buyPrice:   variant.GetBuyPrice().GetValue(),
+				minPrice:   variant.GetMinPrice().GetValue(),
+				salePrices: variant.SalePrices,
+			}
+			if parent, ok := products[variant.GetProduct().GetMeta().GetUUIDFromHref()]; ok {
+				item.parent = parent
+				item.folder = parent.folder
+				if item.minPrice == 0 {
+					item.minPrice = parent.minPrice
+				}
+			}
+			items = append(items, item)
+		}
+	}
+
+	if repricer.hasType(MetaTypeService) {
+		rows, _, err := NewServiceService(repricer.client).GetListAll(ctx, repricer.params(MetaTypeService)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range Deref(rows) {
+			items = append(items, &repriceItem{
+				meta:       service.GetMeta(),
+				name:       service.GetName(),
+				metaType:   MetaTypeService,
+				folder:     repricer.folder(service.GetProductFolder()),
+				attributes: service.Attributes,
+				buyPrice:   service.GetBuyPrice().GetValue(),
+				minPrice:   service.GetMinPrice().GetValue(),
+				salePrices: service.SalePrices,
+			})
+		}
+	}
+
+	if repricer.hasType(MetaTypeBundle) {
+		rows, _, err := NewBundleService(repricer.client).GetListAll(ctx, repricer.params(MetaTypeBundle)...)
+		if err != nil {
+			return nil, err
+		}
+		for _, bundle := range Deref(rows) {
+			items = append(items, &repriceItem{
+				meta:       bundle.GetMeta(),
+				name:       bundle.GetName(),
+				metaType:   MetaTypeBundle,
+				folder:     repricer.folder(bundle.GetProductFolder()),
+				attributes: bundle.Attributes,
+				minPrice:   bundle.GetMinPrice().GetValue(),
+				salePrices: bundle.SalePrices,
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// params возвращает параметры выборки сущностей типа metaType.
+func (repricer *Repricer) params(metaType MetaType) []func(*Params) {
+	if params, ok := repricer.config.TypeParams[metaType]; ok {
+		return params
+	}
+	return repricer.config.Params
+}
+
+// repriceVariantsChunk количество Товаров в одном запросе Модификаций.
+const repriceVariantsChunk = 100
+
+// variants загружает Модификации.
+//
+// Если параметры выборки Модификаций не указаны, а Товары отобраны по [RepricerConfig.Params],
+// то загружаются Модификации отобранных Товаров products.
+func (repricer *Repricer) variants(ctx context.Context, products map[string]*repriceItem) ([]*Variant, error) {
+	service := NewVariantService(repricer.client)
+	if params, ok := repricer.config.TypeParams[MetaTypeVariant]; ok || len(repricer.config.Params) == 0 {
+		rows, _, err := service.GetListAll(ctx, params...)
+		if err != nil {
+			return nil, err
+		}
+		return Deref(rows), nil
+	}
+
+	ids := make([]string, 0, len(products))
+	for id := range products {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var variants []*Variant
+	for start := 0; start < len(ids); start += repriceVariantsChunk {
+		end := min(start+repriceVariantsChunk, len(ids))
+		params := make([]func(*Params), 0, end-start)
+		for _, id := range ids[start:end] {
+			params = append(params, WithFilterEquals("productid", id))
+		}
+		rows, _, err := service.GetListAll(ctx, params...)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, Deref(rows)...)
+	}
+	return variants, nil
+}
+
+// salePrices возвращает цены продажи сущности с применёнными изменениями.
+func (repricer *Repricer) salePrices(item *repriceItem, changes map[string]float64) Slice[SalePrice] {
+	var (
+		result = make(Slice[SalePrice], 0, len(item.salePrices))
+		seen   = make(map[string]bool)
+	)
+
+	for _, salePrice := range item.salePrices {
+		name := salePrice.GetPriceType().GetName()
+		value := salePrice.GetValue()
+		if changed, ok := changes[name]; ok {
+			value = changed
+		}
+		seen[name] = true
+		result.Push(&SalePrice{Value: &value, Currency: salePrice.Currency, PriceType: salePrice.GetPriceType().Clean()})
+	}
+
+	for name, value := range changes {
+		if seen[name] {
+			continue
+		}
+		value := value
+		result.Push(&SalePrice{Value: &value, PriceType: repricer.priceTypes[name].Clean()})
+	}
+
+	return result
+}
+
+// Apply применяет план переоценки.
+//
+// Сущности изменяются порциями по [MaxPositions] через массовое изменение.
+// Сущности, отклонённые сервисом, возвращаются в [RepriceResult.Failed] и не учитываются в Updated.
+// Если в конфигурации указан шаблон Прайс-листа, то после изменения цен создаётся Прайс-лист
+// (без отклонённых сущностей).
+func (repricer *Repricer) Apply(ctx context.Context, plan *RepricePlan) (*RepriceResult, error) {
+	changes := make(map[string]map[string]float64)
+	var hrefs []string
+	for _, change := range plan.Changes {
+		href := change.Entity.GetHref()
+		if _, ok := changes[href]; !ok {
+			changes[href] = make(map[string]float64)
+			hrefs = append(hrefs, href)
+		}
+		changes[href][change.PriceType] = change.New
+	}
+
+	var (
+		products Slice[Product]
+		variants Slice[Variant]
+		services Slice[Service]
+		bundles  Slice[Bundle]
+	)
+	for _, href := range hrefs {
+		item, ok := plan.items[href]
+		if !ok {
+			return nil, fmt.Errorf("reprice: plan is not prepared by Preview")
+		}
+		meta := item.meta
+		salePrices := repricer.salePrices(item, changes[href])
+
+		switch item.metaType {
+		case MetaTypeProduct:
+			products.Push(&Product{Meta: &meta, SalePrices: salePrices})
+		case MetaTypeVariant:
+			variants.Push(&Variant{Meta: &meta, SalePrices: salePrices})
+		case MetaTypeService:
+			services.Push(&Service{Meta: &meta, SalePrices: salePrices})
+		case MetaTypeBundle:
+			bundles.Push(&Bundle{Meta: &meta, SalePrices: salePrices})
+		}
+	}
+
+	result := new(RepriceResult)
+
+	updated, failed, err := repriceUpdate(ctx, NewProductService(repricer.client), products)
+	result.Updated, result.Failed = result.Updated+updated, append(result.Failed, failed...)
+	if err == nil {
+		updated, failed, err = repriceUpdate(ctx, NewVariantService(repricer.client), variants)
+		result.Updated, result.Failed = result.Updated+updated, append(result.Failed, failed...)
+	}
+	if err == nil {
+		updated, failed, err = repriceUpdate(ctx, NewServiceService(repricer.client), services)
+		result.Updated, result.Failed = result.Updated+updated, append(result.Failed, failed...)
+	}
+	if err == nil {
+		updated, failed, err = repriceUpdate(ctx, NewBundleService(repricer.client), bundles)
+		result.Updated, result.Failed = result.Updated+updated, append(result.Failed, failed...)
+	}
+
+	// в Прайс-лист попадают только сущности с изменёнными ценами
+	failedHrefs := make(map[string]bool, len(result.Failed))
+	for _, failure := range result.Failed {
+		failure.Name = plan.items[failure.Entity.GetHref()].name
+		failedHrefs[failure.Entity.GetHref()] = true
+	}
+	if err != nil {
+		return result, err
+	}
+	if len(failedHrefs) > 0 {
+		updatedHrefs := make([]string, 0, len(hrefs)-len(failedHrefs))
+		for _, href := range hrefs {
+			if !failedHrefs[href] {
+				updatedHrefs = append(updatedHrefs, href)
+			}
+		}
+		hrefs = updatedHrefs
+	}
+
+	if repricer.config.PriceList != nil && len(hrefs) > 0 {
+		if result.PriceList, err = repricer.priceList(ctx, plan, hrefs, changes); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// repriceUpdate изменяет сущности порциями по [MaxPositions].
+//
+// Возвращает количество изменённых сущностей и ошибки по отдельным сущностям (без наименования).
+func repriceUpdate[T MetaOwner](ctx context.Context, service interface {
+	CreateUpdateMany(ctx context.Context, entities Slice[T], params ...func(*Params)) (*Slice[T], *resty.Response, error)
+}, entities Slice[T]) (updated int, failed []*RepriceError, err error) {
+	for _, chunk := range entities.IntoChunks(MaxPositions) {
+		_, resp, err := service.CreateUpdateMany(ctx, chunk)
+
+		var raw []json.RawMessage
+		if resp != nil {
+			_ = json.Unmarshal(resp.Body(), &raw)
+		}
+
+		// сервис возвращает массив той же длины, где на месте ошибочных объектов находятся ошибки
+		if len(raw) != len(chunk) {
+			if err == nil {
+				err = fmt.Errorf("reprice: unexpected response length %d, want %d", len(raw), len(chunk))
+			}
+			return updated, failed, err
+		}
+
+		for i, entity := range chunk {
+			var element struct {
+				Errors []ApiError `json:"errors"`
+			}
+			if err := json.Unmarshal(raw[i], &element); err != nil {
+				failed = append(failed, &RepriceError{Entity: (*entity).GetMeta(), Err: err})
+				continue
+			}
+			if len(element.Errors) > 0 {
+				failed = append(failed, &RepriceError{Entity: (*entity).GetMeta(), ApiErrors: element.Errors})
+				continue
+			}
+			updated++
+		}
+	}
+	return updated, failed, nil
+}
+
+// priceList создаёт Прайс-лист с новыми ценами.
+func (repricer *Repricer) priceList(ctx context.Context, plan *RepricePlan, hrefs []string, changes map[string]map[string]float64) (*PriceList, error) {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, href := range hrefs {
+		for name := range changes[href] {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	priceList := *repricer.config.PriceList
+	priceList.Positions = nil
+	priceList.Columns = nil
+	for _, name := range columns {
+		priceList.Columns.Push(&PriceListColumn{Name: String(name)})
+	}
+
+	service := NewPriceListService(repricer.client)
+	created, _, err := service.Create(ctx, &priceList)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions Slice[PriceListPosition]
+	for _, href := range hrefs {
+		position := &PriceListPosition{Assortment: &AssortmentPosition{Meta: plan.items[href].meta}}
+		for _, name := range columns {
+			if value, ok := changes[href][name]; ok {
+				position.Cells.Push(&PriceListCell{Column: String(name), Sum: Float(value)})
+			}
+		}
+		positions.Push(position)
+	}
+
+	if _, _, err = service.CreatePositionMany(ctx, created.GetID(), positions...); err != nil {
+		return created, err
+	}
+
+	return created, nil
+}
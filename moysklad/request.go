@@ -59,6 +59,14 @@ func (requestBuilder *RequestBuilder[T]) SetParams(params []func(*Params)) *Requ
 }
 
 func (requestBuilder *RequestBuilder[T]) Send(ctx context.Context, method string, body any) (*T, *resty.Response, error) {
+	// Проверка тела запроса на создание или изменение
+	if validator := requestBuilder.client.validator; validator != nil && body != nil &&
+		(method == http.MethodPost || method == http.MethodPut) {
+		if err := validator.Validate(ctx, method, requestBuilder.uri, body); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Ограничения на количество запросов
 	requestBuilder.client.limits.Wait()
 	defer requestBuilder.client.limits.Done()
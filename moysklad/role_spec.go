@@ -0,0 +1,629 @@
+package moysklad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Системные роли, которые можно указать в [EmployeeRoleSpec.Role].
+const (
+	SystemRoleAdmin      = "admin"      // Администратор
+	SystemRoleIndividual = "individual" // Индивидуальная роль
+	SystemRoleCashier    = "cashier"    // Кассир
+	SystemRoleWorker     = "worker"     // Сотрудник производства
+)
+
+// PermissionSpec Декларативное описание ролей и прав сотрудников.
+//
+// Права роли задаются по именам полей [EmployeePermissions] в JSON: вложенными объектами
+// или ключами через точку. Права, не указанные в описании, не изменяются;
+// у создаваемой роли они устанавливаются в NO, флаги – в false.
+//
+// Пример (YAML):
+//
+//	roles:
+//	  - name: Менеджер
+//	    permissions:
+//	      demand: {view: ALL, create: ALL, update: OWN, delete: NO, approve: OWN, print: ALL}
+//	      customerOrder.approve: OWN
+//	      viewDashboard: true
+//	employees:
+//	  - uid: ivanov@company
+//	    role: Менеджер
+//	  - uid: admin@company
+//	    role: admin
+type PermissionSpec struct {
+	Roles     []RoleSpec         `json:"roles" yaml:"roles"`         // Пользовательские роли
+	Employees []EmployeeRoleSpec `json:"employees" yaml:"employees"` // Назначение ролей сотрудникам
+}
+
+// RoleSpec Описание пользовательской роли.
+type RoleSpec struct {
+	Name        string         `json:"name" yaml:"name"`               // Наименование роли
+	Permissions map[string]any `json:"permissions" yaml:"permissions"` // Права роли
+}
+
+// EmployeeRoleSpec Назначение роли сотруднику.
+type EmployeeRoleSpec struct {
+	UID    string `json:"uid" yaml:"uid"`                           // Логин сотрудника
+	Role   string `json:"role" yaml:"role"`                         // Наименование пользовательской роли или системная роль (admin, individual, cashier, worker)
+	Active *bool  `json:"active,omitempty" yaml:"active,omitempty"` // Доступ к сервису МойСклад
+}
+
+// ParsePermissionSpec читает описание ролей и прав в формате YAML или JSON.
+func ParsePermissionSpec(r io.Reader) (*PermissionSpec, error) {
+	var spec PermissionSpec
+	if err := yaml.NewDecoder(r).Decode(&spec); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("permissions: %w", err)
+	}
+	return &spec, nil
+}
+
+// flattenPermissions преобразует права в плоский набор значений с ключами вида «demand.approve».
+func flattenPermissions(permissions *EmployeePermissions) map[string]any {
+	data, _ := json.Marshal(permissions)
+	var raw map[string]any
+	_ = json.Unmarshal(data, &raw)
+
+	flat := make(map[string]any)
+	flattenPermissionValues("", raw, flat)
+	return flat
+}
+
+// flattenPermissionValues раскладывает вложенные значения value с префиксом prefix в flat.
+func flattenPermissionValues(prefix string, value any, flat map[string]any) {
+	nested, ok := value.(map[string]any)
+	if !ok {
+		flat[prefix] = value
+		return
+	}
+	for key, child := range nested {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenPermissionValues(key, child, flat)
+	}
+}
+
+// defaultPermissions возвращает права новой роли в плоском виде:
+// все права установлены в [PermissionNo], флаги – в false.
+func defaultPermissions() map[string]any {
+	flat := flattenPermissions(new(EmployeePermissions))
+	for key, value := range flat {
+		if value == "" {
+			flat[key] = string(PermissionNo)
+		}
+	}
+	return flat
+}
+
+// unflattenPermissions собирает права из плоского набора значений.
+func unflattenPermissions(flat map[string]any) (*EmployeePermissions, error) {
+	data, err := json.Marshal(nestPermissions(flat))
+	if err != nil {
+		return nil, err
+	}
+	permissions := new(EmployeePermissions)
+	if err = json.Unmarshal(data, permissions); err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// nestPermissions преобразует плоский набор значений прав во вложенные объекты.
+func nestPermissions(flat map[string]any) map[string]any {
+	raw := make(map[string]any)
+	for key, value := range flat {
+		parts := strings.Split(key, ".")
+		node := raw
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = value
+	}
+	return raw
+}
+
+// validPermissionValues допустимые значения прав.
+var validPermissionValues = map[string]bool{
+	string(PermissionOwn): true, string(PermissionOwnShared): true, string(PermissionOwnGroup): true,
+	string(PermissionOwnGroupShared): true, string(PermissionAll): true, string(PermissionNo): true,
+	string(ScriptPermissionValueOAuthorOrAssignee): true, string(ScriptPermissionValueAssignee): true,
+	string(ScriptPermissionValueAuthor): true,
+}
+
+// normalize проверяет права роли и возвращает их в плоском виде.
+func (roleSpec RoleSpec) normalize() (map[string]any, error) {
+	known := flattenPermissions(new(EmployeePermissions))
+	flat := make(map[string]any)
+	flattenPermissionValues("", roleSpec.Permissions, flat)
+	delete(flat, "")
+
+	for key, value := range flat {
+		current, ok := known[key]
+		if !ok {
+			return nil, fmt.Errorf("permissions: role %q: unknown permission %q", roleSpec.Name, key)
+		}
+		switch current.(type) {
+		case bool:
+			if _, ok := value.(bool); !ok {
+				return nil, fmt.Errorf("permissions: role %q: permission %q must be boolean", roleSpec.Name, key)
+			}
+		default:
+			s, ok := value.(string)
+			if !ok || !validPermissionValues[strings.ToUpper(s)] {
+				return nil, fmt.Errorf("permissions: role %q: invalid value %v of permission %q", roleSpec.Name, value, key)
+			}
+			flat[key] = strings.ToUpper(s)
+		}
+	}
+	return flat, nil
+}
+
+// PermissionChangeKind Вид изменения прав.
+//
+// Возможные значения:
+//   - PermissionChangeRoleCreate   – создание пользовательской роли
+//   - PermissionChangeRoleUpdate   – изменение права пользовательской роли
+//   - PermissionChangeEmployeeRole – назначение роли сотруднику
+//   - PermissionChangeEmployeeActive – изменение доступа сотрудника к сервису
+type PermissionChangeKind string
+
+const (
+	PermissionChangeRoleCreate     PermissionChangeKind = "role_create"     // Создание роли
+	PermissionChangeRoleUpdate     PermissionChangeKind = "role_update"     // Изменение права роли
+	PermissionChangeEmployeeRole   PermissionChangeKind = "employee_role"   // Назначение роли сотруднику
+	PermissionChangeEmployeeActive PermissionChangeKind = "employee_active" // Изменение доступа сотрудника
+)
+
+// PermissionChange Изменение прав.
+type PermissionChange struct {
+	Kind   PermissionChangeKind // Вид изменения
+	Target string               // Наименование роли или логин сотрудника
+	Key    string               // Право (для изменения роли)
+	From   any                  // Текущее значение
+	To     any                  // Новое значение
+}
+
+// String возвращает описание изменения.
+func (change PermissionChange) String() string {
+	switch change.Kind {
+	case PermissionChangeRoleCreate:
+		return fmt.Sprintf("+ роль %q", change.Target)
+	case PermissionChangeRoleUpdate:
+		return fmt.Sprintf("~ роль %q: %s: %v -> %v", change.Target, change.Key, permissionDisplay(change.From), permissionDisplay(change.To))
+	case PermissionChangeEmployeeRole:
+		return fmt.Sprintf("~ сотрудник %s: роль %v -> %v", change.Target, permissionDisplay(change.From), permissionDisplay(change.To))
+	}
+	return fmt.Sprintf("~ сотрудник %s: доступ %v -> %v", change.Target, change.From, change.To)
+}
+
+// permissionDisplay возвращает значение права для вывода.
+func permissionDisplay(value any) any {
+	if value == nil || value == "" {
+		return "—"
+	}
+	return value
+}
+
+// PermissionPlan План изменения ролей и прав сотрудников.
+type PermissionPlan struct {
+	Changes []PermissionChange // Изменения
+
+	roles     []*Role                        // Роли для создания
+	updates   []roleUpdate                   // Изменения существующих ролей
+	employees map[string]*EmployeePermission // Права сотрудников для изменения. Ключ – ID сотрудника
+}
+
+// roleUpdate изменение существующей роли.
+type roleUpdate struct {
+	id          string
+	name        string
+	permissions map[string]any // Изменённые права в плоском виде
+}
+
+// IsEmpty возвращает true, если текущие роли и права соответствуют описанию.
+func (plan PermissionPlan) IsEmpty() bool {
+	return len(plan.Changes) == 0
+}
+
+// String возвращает план изменений в читаемом виде, по одному изменению в строке.
+func (plan PermissionPlan) String() string {
+	if plan.IsEmpty() {
+		return "Изменений нет"
+	}
+	var sb strings.Builder
+	for _, change := range plan.Changes {
+		sb.WriteString(change.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// sort упорядочивает изменения: сначала изменения ролей, затем сотрудников,
+// внутри – по наименованию роли или логину сотрудника и праву.
+func (plan *PermissionPlan) sort() {
+	isRole := func(kind PermissionChangeKind) bool {
+		return kind == PermissionChangeRoleCreate || kind == PermissionChangeRoleUpdate
+	}
+	sort.SliceStable(plan.Changes, func(i, j int) bool {
+		a, b := plan.Changes[i], plan.Changes[j]
+		if isRole(a.Kind) != isRole(b.Kind) {
+			return isRole(a.Kind)
+		}
+		if ta, tb := strings.ToLower(a.Target), strings.ToLower(b.Target); ta != tb {
+			return ta < tb
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Key < b.Key
+	})
+}
+
+// PermissionManager сравнение ролей и прав сотрудников с декларативным описанием [PermissionSpec] и их изменение.
+//
+// Пример:
+//
+//	spec, err := moysklad.ParsePermissionSpec(file)
+//	manager := moysklad.NewPermissionManager(client)
+//	plan, err := manager.Plan(ctx, spec)
+//	fmt.Print(plan)
+//	err = manager.Apply(ctx, plan)
+type PermissionManager struct {
+	client *Client
+}
+
+// NewPermissionManager возвращает [PermissionManager].
+func NewPermissionManager(client *Client) *PermissionManager {
+	return &PermissionManager{client: client}
+}
+
+// Plan сравнивает текущие роли и права сотрудников с описанием spec и возвращает план изменений.
+func (manager *PermissionManager) Plan(ctx context.Context, spec *PermissionSpec) (*PermissionPlan, error) {
+	roleService := NewRoleService(manager.client)
+	roles, _, err := roleService.GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*Role)
+	for _, role := range Deref(roles) {
+		byName[strings.ToLower(role.GetName())] = role
+	}
+
+	plan := &PermissionPlan{employees: make(map[string]*EmployeePermission)}
+	specRoles := make(map[string]bool)
+	for _, roleSpec := range spec.Roles {
+		desired, err := roleSpec.normalize()
+		if err != nil {
+			return nil, err
+		}
+		specRoles[strings.ToLower(roleSpec.Name)] = true
+
+		role, exists := byName[strings.ToLower(roleSpec.Name)]
+		current := defaultPermissions()
+		if exists {
+			current = flattenPermissions(role.Permissions)
+		} else {
+			plan.Changes = append(plan.Changes, PermissionChange{Kind: PermissionChangeRoleCreate, Target: roleSpec.Name})
+		}
+
+		keys := make([]string, 0, len(desired))
+		for key := range desired {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		changed := make(map[string]any)
+		for _, key := range keys {
+			if reflect.DeepEqual(current[key], desired[key]) {
+				continue
+			}
+			changed[key] = desired[key]
+			plan.Changes = append(plan.Changes, PermissionChange{
+				Kind:   PermissionChangeRoleUpdate,
+				Target: roleSpec.Name,
+				Key:    key,
+				From:   current[key],
+				To:     desired[key],
+			})
+			current[key] = desired[key]
+		}
+		// у существующей роли изменяются только отличающиеся права
+		if exists {
+			if len(changed) > 0 {
+				plan.updates = append(plan.updates, roleUpdate{id: role.GetID(), name: roleSpec.Name, permissions: changed})
+			}
+			continue
+		}
+
+		permissions, err := unflattenPermissions(current)
+		if err != nil {
+			return nil, fmt.Errorf("permissions: role %q: %w", roleSpec.Name, err)
+		}
+		plan.roles = append(plan.roles, new(Role).SetName(roleSpec.Name).SetPermissions(permissions))
+	}
+
+	if len(spec.Employees) == 0 {
+		plan.sort()
+		return plan, nil
+	}
+
+	employees, _, err := NewEmployeeService(manager.client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byUID := make(map[string]*Employee)
+	for _, employee := range Deref(employees) {
+		byUID[strings.ToLower(employee.GetUID())] = employee
+	}
+
+	systemRoles, err := manager.systemRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleNames := make(map[string]string)
+	for name, meta := range systemRoles {
+		roleNames[meta.GetHref()] = name
+	}
+	for _, role := range Deref(roles) {
+		roleNames[role.GetMeta().GetHref()] = role.GetName()
+	}
+
+	for _, employeeSpec := range spec.Employees {
+		employee, ok := byUID[strings.ToLower(employeeSpec.UID)]
+		if !ok {
+			return nil, fmt.Errorf("permissions: employee %q not found", employeeSpec.UID)
+		}
+		current, _, err := NewEmployeeService(manager.client).GetPermissions(ctx, employee.GetID())
+		if err != nil {
+			return nil, err
+		}
+		currentRole := roleNames[current.GetRole().GetMeta().GetHref()]
+
+		update := &EmployeePermission{}
+		var changed bool
+		if employeeSpec.Role != "" && !strings.EqualFold(currentRole, employeeSpec.Role) {
+			meta, ok := systemRoles[strings.ToLower(employeeSpec.Role)]
+			if !ok {
+				role, exists := byName[strings.ToLower(employeeSpec.Role)]
+				if !exists && !specRoles[strings.ToLower(employeeSpec.Role)] {
+					return nil, fmt.Errorf("permissions: employee %q: role %q not found", employeeSpec.UID, employeeSpec.Role)
+				}
+				if exists {
+					meta = role.GetMeta()
+				}
+			}
+			// роль, которая будет создана, подставляется при применении плана
+			update.Role = &Role{Name: String(employeeSpec.Role)}
+			if meta.GetHref() != "" {
+				update.Role.Meta = &meta
+			}
+			changed = true
+			plan.Changes = append(plan.Changes, PermissionChange{
+				Kind:   PermissionChangeEmployeeRole,
+				Target: employee.GetUID(),
+				From:   currentRole,
+				To:     employeeSpec.Role,
+			})
+		}
+		if employeeSpec.Active != nil && *employeeSpec.Active != current.GetActive() {
+			update.IsActive = employeeSpec.Active
+			changed = true
+			plan.Changes = append(plan.Changes, PermissionChange{
+				Kind:   PermissionChangeEmployeeActive,
+				Target: employee.GetUID(),
+				From:   current.GetActive(),
+				To:     *employeeSpec.Active,
+			})
+		}
+		if changed {
+			plan.employees[employee.GetID()] = update
+		}
+	}
+	plan.sort()
+	return plan, nil
+}
+
+// Apply создаёт и изменяет роли, затем назначает роли сотрудникам согласно плану.
+func (manager *PermissionManager) Apply(ctx context.Context, plan *PermissionPlan) error {
+	roleService := NewRoleService(manager.client)
+	created := make(map[string]*Meta)
+	for _, role := range plan.roles {
+		result, _, err := roleService.Create(ctx, role)
+		if err != nil {
+			return fmt.Errorf("permissions: role %q: %w", role.GetName(), err)
+		}
+		created[strings.ToLower(result.GetName())] = result.Meta
+	}
+	for _, update := range plan.updates {
+		path := fmt.Sprintf("%s/%s", EndpointRole, update.id)
+		body := map[string]any{"permissions": nestPermissions(update.permissions)}
+		if _, _, err := NewRequestBuilder[Role](manager.client, path).Put(ctx, body); err != nil {
+			return fmt.Errorf("permissions: role %q: %w", update.name, err)
+		}
+	}
+
+	employeeService := NewEmployeeService(manager.client)
+	ids := make([]string, 0, len(plan.employees))
+	for id := range plan.employees {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		update := plan.employees[id]
+		if update.Role != nil {
+			if meta, ok := created[strings.ToLower(update.Role.GetName())]; ok && update.Role.Meta == nil {
+				update.Role.Meta = meta
+			}
+			if update.Role.Meta == nil {
+				return fmt.Errorf("permissions: employee %s: role %q not found", id, update.Role.GetName())
+			}
+			update.Role = update.Role.Clean()
+		}
+		if _, _, err := employeeService.UpdatePermissions(ctx, id, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// systemRoles возвращает метаданные системных ролей.
+func (manager *PermissionManager) systemRoles(ctx context.Context) (map[string]Meta, error) {
+	service := NewRoleService(manager.client)
+	admin, _, err := service.GetAdminRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	individual, _, err := service.GetIndividualRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cashier, _, err := service.GetCashierRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	worker, _, err := service.GetWorkerRole(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]Meta{
+		SystemRoleAdmin:      admin.Meta,
+		SystemRoleIndividual: individual.Meta,
+		SystemRoleCashier:    cashier.Meta,
+		SystemRoleWorker:     worker.Meta,
+	}, nil
+}
+
+// EmployeeAccess Права сотрудника с учётом назначенной роли.
+type EmployeeAccess struct {
+	Employee    *Employee           // Сотрудник
+	Active      bool                // Доступ к сервису МойСклад
+	Role        string              // Наименование роли (для системных ролей – admin, individual, cashier, worker)
+	Admin       bool                // Администратор имеет все права
+	Permissions EmployeePermissions // Права роли
+}
+
+// Value возвращает значение права permission (например, «demand.approve» или «viewAudit»).
+//
+// Для администратора возвращает [PermissionAll] или true.
+func (employeeAccess EmployeeAccess) Value(permission string) any {
+	value, ok := flattenPermissions(&employeeAccess.Permissions)[permission]
+	if !ok {
+		return nil
+	}
+	if employeeAccess.Admin {
+		if _, ok := value.(bool); ok {
+			return true
+		}
+		return string(PermissionAll)
+	}
+	return value
+}
+
+// Can возвращает true, если сотрудник имеет доступ к сервису и право permission предоставлено хотя бы частично.
+func (employeeAccess EmployeeAccess) Can(permission string) bool {
+	if !employeeAccess.Active {
+		return false
+	}
+	switch value := employeeAccess.Value(permission).(type) {
+	case bool:
+		return value
+	case string:
+		return value != "" && value != string(PermissionNo)
+	}
+	return false
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (employeeAccess EmployeeAccess) String() string {
+	return Stringify(employeeAccess)
+}
+
+// EffectivePermissions Действующие права сотрудников.
+type EffectivePermissions []*EmployeeAccess
+
+// Who возвращает сотрудников, которые могут выполнить действие action над сущностью entity,
+// например Who("demand", "approve") – кто может проводить отгрузки.
+//
+// Если action не указан, то entity считается наименованием права-флага (например, «viewAudit»).
+func (effectivePermissions EffectivePermissions) Who(entity, action string) []*EmployeeAccess {
+	permission := entity
+	if action != "" {
+		permission += "." + action
+	}
+	var result []*EmployeeAccess
+	for _, access := range effectivePermissions {
+		if access.Can(permission) {
+			result = append(result, access)
+		}
+	}
+	return result
+}
+
+// Effective возвращает действующие права всех сотрудников.
+//
+// Права пользовательских и индивидуальных ролей берутся из роли; у кассира и сотрудника производства
+// права на документы и справочники отсутствуют.
+func (manager *PermissionManager) Effective(ctx context.Context) (EffectivePermissions, error) {
+	roles, _, err := NewRoleService(manager.client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byHref := make(map[string]*Role)
+	for _, role := range Deref(roles) {
+		byHref[role.GetMeta().GetHref()] = role
+	}
+	systemRoles, err := manager.systemRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	service := NewEmployeeService(manager.client)
+	employees, _, err := service.GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result EffectivePermissions
+	for _, employee := range Deref(employees) {
+		permission, _, err := service.GetPermissions(ctx, employee.GetID())
+		if err != nil {
+			return nil, err
+		}
+		access := &EmployeeAccess{Employee: employee, Active: permission.GetActive()}
+
+		role := permission.GetRole()
+		href := role.GetMeta().GetHref()
+		for name, meta := range systemRoles {
+			if meta.GetHref() == href {
+				access.Role = name
+			}
+		}
+		switch {
+		case access.Role == SystemRoleAdmin:
+			access.Admin = true
+		case access.Role == SystemRoleIndividual:
+			access.Permissions = role.GetPermissions()
+		case access.Role == "":
+			if custom, ok := byHref[href]; ok {
+				access.Role = custom.GetName()
+				access.Permissions = custom.GetPermissions()
+			}
+		}
+		result = append(result, access)
+	}
+	return result, nil
+}
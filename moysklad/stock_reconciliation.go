@@ -0,0 +1,752 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-resty/resty/v2"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StockSource Источник остатка, с которым сравнивается расчётный остаток.
+//
+// Возможные значения:
+//   - StockSourceByStore        – отчёт "Остатки по складам" на конец периода ([ReportStockService.GetByStore])
+//   - StockSourceAll            – расширенный отчёт об остатках на конец периода ([ReportStockService.GetAll])
+//   - StockSourceCurrentByStore – текущие остатки по складам ([ReportStockService.GetCurrentByStore])
+//   - StockSourceCurrentAll     – текущие остатки без разбиения по складам ([ReportStockService.GetCurrentAll])
+type StockSource string
+
+const (
+	StockSourceByStore        StockSource = "stock/bystore"         // Остатки по складам
+	StockSourceAll            StockSource = "stock/all"             // Расширенный отчёт об остатках
+	StockSourceCurrentByStore StockSource = "stock/bystore/current" // Текущие остатки по складам
+	StockSourceCurrentAll     StockSource = "stock/all/current"     // Текущие остатки
+)
+
+// String реализует интерфейс [fmt.Stringer].
+func (stockSource StockSource) String() string {
+	return string(stockSource)
+}
+
+// stockReconciliationDocuments типы документов, изменение остатков по которым воспроизводится при сверке,
+// и знак изменения остатка на складе документа.
+//
+// Инвентаризация не изменяет остатки (для этого создаются Оприходование и Списание),
+// её расчётный остаток используется как контрольная точка.
+var stockReconciliationDocuments = []struct {
+	metaType MetaType
+	sign     float64
+}{
+	{MetaTypeEnter, 1},
+	{MetaTypeSupply, 1},
+	{MetaTypeSalesReturn, 1},
+	{MetaTypeRetailSalesReturn, 1},
+	{MetaTypeDemand, -1},
+	{MetaTypeLoss, -1},
+	{MetaTypeRetailDemand, -1},
+	{MetaTypePurchaseReturn, -1},
+	{MetaTypeMove, 0},
+	{MetaTypeInventory, 0},
+}
+
+// StockReconciliationConfig конфигурация сверки остатков.
+type StockReconciliationConfig struct {
+	// Начало периода.
+	//
+	// Начальные остатки берутся из отчёта "Остатки по складам" на эту дату.
+	// Если не указано, то документы воспроизводятся с начала учёта, а начальные остатки считаются нулевыми.
+	MomentFrom time.Time
+
+	// Конец периода.
+	//
+	// Если не указан, то используется текущий момент и расчётные остатки
+	// дополнительно сравниваются с текущими остатками.
+	MomentTo time.Time
+
+	// Склады, по которым выполняется сверка. Если не указаны, то сверка выполняется по всем складам.
+	Stores Slice[Store]
+
+	// Допустимое расхождение остатков. По умолчанию 0.0001.
+	Tolerance float64
+
+	// Для каждого расхождения запрашивать отчёт по документам номенклатуры ([ReportByOperationsService.GetStock])
+	// и определять документы, на которых расчётный остаток разошёлся с отчётом.
+	//
+	// Выполняет по одному запросу на каждую позицию с расхождением.
+	Explain bool
+}
+
+// StockMovement изменение остатка позиции документом.
+type StockMovement struct {
+	Operation Meta      // Метаданные документа
+	Name      string    // Номер документа
+	Moment    time.Time // Дата документа
+	Quantity  float64   // Изменение остатка по документу
+	Balance   float64   // Расчётный остаток после документа
+	Reported  *float64  // Остаток после документа по отчёту по документам номенклатуры (для Инвентаризации – расчётный остаток)
+	Replayed  bool      // Документ учтён при воспроизведении
+	Reason    string    // Причина, по которой документ считается источником расхождения
+}
+
+// StockDiscrepancy расхождение расчётного остатка с остатком по отчёту.
+type StockDiscrepancy struct {
+	Assortment Meta            // Метаданные Товара/Модификации
+	Store      *Meta           // Метаданные склада (nil для расхождения по всем складам)
+	Name       string          // Наименование Товара/Модификации
+	StoreName  string          // Наименование склада
+	Source     StockSource     // Отчёт, с которым выполнялось сравнение
+	Opening    float64         // Начальный остаток
+	Expected   float64         // Расчётный остаток
+	Reported   float64         // Остаток по отчёту
+	Movements  []StockMovement // Документы периода, изменившие остаток
+	Causes     []StockMovement // Документы, на которых расчётный остаток разошёлся с отчётом по документам номенклатуры
+}
+
+// Difference возвращает разницу между остатком по отчёту и расчётным остатком.
+func (stockDiscrepancy StockDiscrepancy) Difference() float64 {
+	return stockDiscrepancy.Reported - stockDiscrepancy.Expected
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (stockDiscrepancy StockDiscrepancy) String() string {
+	var sb strings.Builder
+
+	name := stockDiscrepancy.Name
+	if name == "" {
+		name = stockDiscrepancy.Assortment.GetUUIDFromHref()
+	}
+	store := "все склады"
+	if stockDiscrepancy.Store != nil {
+		store = stockDiscrepancy.StoreName
+		if store == "" {
+			store = stockDiscrepancy.Store.GetUUIDFromHref()
+		}
+	}
+
+	fmt.Fprintf(&sb, "%s [%s] %s: ожидается %g, в отчёте %g (разница %g)",
+		name, store, stockDiscrepancy.Source, stockDiscrepancy.Expected, stockDiscrepancy.Reported, stockDiscrepancy.Difference())
+
+	for _, cause := range stockDiscrepancy.Causes {
+		fmt.Fprintf(&sb, "\n\t%s %s %s (%+g): %s",
+			cause.Operation.GetType(), cause.Name, cause.Moment.Format(time.DateTime), cause.Quantity, cause.Reason)
+	}
+
+	return sb.String()
+}
+
+// StockReconciliationReport результат сверки остатков.
+type StockReconciliationReport struct {
+	MomentFrom    time.Time          // Начало периода
+	MomentTo      time.Time          // Конец периода
+	Documents     int                // Количество воспроизведённых документов
+	Positions     int                // Количество сверенных позиций (склад и Товар/Модификация)
+	Discrepancies []StockDiscrepancy // Расхождения
+}
+
+// HasDiscrepancies возвращает true, если найдены расхождения.
+func (report StockReconciliationReport) HasDiscrepancies() bool {
+	return len(report.Discrepancies) > 0
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (report StockReconciliationReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "документов: %d, позиций: %d, расхождений: %d", report.Documents, report.Positions, len(report.Discrepancies))
+	for _, discrepancy := range report.Discrepancies {
+		sb.WriteString("\n")
+		sb.WriteString(discrepancy.String())
+	}
+	return sb.String()
+}
+
+// StockReconciler сверка остатков.
+//
+// Вычисляет остатки по складам и номенклатуре, воспроизводя документы Оприходование, Приёмка, Отгрузка,
+// Перемещение, Списание, Инвентаризация, Розничная продажа и документы возвратов за период,
+// и сравнивает их с отчётами об остатках.
+//
+// Сверка выполняется с точностью до модификации: позиции серий относятся к их товару или модификации,
+// позиции комплектов раскладываются на компоненты, услуги не учитываются.
+// Остатки, изменённые документами других типов (например, Тех. операциями), попадают в расхождения
+// и при включённом Explain отображаются в списке документов-источников.
+type StockReconciler struct {
+	client       *Client
+	config       StockReconciliationConfig
+	stores       map[string]bool
+	components   map[string][]stockComponent
+	consignments map[string]Meta
+}
+
+// NewStockReconciler возвращает [StockReconciler].
+func NewStockReconciler(client *Client, config StockReconciliationConfig) *StockReconciler {
+	if config.Tolerance <= 0 {
+		config.Tolerance = 0.0001
+	}
+
+	reconciler := &StockReconciler{
+		client:       client,
+		config:       config,
+		stores:       make(map[string]bool),
+		components:   make(map[string][]stockComponent),
+		consignments: make(map[string]Meta),
+	}
+	for _, store := range config.Stores {
+		reconciler.stores[store.GetMeta().GetUUIDFromHref()] = true
+	}
+
+	return reconciler
+}
+
+// stockKey ключ остатка: ID склада и ID Товара/Модификации.
+type stockKey struct {
+	store      string
+	assortment string
+}
+
+// stockBalance расчётный остаток позиции.
+type stockBalance struct {
+	assortment Meta
+	store      Meta
+	storeName  string
+	opening    float64
+	expected   float64
+	movements  []StockMovement
+}
+
+// stockComponent компонент комплекта.
+type stockComponent struct {
+	assortment Meta
+	quantity   float64
+}
+
+// stockDocument документ, изменяющий остатки.
+type stockDocument struct {
+	Meta        Meta                             `json:"meta"`
+	Name        string                           `json:"name"`
+	Moment      Timestamp                        `json:"moment"`
+	Store       *MetaWrapper                     `json:"store"`
+	SourceStore *MetaWrapper                     `json:"sourceStore"`
+	TargetStore *MetaWrapper                     `json:"targetStore"`
+	Positions   MetaArray[stockDocumentPosition] `json:"positions"`
+}
+
+// stockDocumentPosition позиция документа, изменяющего остатки.
+type stockDocumentPosition struct {
+	Assortment         AssortmentPosition `json:"assortment"`
+	Quantity           float64            `json:"quantity"`
+	CalculatedQuantity *float64           `json:"calculatedQuantity"`
+}
+
+// stockListAll постранично получает все строки отчёта.
+func stockListAll[T any](ctx context.Context, fetch func(ctx context.Context, params ...func(*Params)) (*List[T], *resty.Response, error), params ...func(*Params)) (Slice[T], error) {
+	var rows Slice[T]
+	for offset := 0; ; offset += MaxPositions {
+		list, _, err := fetch(ctx, append(params, WithLimit(MaxPositions), WithOffset(offset))...)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, list.Rows...)
+
+		if list.Len() < MaxPositions || offset+MaxPositions >= list.Size() {
+			return rows, nil
+		}
+	}
+}
+
+// Run выполняет сверку остатков.
+func (reconciler *StockReconciler) Run(ctx context.Context) (*StockReconciliationReport, error) {
+	momentTo := reconciler.config.MomentTo
+	current := momentTo.IsZero()
+	if current {
+		momentTo = time.Now()
+	}
+
+	report := &StockReconciliationReport{MomentFrom: reconciler.config.MomentFrom, MomentTo: momentTo}
+	balances := make(map[stockKey]*stockBalance)
+	names := make(map[string]string)
+
+	// начальные остатки
+	if !reconciler.config.MomentFrom.IsZero() {
+		opening, err := reconciler.stockByStore(ctx, reconciler.config.MomentFrom)
+		if err != nil {
+			return nil, err
+		}
+		for key, position := range opening {
+			balance := reconciler.balance(balances, key, position.assortment, position.store)
+			balance.storeName = position.storeName
+			balance.opening = position.stock
+			balance.expected = position.stock
+		}
+	}
+
+	// воспроизведение документов
+	var documents []*stockDocument
+	for _, document := range stockReconciliationDocuments {
+		rows, err := reconciler.documents(ctx, document.metaType, momentTo)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, rows...)
+	}
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		return documents[i].Moment.Time().Before(documents[j].Moment.Time())
+	})
+
+	for _, document := range documents {
+		if err := reconciler.replay(ctx, balances, document); err != nil {
+			return nil, err
+		}
+	}
+	report.Documents = len(documents)
+
+	// остатки по складам на конец периода
+	reported, err := reconciler.stockByStore(ctx, momentTo)
+	if err != nil {
+		return nil, err
+	}
+	for key, position := range reported {
+		balance := reconciler.balance(balances, key, position.assortment, position.store)
+		if balance.storeName == "" {
+			balance.storeName = position.storeName
+		}
+	}
+
+	var currentByStore map[stockKey]float64
+	if current {
+		rows, _, err := NewReportStockService(reconciler.client).GetCurrentByStore(ctx)
+		if err != nil {
+			return nil, err
+		}
+		currentByStore = make(map[stockKey]float64)
+		for _, row := range Deref(rows) {
+			if !reconciler.storeAllowed(row.StoreID) {
+				continue
+			}
+			currentByStore[stockKey{row.StoreID, row.AssortmentID}] = row.Stock
+		}
+	}
+
+	// итоги по всем складам
+	all, err := stockListAll(ctx, NewReportStockService(reconciler.client).GetAll, reconciler.reportParams(momentTo)...)
+	if err != nil {
+		return nil, err
+	}
+	reportedAll := make(map[string]float64)
+	for _, row := range all {
+		id := row.Meta.GetUUIDFromHref()
+		names[id] = row.Name
+		reportedAll[id] += row.Stock
+	}
+
+	var currentAll map[string]float64
+	if current && len(reconciler.stores) == 0 {
+		rows, _, err := NewReportStockService(reconciler.client).GetCurrentAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		currentAll = make(map[string]float64)
+		for _, row := range Deref(rows) {
+			currentAll[row.AssortmentID] += row.Stock
+		}
+	}
+
+	// сравнение по складам
+	keys := make([]stockKey, 0, len(balances))
+	for key := range balances {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].assortment != keys[j].assortment {
+			return keys[i].assortment < keys[j].assortment
+		}
+		return keys[i].store < keys[j].store
+	})
+
+	expectedAll := make(map[string]float64)
+	assortments := make(map[string]Meta)
+	for _, key := range keys {
+		balance := balances[key]
+		expectedAll[key.assortment] += balance.expected
+		assortments[key.assortment] = balance.assortment
+
+		sources := []struct {
+			source StockSource
+			value  float64
+			ok     bool
+		}{
+			{StockSourceByStore, reported[key].stock, true},
+			{StockSourceCurrentByStore, currentByStore[key], currentByStore != nil},
+		}
+		for _, source := range sources {
+			if !source.ok || reconciler.equal(balance.expected, source.value) {
+				continue
+			}
+
+			store := balance.store
+			discrepancy := StockDiscrepancy{
+				Assortment: balance.assortment,
+				Store:      &store,
+				Name:       names[key.assortment],
+				StoreName:  balance.storeName,
+				Source:     source.source,
+				Opening:    balance.opening,
+				Expected:   balance.expected,
+				Reported:   source.value,
+				Movements:  balance.movements,
+			}
+
+			if reconciler.config.Explain && source.source == StockSourceByStore {
+				if discrepancy.Causes, err = reconciler.explain(ctx, balance, momentTo); err != nil {
+					return nil, err
+				}
+			}
+
+			report.Discrepancies = append(report.Discrepancies, discrepancy)
+		}
+	}
+	report.Positions = len(keys)
+
+	// сравнение итогов по всем складам
+	var ids []string
+	for id := range expectedAll {
+		ids = append(ids, id)
+	}
+	for id := range reportedAll {
+		if _, ok := expectedAll[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		sources := []struct {
+			source StockSource
+			value  float64
+			ok     bool
+		}{
+			{StockSourceAll, reportedAll[id], true},
+			{StockSourceCurrentAll, currentAll[id], currentAll != nil},
+		}
+		for _, source := range sources {
+			if !source.ok || reconciler.equal(expectedAll[id], source.value) {
+				continue
+			}
+
+			assortment, ok := assortments[id]
+			if !ok {
+				for _, row := range all {
+					if row.Meta.GetUUIDFromHref() == id {
+						assortment = row.Meta
+						break
+					}
+				}
+			}
+
+			report.Discrepancies = append(report.Discrepancies, StockDiscrepancy{
+				Assortment: assortment,
+				Name:       names[id],
+				Source:     source.source,
+				Expected:   expectedAll[id],
+				Reported:   source.value,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// equal сравнивает остатки с учётом допустимого расхождения.
+func (reconciler *StockReconciler) equal(l, r float64) bool {
+	return math.Abs(l-r) <= reconciler.config.Tolerance
+}
+
+// storeAllowed возвращает true, если склад участвует в сверке.
+func (reconciler *StockReconciler) storeAllowed(storeID string) bool {
+	return len(reconciler.stores) == 0 || reconciler.stores[storeID]
+}
+
+// balance возвращает расчётный остаток позиции, создавая его при необходимости.
+func (reconciler *StockReconciler) balance(balances map[stockKey]*stockBalance, key stockKey, assortment, store Meta) *stockBalance {
+	balance, ok := balances[key]
+	if !ok {
+		balance = &stockBalance{assortment: assortment, store: store}
+		balances[key] = balance
+	}
+	return balance
+}
+
+// reportParams возвращает параметры запроса отчёта об остатках на момент moment.
+func (reconciler *StockReconciler) reportParams(moment time.Time) []func(*Params) {
+	params := []func(*Params){
+		WithGroupByVariant(),
+		WithFilterEquals("moment", moment.Format(time.DateTime)),
+	}
+	for _, store := range reconciler.config.Stores {
+		params = append(params, WithFilterObject(store))
+	}
+	return params
+}
+
+type stockByStorePosition struct {
+	assortment Meta
+	store      Meta
+	storeName  string
+	stock      float64
+}
+
+// stockByStore возвращает остатки по складам на момент moment.
+func (reconciler *StockReconciler) stockByStore(ctx context.Context, moment time.Time) (map[stockKey]stockByStorePosition, error) {
+	rows, err := stockListAll(ctx, NewReportStockService(reconciler.client).GetByStore, reconciler.reportParams(moment)...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[stockKey]stockByStorePosition)
+	for _, row := range rows {
+		for _, position := range row.StockByStore {
+			storeID := position.Meta.GetUUIDFromHref()
+			if !reconciler.storeAllowed(storeID) {
+				continue
+			}
+			key := stockKey{storeID, row.Meta.GetUUIDFromHref()}
+			result[key] = stockByStorePosition{
+				assortment: row.Meta,
+				store:      position.Meta,
+				storeName:  position.Name,
+				stock:      position.Stock,
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// documents возвращает проведённые документы типа metaType за период.
+func (reconciler *StockReconciler) documents(ctx context.Context, metaType MetaType, momentTo time.Time) ([]*stockDocument, error) {
+	path := EndpointEntity + string(metaType)
+	params := []func(*Params){
+		WithExpand("positions"),
+		WithFilterEquals("applicable", "true"),
+		WithFilterLesserOrEquals("moment", momentTo.Format(time.DateTime)),
+	}
+	if !reconciler.config.MomentFrom.IsZero() {
+		params = append(params, WithFilterGreaterOrEquals("moment", reconciler.config.MomentFrom.Format(time.DateTime)))
+	}
+
+	rows, _, err := getAll[stockDocument](ctx, reconciler.client, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, document := range Deref(rows) {
+		// развёрнуты не все позиции документа
+		if document.Positions.Size() > document.Positions.Len() {
+			positionsPath := fmt.Sprintf(EndpointPositions, path, document.Meta.GetUUIDFromHref())
+			positions, _, err := getAll[stockDocumentPosition](ctx, reconciler.client, positionsPath, nil)
+			if err != nil {
+				return nil, err
+			}
+			document.Positions.Rows = Deref(positions)
+		}
+	}
+
+	return Deref(rows), nil
+}
+
+// replay применяет изменения остатков документа.
+func (reconciler *StockReconciler) replay(ctx context.Context, balances map[stockKey]*stockBalance, document *stockDocument) error {
+	type storeChange struct {
+		store *MetaWrapper
+		sign  float64
+	}
+
+	var changes []storeChange
+	metaType := document.Meta.GetType()
+	switch metaType {
+	case MetaTypeMove:
+		changes = []storeChange{{document.SourceStore, -1}, {document.TargetStore, 1}}
+	default:
+		for _, d := range stockReconciliationDocuments {
+			if d.metaType == metaType {
+				changes = []storeChange{{document.Store, d.sign}}
+				break
+			}
+		}
+	}
+
+	for _, position := range document.Positions.Rows {
+		components, err := reconciler.resolve(ctx, position.Assortment)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			if change.store == nil {
+				continue
+			}
+			storeID := change.store.Meta.GetUUIDFromHref()
+			if !reconciler.storeAllowed(storeID) {
+				continue
+			}
+
+			for _, component := range components {
+				key := stockKey{storeID, component.assortment.GetUUIDFromHref()}
+				balance := reconciler.balance(balances, key, component.assortment, change.store.Meta)
+
+				movement := StockMovement{
+					Operation: document.Meta,
+					Name:      document.Name,
+					Moment:    document.Moment.Time(),
+					Quantity:  change.sign * position.Quantity * component.quantity,
+					Replayed:  true,
+				}
+
+				// расчётный остаток инвентаризации – контрольная точка
+				if metaType == MetaTypeInventory && position.CalculatedQuantity != nil {
+					calculated := *position.CalculatedQuantity
+					movement.Reported = &calculated
+					if !reconciler.equal(calculated, balance.expected) {
+						movement.Reason = fmt.Sprintf("расчётный остаток инвентаризации %g не совпадает с воспроизведённым %g", calculated, balance.expected)
+					}
+				}
+
+				balance.expected += movement.Quantity
+				movement.Balance = balance.expected
+				balance.movements = append(balance.movements, movement)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolve возвращает Товары/Модификации, остатки которых изменяет позиция, и их количество на единицу позиции.
+func (reconciler *StockReconciler) resolve(ctx context.Context, assortment AssortmentPosition) ([]stockComponent, error) {
+	href := assortment.Meta.GetHref()
+
+	switch assortment.Meta.GetType() {
+	case MetaTypeService:
+		return nil, nil
+
+	case MetaTypeConsignment:
+		parent, ok := reconciler.consignments[href]
+		if !ok {
+			consignment, _, err := FetchMeta[Consignment](ctx, reconciler.client, assortment.Meta)
+			if err != nil {
+				return nil, err
+			}
+			parent = consignment.GetAssortment().Meta
+			reconciler.consignments[href] = parent
+		}
+		return []stockComponent{{parent, 1}}, nil
+
+	case MetaTypeBundle:
+		components, ok := reconciler.components[href]
+		if !ok {
+			bundle, _, err := FetchMeta[Bundle](ctx, reconciler.client, assortment.Meta, WithExpand("components"))
+			if err != nil {
+				return nil, err
+			}
+			for _, component := range bundle.GetComponents().Rows {
+				resolved, err := reconciler.resolve(ctx, component.GetAssortment())
+				if err != nil {
+					return nil, err
+				}
+				for _, c := range resolved {
+					components = append(components, stockComponent{c.assortment, c.quantity * component.GetQuantity()})
+				}
+			}
+			reconciler.components[href] = components
+		}
+		return components, nil
+	}
+
+	return []stockComponent{{assortment.Meta, 1}}, nil
+}
+
+// explain сопоставляет воспроизведённые документы позиции с отчётом по документам номенклатуры
+// и возвращает документы, на которых остатки разошлись.
+func (reconciler *StockReconciler) explain(ctx context.Context, balance *stockBalance, momentTo time.Time) ([]StockMovement, error) {
+	assortment := AssortmentPosition{Meta: balance.assortment}
+	service := NewReportByOperationsService(reconciler.client)
+	rows, err := stockListAll(ctx, func(ctx context.Context, params ...func(*Params)) (*List[ReportByOperationsStock], *resty.Response, error) {
+		return service.GetStock(ctx, assortment, params...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	storeID := balance.store.GetUUIDFromHref()
+	var operations []*ReportByOperationsStock
+	for _, row := range rows {
+		moment := row.Moment.Time()
+		if row.Store.Meta.GetUUIDFromHref() != storeID || moment.After(momentTo) ||
+			(!reconciler.config.MomentFrom.IsZero() && moment.Before(reconciler.config.MomentFrom)) {
+			continue
+		}
+		operations = append(operations, row)
+	}
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operations[i].Moment.Time().Before(operations[j].Moment.Time())
+	})
+
+	movements := make(map[string]int)
+	for i, movement := range balance.movements {
+		movements[movement.Operation.GetHref()] = i
+	}
+
+	var causes []StockMovement
+	seen := make(map[string]bool)
+	previous := balance.opening
+	for _, operation := range operations {
+		href := operation.Operation.Meta.GetHref()
+		reported := operation.Stock
+		delta := reported - previous
+		previous = reported
+		seen[href] = true
+
+		i, ok := movements[href]
+		if !ok {
+			causes = append(causes, StockMovement{
+				Operation: operation.Operation.Meta,
+				Moment:    operation.Moment.Time(),
+				Quantity:  delta,
+				Reported:  &reported,
+				Reason:    "документ изменяет остаток, но не учтён при воспроизведении",
+			})
+			continue
+		}
+
+		movement := balance.movements[i]
+		movement.Reported = &reported
+		balance.movements[i] = movement
+		if !reconciler.equal(movement.Quantity, delta) {
+			movement.Reason = fmt.Sprintf("изменение остатка по отчёту %g, по документу %g", delta, movement.Quantity)
+			causes = append(causes, movement)
+		}
+	}
+
+	for _, movement := range balance.movements {
+		if seen[movement.Operation.GetHref()] {
+			continue
+		}
+		switch {
+		case movement.Reason != "":
+			causes = append(causes, movement)
+		case movement.Operation.GetType() != MetaTypeInventory && !reconciler.equal(movement.Quantity, 0):
+			movement.Reason = "документ отсутствует в отчёте по документам номенклатуры"
+			causes = append(causes, movement)
+		}
+	}
+
+	sort.SliceStable(causes, func(i, j int) bool {
+		return causes[i].Moment.Before(causes[j].Moment)
+	})
+
+	return causes, nil
+}
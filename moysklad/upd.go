@@ -0,0 +1,927 @@
+package moysklad
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Версия формата и КНД универсального передаточного документа (приказ ФНС России от 19.12.2023 № ЕД-7-26/970@).
+//
+// УПД формируется в формате 5.03; [ParseUPD] также читает файлы предыдущего формата 5.01.
+const (
+	UPDFormatVersion = "5.03"
+	UPDKND           = "1115131"
+)
+
+// updFormatVersions версии формата, которые читает [ParseUPD].
+var updFormatVersions = []string{"5.01", UPDFormatVersion}
+
+// UPDStatus Статус УПД.
+//
+// Возможные значения:
+//   - UPDStatusInvoice  – счёт-фактура и передаточный документ (акт), функция СЧФДОП
+//   - UPDStatusTransfer – только передаточный документ (акт), функция ДОП
+type UPDStatus int
+
+const (
+	UPDStatusInvoice  UPDStatus = 1 // Счёт-фактура и передаточный документ (акт)
+	UPDStatusTransfer UPDStatus = 2 // Передаточный документ (акт)
+)
+
+// Функции и наименования документа в зависимости от статуса УПД.
+const (
+	updFunctionInvoice  = "СЧФДОП"
+	updFunctionTransfer = "ДОП"
+	updFactName         = "Документ об отгрузке товаров (выполнении работ), передаче имущественных прав (документ об оказании услуг)"
+	updDocNameInvoice   = "Счет-фактура и документ об отгрузке товаров (выполнении работ), передаче имущественных прав (документ об оказании услуг)"
+	updWithoutVat       = "без НДС"
+	updWithoutExcise    = "без акциза"
+	updSame             = "он же"
+	updRussia           = "643"
+	updRussiaName       = "Россия"
+	updRuble            = "643"
+	updRubleName        = "Российский рубль"
+)
+
+// UPD Универсальный передаточный документ (файл обмена формата ФНС).
+type UPD struct {
+	XMLName  xml.Name     `xml:"Файл"`
+	FileID   string       `xml:"ИдФайл,attr"`             // Идентификатор файла
+	Version  string       `xml:"ВерсФорм,attr"`           // Версия формата
+	Program  string       `xml:"ВерсПрог,attr,omitempty"` // Версия программы, с помощью которой сформирован файл
+	Exchange *UPDExchange `xml:"СвУчДокОбор,omitempty"`   // Сведения об участниках электронного документооборота
+	Document UPDDocument  `xml:"Документ"`                // Документ
+}
+
+// UPDExchange Сведения об участниках электронного документооборота.
+type UPDExchange struct {
+	SenderID    string       `xml:"ИдОтпр,attr"`         // Идентификатор отправителя
+	RecipientID string       `xml:"ИдПол,attr"`          // Идентификатор получателя
+	Operator    *UPDOperator `xml:"СвОЭДОтпр,omitempty"` // Сведения об операторе ЭДО отправителя
+}
+
+// UPDOperator Сведения об операторе электронного документооборота.
+type UPDOperator struct {
+	Name string `xml:"НаимОрг,attr"` // Наименование
+	INN  string `xml:"ИННЮЛ,attr"`   // ИНН
+	ID   string `xml:"ИдЭДО,attr"`   // Идентификатор оператора ЭДО
+}
+
+// UPDDocument Документ УПД.
+type UPDDocument struct {
+	KND      string      `xml:"КНД,attr"`                  // Код документа по КНД
+	Function string      `xml:"Функция,attr"`              // Функция документа (СЧФ, СЧФДОП, ДОП)
+	FactName string      `xml:"ПоФактХЖ,attr,omitempty"`   // Наименование первичного документа
+	DocName  string      `xml:"НаимДокОпр,attr,omitempty"` // Наименование документа, определённое организацией
+	Date     string      `xml:"ДатаИнфПр,attr"`            // Дата формирования файла (ДД.ММ.ГГГГ)
+	Time     string      `xml:"ВремИнфПр,attr"`            // Время формирования файла (ЧЧ.ММ.СС)
+	Economic string      `xml:"НаимЭконСубСост,attr"`      // Наименование экономического субъекта – составителя файла
+	Invoice  UPDInvoice  `xml:"СвСчФакт"`                  // Сведения о счёте-фактуре
+	Table    UPDTable    `xml:"ТаблСчФакт"`                // Таблица счёта-фактуры
+	Transfer UPDTransfer `xml:"СвПродПер"`                 // Сведения о передаче товаров
+	Signers  []UPDSigner `xml:"Подписант"`                 // Сведения о лице, подписывающем документ
+}
+
+// UPDInvoice Сведения о счёте-фактуре.
+type UPDInvoice struct {
+	Number      string           `xml:"НомерДок,attr"`           // Номер документа
+	Date        string           `xml:"ДатаДок,attr"`            // Дата документа (ДД.ММ.ГГГГ)
+	NumberV501  string           `xml:"НомерСчФ,attr,omitempty"` // Номер документа (формат 5.01)
+	DateV501    string           `xml:"ДатаСчФ,attr,omitempty"`  // Дата документа (формат 5.01)
+	Seller      UPDParticipant   `xml:"СвПрод"`                  // Сведения о продавце
+	Shipper     *UPDShipper      `xml:"ГрузОт,omitempty"`        // Сведения о грузоотправителе
+	Consignee   *UPDParticipant  `xml:"ГрузПолуч,omitempty"`     // Сведения о грузополучателе
+	PaymentDocs []UPDPaymentDoc  `xml:"СвПРД,omitempty"`         // Сведения о платёжно-расчётных документах
+	Buyer       UPDParticipant   `xml:"СвПокуп"`                 // Сведения о покупателе
+	Currency    UPDCurrency      `xml:"ДенИзм"`                  // Денежное измерение
+	Extra       *UPDInvoiceExtra `xml:"ДопСвФХЖ1,omitempty"`     // Дополнительные сведения
+}
+
+// GetNumber возвращает номер документа (в том числе из файла формата 5.01).
+func (invoice UPDInvoice) GetNumber() string {
+	return firstNonEmpty(invoice.Number, invoice.NumberV501)
+}
+
+// GetDate возвращает дату документа в формате ДД.ММ.ГГГГ (в том числе из файла формата 5.01).
+func (invoice UPDInvoice) GetDate() string {
+	return firstNonEmpty(invoice.Date, invoice.DateV501)
+}
+
+// UPDCurrency Денежное измерение.
+type UPDCurrency struct {
+	Code string `xml:"КодОКВ,attr"`  // Код валюты по ОКВ
+	Name string `xml:"НаимОКВ,attr"` // Наименование валюты
+}
+
+// UPDShipper Сведения о грузоотправителе.
+type UPDShipper struct {
+	Same    string          `xml:"ОнЖе,omitempty"`     // Грузоотправитель – он же продавец
+	Shipper *UPDParticipant `xml:"ГрузОтпр,omitempty"` // Грузоотправитель
+}
+
+// UPDPaymentDoc Сведения о платёжно-расчётном документе.
+type UPDPaymentDoc struct {
+	Number string `xml:"НомерПРД,attr"` // Номер документа
+	Date   string `xml:"ДатаПРД,attr"`  // Дата документа (ДД.ММ.ГГГГ)
+}
+
+// UPDInvoiceExtra Дополнительные сведения об участниках факта хозяйственной жизни.
+type UPDInvoiceExtra struct {
+	StateContractID string `xml:"ИдГосКон,attr,omitempty"` // Идентификатор государственного контракта
+}
+
+// UPDParticipant Сведения об участнике (продавце, покупателе, грузоотправителе, грузополучателе).
+type UPDParticipant struct {
+	OKPO    string           `xml:"ОКПО,attr,omitempty"` // Код по ОКПО
+	ID      UPDParticipantID `xml:"ИдСв"`                // Идентификационные сведения
+	Address *UPDAddress      `xml:"Адрес,omitempty"`     // Адрес
+}
+
+// UPDParticipantID Идентификационные сведения участника.
+type UPDParticipantID struct {
+	Legal        *UPDLegal        `xml:"СвЮЛУч,omitempty"` // Сведения о юридическом лице
+	Entrepreneur *UPDEntrepreneur `xml:"СвИП,omitempty"`   // Сведения об индивидуальном предпринимателе
+}
+
+// UPDLegal Сведения о юридическом лице.
+type UPDLegal struct {
+	Name string `xml:"НаимОрг,attr"`       // Наименование
+	INN  string `xml:"ИННЮЛ,attr"`         // ИНН
+	KPP  string `xml:"КПП,attr,omitempty"` // КПП
+}
+
+// UPDEntrepreneur Сведения об индивидуальном предпринимателе.
+type UPDEntrepreneur struct {
+	INN          string      `xml:"ИННФЛ,attr"`                // ИНН
+	Registration string      `xml:"СвГосРегИП,attr,omitempty"` // Реквизиты свидетельства о государственной регистрации
+	Name         UPDFullName `xml:"ФИО"`                       // ФИО
+}
+
+// UPDFullName Фамилия, имя, отчество.
+type UPDFullName struct {
+	LastName   string `xml:"Фамилия,attr"`            // Фамилия
+	FirstName  string `xml:"Имя,attr"`                // Имя
+	MiddleName string `xml:"Отчество,attr,omitempty"` // Отчество
+}
+
+// UPDAddress Адрес.
+//
+// Адрес по ГАР (код или структурированный адрес) не формируется, так как МойСклад не хранит коды ГАР.
+type UPDAddress struct {
+	Info *UPDAddressInfo `xml:"АдрИнф,omitempty"` // Адрес в виде текста с кодом страны
+}
+
+// UPDAddressInfo Адрес в виде текста с кодом страны.
+type UPDAddressInfo struct {
+	CountryCode string `xml:"КодСтр,attr"`              // Код страны по ОКСМ
+	CountryName string `xml:"НаимСтран,attr,omitempty"` // Наименование страны
+	Text        string `xml:"АдрТекст,attr"`            // Адрес
+}
+
+// String возвращает адрес одной строкой.
+func (address UPDAddress) String() string {
+	if address.Info != nil {
+		return address.Info.Text
+	}
+	return ""
+}
+
+// UPDTable Таблица счёта-фактуры.
+type UPDTable struct {
+	Items []UPDItem `xml:"СведТов"`  // Сведения о товарах
+	Total UPDTotal  `xml:"ВсегоОпл"` // Итого к оплате
+}
+
+// UPDItem Сведения о товаре (работе, услуге).
+type UPDItem struct {
+	Line          int           `xml:"НомСтр,attr"`                // Номер строки
+	Name          string        `xml:"НаимТов,attr"`               // Наименование
+	OKEI          string        `xml:"ОКЕИ_Тов,attr,omitempty"`    // Код единицы измерения по ОКЕИ
+	UomName       string        `xml:"НаимЕдИзм,attr,omitempty"`   // Наименование единицы измерения
+	Quantity      string        `xml:"КолТов,attr,omitempty"`      // Количество
+	Price         string        `xml:"ЦенаТов,attr,omitempty"`     // Цена за единицу без НДС
+	SumWithoutVat string        `xml:"СтТовБезНДС,attr,omitempty"` // Стоимость без НДС
+	VatRate       string        `xml:"НалСт,attr"`                 // Налоговая ставка
+	SumWithVat    string        `xml:"СтТовУчНал,attr"`            // Стоимость с НДС
+	Excise        UPDExcise     `xml:"Акциз"`                      // Сумма акциза
+	Vat           UPDVat        `xml:"СумНал"`                     // Сумма НДС
+	Customs       []UPDCustoms  `xml:"СвТД,omitempty"`             // Сведения о таможенной декларации
+	Extra         *UPDItemExtra `xml:"ДопСведТов,omitempty"`       // Дополнительные сведения о товаре
+}
+
+// UPDExcise Сумма акциза.
+type UPDExcise struct {
+	Sum  string `xml:"СумАкциз,omitempty"` // Сумма акциза
+	None string `xml:"БезАкциз,omitempty"` // Без акциза
+}
+
+// UPDVat Сумма НДС.
+type UPDVat struct {
+	Sum  string `xml:"СумНал,omitempty"` // Сумма НДС
+	None string `xml:"БезНДС,omitempty"` // Без НДС
+}
+
+// UPDCustoms Сведения о таможенной декларации.
+type UPDCustoms struct {
+	CountryCode string `xml:"КодПроисх,attr"`         // Код страны происхождения по ОКСМ
+	Number      string `xml:"НомерТД,attr,omitempty"` // Регистрационный номер таможенной декларации
+}
+
+// UPDItemExtra Дополнительные сведения о товаре.
+type UPDItemExtra struct {
+	Kind           string              `xml:"ПрТовРаб,attr,omitempty"`    // Признак: 1 – имущество, 2 – работа, 3 – услуга
+	Article        string              `xml:"АртикулТов,attr,omitempty"`  // Артикул
+	Code           string              `xml:"КодТов,attr,omitempty"`      // Код товара
+	CountryName    string              `xml:"КрНаимСтрПр,attr,omitempty"` // Краткое наименование страны происхождения
+	Identification []UPDIdentification `xml:"НомСредИдентТов,omitempty"`  // Номера средств идентификации товара
+}
+
+// UPDIdentification Номера средств идентификации товара.
+type UPDIdentification struct {
+	Codes []string `xml:"КИЗ,omitempty"`     // Коды маркировки единиц товара
+	Packs []string `xml:"НомУпак,omitempty"` // Коды упаковок
+}
+
+// UPDTotal Итого к оплате.
+type UPDTotal struct {
+	SumWithoutVat string `xml:"СтТовБезНДСВсего,attr"` // Стоимость без НДС
+	SumWithVat    string `xml:"СтТовУчНалВсего,attr"`  // Стоимость с НДС
+	Vat           UPDVat `xml:"СумНалВсего"`           // Сумма НДС
+}
+
+// UPDTransfer Сведения о передаче товаров.
+type UPDTransfer struct {
+	Info UPDTransferInfo `xml:"СвПер"` // Сведения о передаче
+}
+
+// UPDTransferInfo Сведения о передаче.
+type UPDTransferInfo struct {
+	Operation string     `xml:"СодОпер,attr"`           // Содержание операции
+	Date      string     `xml:"ДатаПер,attr,omitempty"` // Дата отгрузки (ДД.ММ.ГГГГ)
+	Bases     []UPDBasis `xml:"ОснПер"`                 // Основание передачи
+}
+
+// UPDBasis Основание передачи.
+type UPDBasis struct {
+	Name   string `xml:"РеквНаимДок,attr"`            // Наименование документа-основания
+	Number string `xml:"РеквНомерДок,attr,omitempty"` // Номер документа-основания
+	Date   string `xml:"РеквДатаДок,attr,omitempty"`  // Дата документа-основания (ДД.ММ.ГГГГ)
+}
+
+// Тип подписи и способ подтверждения полномочий подписанта.
+const (
+	UPDSignatureQualified     = "1" // Усиленная квалифицированная электронная подпись
+	UPDAuthoritySignatureData = "1" // Полномочия подтверждаются данными электронной подписи
+)
+
+// UPDSigner Сведения о лице, подписывающем документ.
+type UPDSigner struct {
+	Position      string      `xml:"Должн,attr,omitempty"` // Должность
+	SignatureType string      `xml:"ТипПодпис,attr"`       // Тип подписи
+	Authority     string      `xml:"СпосПодтПолном,attr"`  // Способ подтверждения полномочий
+	FullName      UPDFullName `xml:"ФИО"`                  // ФИО
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (upd UPD) String() string {
+	return Stringify(upd)
+}
+
+// FileName возвращает имя файла УПД.
+func (upd UPD) FileName() string {
+	return upd.FileID + ".xml"
+}
+
+// Status возвращает статус УПД по функции документа.
+func (upd UPD) Status() UPDStatus {
+	if upd.Document.Function == updFunctionTransfer {
+		return UPDStatusTransfer
+	}
+	return UPDStatusInvoice
+}
+
+// WriteTo записывает УПД в формате XML в кодировке windows-1251.
+//
+// Реализует интерфейс [io.WriterTo].
+func (upd UPD) WriteTo(w io.Writer) (int64, error) {
+	data, err := xml.MarshalIndent(upd, "", "\t")
+	if err != nil {
+		return 0, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="windows-1251"?>` + "\n")
+	buf.Write(encodeCP1251XML(string(data)))
+	return buf.WriteTo(w)
+}
+
+// ParseUPD читает УПД в формате XML (в кодировке windows-1251 или UTF-8).
+func ParseUPD(r io.Reader) (*UPD, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = xmlCharsetReader
+
+	var upd UPD
+	if err := decoder.Decode(&upd); err != nil {
+		return nil, fmt.Errorf("upd: %w", err)
+	}
+	if upd.Document.KND != UPDKND {
+		return nil, fmt.Errorf("upd: unsupported document KND %q", upd.Document.KND)
+	}
+	if !slices.Contains(updFormatVersions, upd.Version) {
+		return nil, fmt.Errorf("upd: unsupported format version %q", upd.Version)
+	}
+	return &upd, nil
+}
+
+// UPDPerson ФИО и должность подписанта.
+type UPDPerson struct {
+	LastName   string // Фамилия
+	FirstName  string // Имя
+	MiddleName string // Отчество
+	Position   string // Должность
+}
+
+// UPDConfig конфигурация формирования УПД.
+type UPDConfig struct {
+	// Статус УПД (по умолчанию [UPDStatusInvoice]).
+	Status UPDStatus
+
+	// Идентификаторы участников электронного документооборота (отправителя и получателя).
+	SenderID    string
+	RecipientID string
+
+	// Оператор электронного документооборота отправителя (необязательно).
+	Operator *UPDOperator
+
+	// Подписант. Если не указан, то используется руководитель юрлица ([Organization.Director], [Organization.DirectorPosition]).
+	Signer *UPDPerson
+
+	// Наименование доп. поля товара, содержащего номер таможенной декларации.
+	GTDAttribute string
+
+	// Содержание операции (по умолчанию "Товары переданы").
+	Operation string
+}
+
+// UPDSourcePosition Позиция отгрузки с данными, необходимыми для формирования УПД.
+type UPDSourcePosition struct {
+	Position *DemandPosition // Позиция Отгрузки
+	Name     string          // Наименование товара (услуги)
+	Article  string          // Артикул
+	Code     string          // Код
+	Service  bool            // Признак услуги
+	Uom      *Uom            // Единица измерения (по умолчанию штука, код 796)
+	Country  *Country        // Страна происхождения
+	GTD      string          // Номер таможенной декларации
+}
+
+// UPDSource Данные для формирования УПД.
+type UPDSource struct {
+	Demand     *Demand             // Отгрузка
+	FactureOut *FactureOut         // Счёт-фактура выданный (обязателен для статуса 1)
+	Seller     *Organization       // Юрлицо – продавец
+	Buyer      *Agent              // Покупатель (контрагент или юрлицо)
+	Consignee  *Agent              // Грузополучатель (если отличается от покупателя)
+	Positions  []UPDSourcePosition // Позиции
+}
+
+// UPDRequisitesError ошибка формирования УПД из-за незаполненных реквизитов.
+type UPDRequisitesError struct {
+	Missing []string // Описание незаполненных реквизитов
+}
+
+// Error реализует интерфейс error.
+func (err UPDRequisitesError) Error() string {
+	return "upd: missing requisites: " + strings.Join(err.Missing, "; ")
+}
+
+// updParty реквизиты участника, общие для юрлица и контрагента.
+type updParty struct {
+	companyType CompanyType
+	name        string
+	inn         string
+	kpp         string
+	okpo        string
+	ogrnip      string
+	fullName    UPDFullName
+	address     string
+}
+
+// newUPDParty возвращает реквизиты юрлица или контрагента.
+func newUPDParty(agent *Agent) *updParty {
+	if agent == nil {
+		return nil
+	}
+	switch {
+	case agent.IsOrganization():
+		return newUPDPartyOrganization(agent.AsOrganization())
+	case agent.IsCounterparty():
+		counterparty := agent.AsCounterparty()
+		if counterparty == nil {
+			return nil
+		}
+		return &updParty{
+			companyType: counterparty.CompanyType,
+			name:        firstNonEmpty(counterparty.GetLegalTitle(), counterparty.GetName()),
+			inn:         counterparty.GetINN(),
+			kpp:         counterparty.GetKPP(),
+			okpo:        counterparty.GetOKPO(),
+			ogrnip:      counterparty.GetOGRNIP(),
+			fullName:    UPDFullName{counterparty.GetLegalLastName(), counterparty.GetLegalFirstName(), counterparty.GetLegalMiddleName()},
+			address:     updAddressText(counterparty.GetLegalAddress(), counterparty.LegalAddressFull),
+		}
+	}
+	return nil
+}
+
+// newUPDPartyOrganization возвращает реквизиты юрлица.
+func newUPDPartyOrganization(organization *Organization) *updParty {
+	if organization == nil {
+		return nil
+	}
+	return &updParty{
+		companyType: organization.CompanyType,
+		name:        firstNonEmpty(organization.GetLegalTitle(), organization.GetName()),
+		inn:         organization.GetINN(),
+		kpp:         organization.GetKPP(),
+		okpo:        organization.GetOKPO(),
+		ogrnip:      organization.GetOGRNIP(),
+		fullName:    UPDFullName{organization.GetLegalLastName(), organization.GetLegalFirstName(), organization.GetLegalMiddleName()},
+		address:     updAddressText(organization.GetLegalAddress(), organization.LegalAddressFull),
+	}
+}
+
+// updAddressText возвращает адрес одной строкой.
+func updAddressText(address string, full *Address) string {
+	if address != "" || full == nil {
+		return address
+	}
+	return joinNonEmpty(", ", full.GetPostalCode(), full.GetCity(), full.GetStreet(), full.GetHouse(), full.GetApartment())
+}
+
+// participant возвращает сведения об участнике, добавляя в missing незаполненные реквизиты.
+func (party *updParty) participant(role string, missing *[]string) UPDParticipant {
+	participant := UPDParticipant{OKPO: party.okpo}
+
+	if party.inn == "" {
+		*missing = append(*missing, role+": ИНН")
+	}
+	if party.address == "" {
+		*missing = append(*missing, role+": юридический адрес")
+	} else {
+		participant.Address = &UPDAddress{Info: &UPDAddressInfo{CountryCode: updRussia, CountryName: updRussiaName, Text: party.address}}
+	}
+
+	switch party.companyType {
+	case CompanyEntrepreneur:
+		if party.fullName.LastName == "" || party.fullName.FirstName == "" {
+			*missing = append(*missing, role+": ФИО индивидуального предпринимателя")
+		}
+		participant.ID.Entrepreneur = &UPDEntrepreneur{INN: party.inn, Name: party.fullName}
+		if party.ogrnip != "" {
+			participant.ID.Entrepreneur.Registration = "ОГРНИП " + party.ogrnip
+		}
+	case CompanyIndividual:
+		*missing = append(*missing, role+": физическое лицо не может быть участником УПД")
+	default:
+		if party.kpp == "" {
+			*missing = append(*missing, role+": КПП")
+		}
+		if party.name == "" {
+			*missing = append(*missing, role+": наименование")
+		}
+		participant.ID.Legal = &UPDLegal{Name: party.name, INN: party.inn, KPP: party.kpp}
+	}
+	return participant
+}
+
+// economic возвращает наименование экономического субъекта.
+func (party *updParty) economic() string {
+	name := party.name
+	if party.companyType == CompanyEntrepreneur {
+		name = "ИП " + joinNonEmpty(" ", party.fullName.LastName, party.fullName.FirstName, party.fullName.MiddleName)
+	}
+	if party.kpp != "" {
+		return fmt.Sprintf("%s, ИНН/КПП %s/%s", name, party.inn, party.kpp)
+	}
+	return fmt.Sprintf("%s, ИНН %s", name, party.inn)
+}
+
+// updPositionSums вычисляет стоимость позиции без НДС, сумму НДС и стоимость с НДС в копейках.
+func updPositionSums(position *DemandPosition, vatEnabled, vatIncluded bool) (float64, float64, float64) {
+	sum := math.Round(position.GetPrice() * position.GetQuantity() * (1 - position.GetDiscount()/100))
+	rate := float64(position.GetVat())
+	if !vatEnabled || !position.GetVatEnabled() || rate == 0 {
+		return sum, 0, sum
+	}
+	if vatIncluded {
+		vat := math.Round(sum * rate / (100 + rate))
+		return sum - vat, vat, sum
+	}
+	vat := math.Round(sum * rate / 100)
+	return sum, vat, sum + vat
+}
+
+// NewUPD формирует УПД по данным отгрузки.
+//
+// Если не заполнены обязательные реквизиты, то возвращает [UPDRequisitesError] со списком всех недостающих реквизитов.
+func NewUPD(source UPDSource, config UPDConfig) (*UPD, error) {
+	if config.Status == 0 {
+		config.Status = UPDStatusInvoice
+	}
+	if config.Operation == "" {
+		config.Operation = "Товары переданы"
+	}
+
+	var missing []string
+	if source.Demand == nil {
+		return nil, fmt.Errorf("upd: demand is required")
+	}
+	demand := source.Demand
+
+	if config.SenderID == "" || config.RecipientID == "" {
+		missing = append(missing, "идентификаторы участников ЭДО (отправителя и получателя)")
+	}
+
+	seller := newUPDPartyOrganization(source.Seller)
+	buyer := newUPDParty(source.Buyer)
+	if seller == nil {
+		return nil, fmt.Errorf("upd: seller organization is required")
+	}
+	if buyer == nil {
+		return nil, fmt.Errorf("upd: buyer counterparty is required")
+	}
+
+	now := time.Now()
+	upd := &UPD{
+		FileID:   fmt.Sprintf("ON_NSCHFDOPPR_%s_%s_%s_%s", config.RecipientID, config.SenderID, now.Format("20060102"), newUUID()),
+		Version:  UPDFormatVersion,
+		Program:  "go-moysklad",
+		Exchange: &UPDExchange{SenderID: config.SenderID, RecipientID: config.RecipientID, Operator: config.Operator},
+		Document: UPDDocument{
+			KND:      UPDKND,
+			Function: updFunctionInvoice,
+			FactName: updFactName,
+			DocName:  updDocNameInvoice,
+			Date:     now.Format("02.01.2006"),
+			Time:     now.Format("15.04.05"),
+			Economic: seller.economic(),
+		},
+	}
+	document := &upd.Document
+
+	// номер и дата: счёт-фактура для статуса 1, отгрузка для статуса 2
+	number, date := demand.GetName(), demand.GetMoment()
+	if config.Status == UPDStatusTransfer {
+		document.Function, document.DocName = updFunctionTransfer, updFactName
+	} else if source.FactureOut == nil {
+		missing = append(missing, "счёт-фактура выданный")
+	} else {
+		number, date = source.FactureOut.GetName(), source.FactureOut.GetMoment()
+		if paymentNumber := source.FactureOut.GetPaymentNumber(); paymentNumber != "" {
+			document.Invoice.PaymentDocs = append(document.Invoice.PaymentDocs, UPDPaymentDoc{
+				Number: paymentNumber,
+				Date:   source.FactureOut.GetPaymentDate().Format("02.01.2006"),
+			})
+		}
+	}
+	if number == "" {
+		missing = append(missing, "номер документа")
+	}
+
+	document.Invoice.Number = number
+	document.Invoice.Date = date.Format("02.01.2006")
+	document.Invoice.Currency = UPDCurrency{Code: updRuble, Name: updRubleName}
+	document.Invoice.Seller = seller.participant("продавец", &missing)
+	document.Invoice.Buyer = buyer.participant("покупатель", &missing)
+	document.Invoice.Shipper = &UPDShipper{Same: updSame}
+	if consignee := newUPDParty(source.Consignee); consignee != nil {
+		participant := consignee.participant("грузополучатель", &missing)
+		document.Invoice.Consignee = &participant
+	} else {
+		participant := buyer.participant("грузополучатель", new([]string))
+		document.Invoice.Consignee = &participant
+	}
+	if stateContractID := demand.GetStateContractID(); stateContractID != "" {
+		document.Invoice.Extra = &UPDInvoiceExtra{StateContractID: stateContractID}
+	}
+
+	// позиции
+	if len(source.Positions) == 0 {
+		missing = append(missing, "позиции отгрузки")
+	}
+	var totalWithout, totalVat, totalWith float64
+	var vatApplied bool
+	for i, item := range source.Positions {
+		line := i + 1
+		position := item.Position
+		without, vat, with := updPositionSums(position, demand.GetVatEnabled(), demand.GetVatIncluded())
+		totalWithout, totalVat, totalWith = totalWithout+without, totalVat+vat, totalWith+with
+
+		if item.Name == "" {
+			missing = append(missing, fmt.Sprintf("позиция %d: наименование", line))
+		}
+
+		okei, uomName := "796", "шт"
+		if item.Uom != nil {
+			okei, uomName = item.Uom.GetCode(), item.Uom.GetName()
+			if okei == "" {
+				missing = append(missing, fmt.Sprintf("позиция %d: код ОКЕИ единицы измерения %q", line, uomName))
+			}
+		}
+
+		updItem := UPDItem{
+			Line:          line,
+			Name:          item.Name,
+			OKEI:          okei,
+			UomName:       uomName,
+			Quantity:      strconv.FormatFloat(position.GetQuantity(), 'f', -1, 64),
+			SumWithoutVat: updAmount(without),
+			SumWithVat:    updAmount(with),
+			Excise:        UPDExcise{None: updWithoutExcise},
+			Extra:         &UPDItemExtra{Kind: "1", Article: item.Article, Code: item.Code},
+		}
+		if quantity := position.GetQuantity(); quantity != 0 {
+			updItem.Price = strconv.FormatFloat(math.Round(without/quantity)/100, 'f', 2, 64)
+		}
+		if item.Service {
+			updItem.Extra.Kind = "3"
+		}
+
+		if demand.GetVatEnabled() && position.GetVatEnabled() {
+			vatApplied = true
+			updItem.VatRate = fmt.Sprintf("%d%%", position.GetVat())
+			updItem.Vat.Sum = updAmount(vat)
+		} else {
+			updItem.VatRate = updWithoutVat
+			updItem.Vat.None = updWithoutVat
+		}
+
+		if item.Country != nil {
+			updItem.Extra.CountryName = item.Country.GetName()
+		}
+		if item.GTD != "" {
+			if item.Country == nil || item.Country.GetCode() == "" {
+				missing = append(missing, fmt.Sprintf("позиция %d: код страны происхождения для ГТД %s", line, item.GTD))
+			} else {
+				updItem.Customs = append(updItem.Customs, UPDCustoms{CountryCode: item.Country.GetCode(), Number: item.GTD})
+			}
+		}
+
+		var identification UPDIdentification
+		for _, trackingCode := range position.TrackingCodes {
+			if trackingCode.Type == TrackingCodeTypeTrackingCode || trackingCode.Type == "" {
+				identification.Codes = append(identification.Codes, trackingCode.GetCis())
+			} else {
+				identification.Packs = append(identification.Packs, trackingCode.GetCis())
+			}
+		}
+		if len(identification.Codes) > 0 {
+			updItem.Extra.Identification = append(updItem.Extra.Identification, UPDIdentification{Codes: identification.Codes})
+		}
+		if len(identification.Packs) > 0 {
+			updItem.Extra.Identification = append(updItem.Extra.Identification, UPDIdentification{Packs: identification.Packs})
+		}
+
+		document.Table.Items = append(document.Table.Items, updItem)
+	}
+
+	document.Table.Total = UPDTotal{SumWithoutVat: updAmount(totalWithout), SumWithVat: updAmount(totalWith)}
+	if vatApplied {
+		document.Table.Total.Vat.Sum = updAmount(totalVat)
+	} else {
+		document.Table.Total.Vat.None = updWithoutVat
+	}
+
+	// сведения о передаче
+	transfer := UPDTransferInfo{Operation: config.Operation, Date: demand.GetMoment().Format("02.01.2006")}
+	if contract := Deref(demand.Contract).getValue(); contract.GetName() != "" {
+		transfer.Bases = append(transfer.Bases, UPDBasis{Name: "Договор", Number: contract.GetName(), Date: contract.GetMoment().Format("02.01.2006")})
+	} else {
+		transfer.Bases = append(transfer.Bases, UPDBasis{Name: "Без документа-основания"})
+	}
+	document.Transfer.Info = transfer
+
+	// подписант
+	signer := UPDSigner{SignatureType: UPDSignatureQualified, Authority: UPDAuthoritySignatureData}
+	if seller.companyType == CompanyEntrepreneur {
+		signer.Position = "Индивидуальный предприниматель"
+		signer.FullName = seller.fullName
+	} else {
+		person := config.Signer
+		if person == nil {
+			person = updDirector(source.Seller)
+		}
+		if person.LastName == "" || person.FirstName == "" {
+			missing = append(missing, "подписант: ФИО (руководитель юрлица)")
+		}
+		if person.Position == "" {
+			missing = append(missing, "подписант: должность")
+		}
+		signer.Position = person.Position
+		signer.FullName = UPDFullName{person.LastName, person.FirstName, person.MiddleName}
+	}
+	document.Signers = append(document.Signers, signer)
+
+	if len(missing) > 0 {
+		return nil, &UPDRequisitesError{Missing: missing}
+	}
+	return upd, nil
+}
+
+// updDirector возвращает ФИО и должность руководителя юрлица.
+func updDirector(organization *Organization) *UPDPerson {
+	person := &UPDPerson{Position: organization.GetDirectorPosition()}
+	names := strings.Fields(organization.GetDirector())
+	if len(names) > 0 {
+		person.LastName = names[0]
+	}
+	if len(names) > 1 {
+		person.FirstName = names[1]
+	}
+	if len(names) > 2 {
+		person.MiddleName = strings.Join(names[2:], " ")
+	}
+	return person
+}
+
+// updAmount форматирует сумму в копейках как сумму в рублях.
+func updAmount(kopecks float64) string {
+	return strconv.FormatFloat(math.Round(kopecks)/100, 'f', 2, 64)
+}
+
+// joinNonEmpty объединяет непустые строки через разделитель sep.
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// newUUID возвращает случайный UUID версии 4.
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// UPDGenerator формирует УПД по отгрузкам МойСклад.
+//
+// Пример:
+//
+//	generator := moysklad.NewUPDGenerator(client, moysklad.UPDConfig{SenderID: "2BM-...", RecipientID: "2BM-..."})
+//	upd, err := generator.Generate(ctx, demandID)
+//	var requisites *moysklad.UPDRequisitesError
+//	if errors.As(err, &requisites) {
+//		// requisites.Missing – список незаполненных реквизитов
+//	}
+//	upd.WriteTo(file)
+type UPDGenerator struct {
+	client    *Client
+	config    UPDConfig
+	uoms      map[string]*Uom
+	countries map[string]*Country
+	products  map[string]*Product
+}
+
+// NewUPDGenerator возвращает [UPDGenerator].
+func NewUPDGenerator(client *Client, config UPDConfig) *UPDGenerator {
+	return &UPDGenerator{
+		client:    client,
+		config:    config,
+		uoms:      make(map[string]*Uom),
+		countries: make(map[string]*Country),
+		products:  make(map[string]*Product),
+	}
+}
+
+// Generate формирует УПД по отгрузке с указанным ID.
+func (generator *UPDGenerator) Generate(ctx context.Context, demandID string) (*UPD, error) {
+	source, err := generator.Source(ctx, demandID)
+	if err != nil {
+		return nil, err
+	}
+	return NewUPD(*source, generator.config)
+}
+
+// Source загружает отгрузку, связанные с ней документы, реквизиты участников и данные товаров.
+func (generator *UPDGenerator) Source(ctx context.Context, demandID string) (*UPDSource, error) {
+	service := NewDemandService(generator.client)
+	demand, _, err := service.GetByID(ctx, demandID, WithExpand("organization", "agent", "contract", "consignee", "factureOut"))
+	if err != nil {
+		return nil, err
+	}
+	positions, _, err := service.GetPositionListAll(ctx, demandID, WithExpand("assortment"))
+	if err != nil {
+		return nil, err
+	}
+
+	source := &UPDSource{
+		Demand:     demand,
+		FactureOut: demand.FactureOut,
+		Seller:     demand.Organization,
+		Buyer:      demand.Agent,
+		Consignee:  demand.Consignee,
+	}
+	for _, position := range *positions {
+		item, err := generator.position(ctx, position)
+		if err != nil {
+			return nil, err
+		}
+		source.Positions = append(source.Positions, *item)
+	}
+	return source, nil
+}
+
+// position загружает данные товара позиции.
+func (generator *UPDGenerator) position(ctx context.Context, position *DemandPosition) (*UPDSourcePosition, error) {
+	item := &UPDSourcePosition{Position: position}
+	assortment := position.Assortment
+	if assortment == nil {
+		return item, nil
+	}
+	item.Name, item.Code = assortment.Name, assortment.Code
+
+	var (
+		product *Product
+		uom     *NullValue[Uom]
+		country *NullValue[Country]
+	)
+	switch {
+	case assortment.IsProduct():
+		product = assortment.AsProduct()
+	case assortment.IsVariant():
+		if variant := assortment.AsVariant(); variant != nil && variant.Product != nil {
+			var err error
+			if product, err = generator.product(ctx, variant.Product.GetMeta()); err != nil {
+				return nil, err
+			}
+		}
+	case assortment.IsService():
+		item.Service = true
+		if service := assortment.AsService(); service != nil {
+			uom = service.Uom
+		}
+	case assortment.IsBundle():
+		if bundle := assortment.AsBundle(); bundle != nil {
+			item.Article, uom, country = bundle.GetArticle(), bundle.Uom, bundle.Country
+		}
+	}
+	if product != nil {
+		item.Article, uom, country = product.GetArticle(), product.Uom, product.Country
+		if generator.config.GTDAttribute != "" {
+			for _, attribute := range product.Attributes {
+				if value := attribute.GetValue(); attribute.GetName() == generator.config.GTDAttribute && value != nil {
+					item.GTD = fmt.Sprint(value)
+				}
+			}
+		}
+	}
+
+	var err error
+	if meta := Deref(uom).getValue().GetMeta(); meta.GetHref() != "" {
+		if item.Uom, err = getCached(ctx, generator.uoms, meta, NewUomService(generator.client).GetByID); err != nil {
+			return nil, err
+		}
+	}
+	if meta := Deref(country).getValue().GetMeta(); meta.GetHref() != "" {
+		if item.Country, err = getCached(ctx, generator.countries, meta, NewCountryService(generator.client).GetByID); err != nil {
+			return nil, err
+		}
+	}
+	return item, nil
+}
+
+// product загружает товар модификации.
+func (generator *UPDGenerator) product(ctx context.Context, meta Meta) (*Product, error) {
+	return getCached(ctx, generator.products, meta, NewProductService(generator.client).GetByID)
+}
+
+// getCached возвращает сущность из кэша или загружает её по ID.
+func getCached[T any](ctx context.Context, cache map[string]*T, meta Meta, get func(context.Context, string, ...func(*Params)) (*T, *resty.Response, error)) (*T, error) {
+	href := meta.GetHref()
+	if entity, ok := cache[href]; ok {
+		return entity, nil
+	}
+	entity, _, err := get(ctx, meta.GetUUIDFromHref())
+	if err != nil {
+		return nil, err
+	}
+	cache[href] = entity
+	return entity, nil
+}
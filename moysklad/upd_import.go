@@ -0,0 +1,343 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// updAssortmentPerRequest количество значений фильтра в одном запросе поиска ассортимента.
+const updAssortmentPerRequest = 50
+
+// UPDImportConfig конфигурация загрузки входящих УПД.
+type UPDImportConfig struct {
+	// Юрлицо – покупатель. Если не указано, то юрлицо ищется по ИНН покупателя из УПД.
+	Organization *Organization
+
+	// Склад приёмки.
+	Store *Store
+}
+
+// UPDDraft Черновик приёмки и счёта-фактуры полученного, сформированный по входящему УПД.
+type UPDDraft struct {
+	Supply    *Supply    // Приёмка (не проведена)
+	FactureIn *FactureIn // Счёт-фактура полученный (только для УПД со статусом 1)
+	Unmatched []string   // Строки УПД, для которых не найден товар в МойСклад
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (draft UPDDraft) String() string {
+	return Stringify(draft)
+}
+
+// UPDImporter формирует приёмки и счета-фактуры полученные по входящим УПД поставщиков.
+//
+// Товары сопоставляются по артикулу, затем по коду и наименованию.
+// Строки, для которых товар не найден, не попадают в приёмку и перечисляются в [UPDDraft.Unmatched].
+//
+// Пример:
+//
+//	upd, err := moysklad.ParseUPD(file)
+//	importer := moysklad.NewUPDImporter(client, moysklad.UPDImportConfig{Store: store})
+//	draft, err := importer.Draft(ctx, upd)
+//	// проверка draft.Unmatched
+//	err = importer.Save(ctx, draft)
+type UPDImporter struct {
+	client    *Client
+	config    UPDImportConfig
+	countries map[string]*Country
+}
+
+// NewUPDImporter возвращает [UPDImporter].
+func NewUPDImporter(client *Client, config UPDImportConfig) *UPDImporter {
+	return &UPDImporter{client: client, config: config, countries: make(map[string]*Country)}
+}
+
+// Draft формирует черновик приёмки и счёта-фактуры полученного по входящему УПД.
+//
+// Документы не сохраняются в МойСклад, для сохранения используется [UPDImporter.Save].
+func (importer *UPDImporter) Draft(ctx context.Context, upd *UPD) (*UPDDraft, error) {
+	invoice := upd.Document.Invoice
+
+	supplier, err := importer.supplier(ctx, invoice.Seller)
+	if err != nil {
+		return nil, err
+	}
+	organization, err := importer.organization(ctx, invoice.Buyer)
+	if err != nil {
+		return nil, err
+	}
+
+	supply := new(Supply).
+		SetAgent(supplier).
+		SetOrganization(organization).
+		SetIncomingNumber(invoice.GetNumber()).
+		SetVatEnabled(true).
+		SetVatIncluded(true).
+		SetApplicable(false)
+	if importer.config.Store != nil {
+		supply.SetStore(importer.config.Store)
+	}
+	incomingDate, err := parseTime(invoice.GetDate())
+	if err == nil {
+		supply.SetIncomingDate(incomingDate)
+	}
+
+	draft := &UPDDraft{Supply: supply}
+
+	items := upd.Document.Table.Items
+	assortment, err := importer.assortment(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []*SupplyPosition
+	for i, item := range items {
+		found := assortment[i]
+		if found == nil {
+			draft.Unmatched = append(draft.Unmatched, fmt.Sprintf("%d. %s", item.Line, item.Name))
+			continue
+		}
+		position, err := importer.position(ctx, item, found)
+		if err != nil {
+			return nil, fmt.Errorf("upd: line %d: %w", item.Line, err)
+		}
+		positions = append(positions, position)
+	}
+	supply.SetPositions(positions...)
+
+	if upd.Document.Function != updFunctionTransfer {
+		draft.FactureIn = new(FactureIn).
+			SetAgent(supplier).
+			SetOrganization(organization).
+			SetIncomingNumber(invoice.GetNumber()).
+			SetApplicable(false)
+		if !incomingDate.IsZero() {
+			draft.FactureIn.SetIncomingDate(incomingDate)
+		}
+	}
+	return draft, nil
+}
+
+// Save сохраняет приёмку и счёт-фактуру полученный из черновика.
+//
+// Черновик дополняется созданными документами.
+func (importer *UPDImporter) Save(ctx context.Context, draft *UPDDraft) error {
+	supply, _, err := NewSupplyService(importer.client).Create(ctx, draft.Supply)
+	if err != nil {
+		return err
+	}
+	draft.Supply = supply
+
+	if draft.FactureIn == nil {
+		return nil
+	}
+	factureIn, _, err := NewFactureInService(importer.client).Create(ctx, draft.FactureIn.SetSupplies(supply))
+	if err != nil {
+		return err
+	}
+	draft.FactureIn = factureIn
+	return nil
+}
+
+// supplier находит контрагента-поставщика по ИНН и КПП.
+func (importer *UPDImporter) supplier(ctx context.Context, seller UPDParticipant) (*Counterparty, error) {
+	inn, kpp := seller.inn()
+	if inn == "" {
+		return nil, fmt.Errorf("upd: seller INN is empty")
+	}
+
+	params := []func(*Params){WithFilterEquals("inn", inn)}
+	if kpp != "" {
+		params = append(params, WithFilterEquals("kpp", kpp))
+	}
+	counterparties, _, err := NewCounterpartyService(importer.client).GetListAll(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+	if counterparties.Len() == 0 {
+		return nil, fmt.Errorf("upd: counterparty with INN %s not found", inn)
+	}
+	return (*counterparties)[0], nil
+}
+
+// organization возвращает юрлицо из конфигурации или находит его по ИНН покупателя.
+func (importer *UPDImporter) organization(ctx context.Context, buyer UPDParticipant) (*Organization, error) {
+	if importer.config.Organization != nil {
+		return importer.config.Organization, nil
+	}
+
+	inn, _ := buyer.inn()
+	if inn == "" {
+		return nil, fmt.Errorf("upd: buyer INN is empty")
+	}
+	organizations, _, err := NewOrganizationService(importer.client).GetListAll(ctx, WithFilterEquals("inn", inn))
+	if err != nil {
+		return nil, err
+	}
+	if organizations.Len() == 0 {
+		return nil, fmt.Errorf("upd: organization with INN %s not found", inn)
+	}
+	return (*organizations)[0], nil
+}
+
+// inn возвращает ИНН и КПП участника.
+func (participant UPDParticipant) inn() (string, string) {
+	if legal := participant.ID.Legal; legal != nil {
+		return legal.INN, legal.KPP
+	}
+	if entrepreneur := participant.ID.Entrepreneur; entrepreneur != nil {
+		return entrepreneur.INN, ""
+	}
+	return "", ""
+}
+
+// assortment сопоставляет строки УПД с ассортиментом по артикулу, коду и наименованию.
+func (importer *UPDImporter) assortment(ctx context.Context, items []UPDItem) ([]*AssortmentPosition, error) {
+	found := make([]*AssortmentPosition, len(items))
+
+	keys := []struct {
+		filter string
+		value  func(item UPDItem) string
+		index  func(position *AssortmentPosition) string
+	}{
+		{"article", func(item UPDItem) string { return Deref(item.Extra).Article }, assortmentArticle},
+		{"code", func(item UPDItem) string { return Deref(item.Extra).Code }, func(position *AssortmentPosition) string { return position.Code }},
+		{"name", func(item UPDItem) string { return item.Name }, func(position *AssortmentPosition) string { return position.Name }},
+	}
+
+	service := NewAssortmentService(importer.client)
+	for _, key := range keys {
+		var values []string
+		seen := make(map[string]bool)
+		for i, item := range items {
+			if value := key.value(item); found[i] == nil && value != "" && !seen[value] {
+				seen[value] = true
+				values = append(values, value)
+			}
+		}
+
+		chunks := NewSliceFrom(values)
+		for _, chunk := range chunks.IntoChunks(updAssortmentPerRequest) {
+			var params []func(*Params)
+			for _, value := range chunk {
+				params = append(params, WithFilterEquals(key.filter, *value))
+			}
+			rows, _, err := service.GetListAll(ctx, params...)
+			if err != nil {
+				return nil, err
+			}
+
+			index := make(map[string]*AssortmentPosition)
+			for _, row := range rows {
+				if value := key.index(row); value != "" {
+					if _, ok := index[value]; !ok {
+						index[value] = row
+					}
+				}
+			}
+			for i, item := range items {
+				if found[i] == nil {
+					found[i] = index[key.value(item)]
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// assortmentArticle возвращает артикул товара или комплекта.
+func assortmentArticle(position *AssortmentPosition) string {
+	switch {
+	case position.IsProduct():
+		return position.AsProduct().GetArticle()
+	case position.IsBundle():
+		return position.AsBundle().GetArticle()
+	}
+	return ""
+}
+
+// position формирует позицию приёмки по строке УПД.
+func (importer *UPDImporter) position(ctx context.Context, item UPDItem, assortment *AssortmentPosition) (*SupplyPosition, error) {
+	position := new(SupplyPosition).SetAssortment(assortment)
+
+	quantity := 1.0
+	if item.Quantity != "" {
+		value, err := parseNumber(item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		quantity = value
+	}
+	position.SetQuantity(quantity)
+
+	sum, err := parseNumber(item.SumWithVat)
+	if err != nil {
+		return nil, err
+	}
+	if quantity != 0 {
+		position.SetPrice(math.Round(sum * 100 / quantity))
+	}
+
+	if rate := strings.TrimSuffix(strings.TrimSpace(item.VatRate), "%"); rate != "" && rate != updWithoutVat {
+		vat, err := strconv.Atoi(strings.SplitN(rate, "/", 2)[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid VAT rate %q", item.VatRate)
+		}
+		position.SetVat(vat).SetVatEnabled(true)
+	} else {
+		position.SetVat(0).SetVatEnabled(false)
+	}
+
+	for _, customs := range item.Customs {
+		if customs.Number != "" {
+			position.SetGTD(&GTD{Name: String(customs.Number)})
+		}
+		if customs.CountryCode != "" {
+			country, err := importer.country(ctx, customs.CountryCode)
+			if err != nil {
+				return nil, err
+			}
+			if country != nil {
+				position.SetCountry(country)
+			}
+		}
+	}
+
+	var trackingCodes []*TrackingCode
+	for _, identification := range Deref(item.Extra).Identification {
+		for _, code := range identification.Codes {
+			trackingCodes = append(trackingCodes, new(TrackingCode).SetCis(code).SetTypeTrackingCode())
+		}
+		for _, pack := range identification.Packs {
+			trackingCode := new(TrackingCode).SetCis(pack).SetTypeConsumerPack()
+			if markingCode, err := ParseMarkingCode(pack); err == nil && markingCode.SSCC != "" {
+				trackingCode.SetTypeTransportPack()
+			}
+			trackingCodes = append(trackingCodes, trackingCode)
+		}
+	}
+	if len(trackingCodes) > 0 {
+		position.SetTrackingCodes(trackingCodes...)
+	}
+	return position, nil
+}
+
+// country находит страну по цифровому коду ОКСМ.
+func (importer *UPDImporter) country(ctx context.Context, code string) (*Country, error) {
+	if country, ok := importer.countries[code]; ok {
+		return country, nil
+	}
+	countries, _, err := NewCountryService(importer.client).GetListAll(ctx, WithFilterEquals("code", code))
+	if err != nil {
+		return nil, err
+	}
+	var country *Country
+	if countries.Len() > 0 {
+		country = (*countries)[0]
+	}
+	importer.countries[code] = country
+	return country, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"io"
 
 	"time"
 )
@@ -500,6 +501,9 @@ type VariantService interface {
 	// Возвращает список изображений.
 	CreateImage(ctx context.Context, id string, image *Image) (*Slice[Image], *resty.Response, error)
 
+	// UploadImage выполняет запрос на добавление изображения с именем filename и содержимым r.
+	UploadImage(ctx context.Context, id, filename string, r io.Reader) (*Slice[Image], *resty.Response, error)
+
 	// UpdateImageMany выполняет запрос на обновления изображений.
 	// Принимает контекст, ID модификации и изображение.
 	// Если необходимо оставить некоторые Изображения, то необходимо передать эти изображения.
@@ -0,0 +1,298 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VariantMatrixAxis Характеристика матрицы модификаций и её значения.
+type VariantMatrixAxis struct {
+	Name   string   // Наименование характеристики
+	Values []string // Значения характеристики
+}
+
+// VariantMatrixCombination Сочетание значений характеристик. Ключ – наименование характеристики.
+type VariantMatrixCombination map[string]string
+
+// key возвращает ключ сочетания, не зависящий от порядка характеристик и регистра значений.
+func (combination VariantMatrixCombination) key() string {
+	parts := make([]string, 0, len(combination))
+	for name, value := range combination {
+		parts = append(parts, strings.ToLower(strings.TrimSpace(name))+"="+strings.ToLower(strings.TrimSpace(value)))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\x00")
+}
+
+// VariantMatrixCombinations возвращает декартово произведение значений характеристик axes.
+//
+// Пустые и повторяющиеся значения пропускаются; порядок сочетаний соответствует порядку осей и значений.
+func VariantMatrixCombinations(axes ...VariantMatrixAxis) []VariantMatrixCombination {
+	combinations := []VariantMatrixCombination{{}}
+	for _, axis := range axes {
+		var values []string
+		seen := make(map[string]bool)
+		for _, value := range axis.Values {
+			value = strings.TrimSpace(value)
+			if key := strings.ToLower(value); value != "" && !seen[key] {
+				seen[key] = true
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		next := make([]VariantMatrixCombination, 0, len(combinations)*len(values))
+		for _, combination := range combinations {
+			for _, value := range values {
+				item := make(VariantMatrixCombination, len(combination)+1)
+				for name, v := range combination {
+					item[name] = v
+				}
+				item[axis.Name] = value
+				next = append(next, item)
+			}
+		}
+		combinations = next
+	}
+	if len(combinations) == 1 && len(combinations[0]) == 0 {
+		return nil
+	}
+	return combinations
+}
+
+// VariantMatrixConfig конфигурация генерации модификаций.
+type VariantMatrixConfig struct {
+	// Характеристики и их значения.
+	Axes []VariantMatrixAxis
+
+	// Шаблон наименования модификации. Подстановки: {product} – наименование товара, {article} – артикул,
+	// {code} – код товара, {Наименование характеристики} – значение характеристики.
+	// Если не указан, то наименование формирует МойСклад.
+	NameTemplate string
+
+	// Шаблон кода модификации с теми же подстановками, что и NameTemplate. Если не указан, то код формирует МойСклад.
+	CodeTemplate string
+
+	// Сформировать внутренние штрихкоды EAN-13 для модификаций и их упаковок (см. [BarcodeGenerator]).
+	GenerateBarcodes bool
+
+	// Функция для заполнения цен, штрихкодов, упаковок и других полей модификации.
+	Customize func(variant *Variant, combination VariantMatrixCombination) error
+}
+
+// VariantMatrixResult Результат генерации модификаций.
+type VariantMatrixResult struct {
+	Variants Slice[Variant]             // Новые модификации (созданные или подготовленные к созданию)
+	Existing []VariantMatrixCombination // Сочетания, для которых модификации уже существуют
+}
+
+// String реализует интерфейс [fmt.Stringer].
+func (result VariantMatrixResult) String() string {
+	return Stringify(result)
+}
+
+// VariantMatrixGenerator генератор матрицы модификаций товара по сочетаниям значений характеристик.
+//
+// Пример:
+//
+//	generator := moysklad.NewVariantMatrixGenerator(client, moysklad.VariantMatrixConfig{
+//		Axes: []moysklad.VariantMatrixAxis{
+//			{Name: "Размер", Values: []string{"S", "M", "L"}},
+//			{Name: "Цвет", Values: []string{"Белый", "Чёрный"}},
+//		},
+//		CodeTemplate: "{article}-{Размер}-{Цвет}",
+//	})
+//	result, err := generator.Generate(ctx, product)
+type VariantMatrixGenerator struct {
+	client *Client
+	config VariantMatrixConfig
+}
+
+// NewVariantMatrixGenerator возвращает [VariantMatrixGenerator].
+func NewVariantMatrixGenerator(client *Client, config VariantMatrixConfig) *VariantMatrixGenerator {
+	return &VariantMatrixGenerator{client: client, config: config}
+}
+
+// Plan подготавливает модификации товара для отсутствующих сочетаний без сохранения в МойСклад.
+//
+// Недостающие характеристики в модификациях указываются только по наименованию и создаются при вызове [VariantMatrixGenerator.Generate].
+func (generator *VariantMatrixGenerator) Plan(ctx context.Context, product *Product) (*VariantMatrixResult, error) {
+	characteristics, err := generator.characteristics(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return generator.plan(ctx, product, characteristics)
+}
+
+// Generate создаёт недостающие характеристики и модификации товара для отсутствующих сочетаний.
+//
+// Модификации создаются порциями по [MaxPositions].
+func (generator *VariantMatrixGenerator) Generate(ctx context.Context, product *Product) (*VariantMatrixResult, error) {
+	characteristics, err := generator.characteristics(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	result, err := generator.plan(ctx, product, characteristics)
+	if err != nil {
+		return nil, err
+	}
+
+	service := NewVariantService(generator.client)
+	created := NewSlice[Variant]()
+	for _, chunk := range result.Variants.IntoChunks(MaxPositions) {
+		rows, _, err := service.CreateUpdateMany(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		created.Push(Deref(rows)...)
+	}
+	result.Variants = created
+	return result, nil
+}
+
+// characteristics возвращает характеристики модификаций по наименованию, при create создаёт недостающие.
+func (generator *VariantMatrixGenerator) characteristics(ctx context.Context, create bool) (map[string]*Characteristic, error) {
+	service := NewVariantService(generator.client)
+	metadata, _, err := service.GetMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	characteristics := make(map[string]*Characteristic)
+	for _, characteristic := range metadata.Characteristics {
+		characteristics[strings.ToLower(characteristic.GetName())] = characteristic
+	}
+
+	var missing []*Characteristic
+	for _, axis := range generator.config.Axes {
+		if axis.Name == "" {
+			return nil, fmt.Errorf("variant matrix: characteristic name is empty")
+		}
+		if _, ok := characteristics[strings.ToLower(axis.Name)]; !ok {
+			missing = append(missing, new(Characteristic).SetName(axis.Name))
+		}
+	}
+	if !create || len(missing) == 0 {
+		return characteristics, nil
+	}
+
+	rows, _, err := service.CreateCharacteristicMany(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+	for _, characteristic := range Deref(rows) {
+		characteristics[strings.ToLower(characteristic.GetName())] = characteristic
+	}
+	return characteristics, nil
+}
+
+// plan формирует модификации для сочетаний, которых ещё нет у товара.
+func (generator *VariantMatrixGenerator) plan(ctx context.Context, product *Product, characteristics map[string]*Characteristic) (*VariantMatrixResult, error) {
+	existing, _, err := NewVariantService(generator.client).GetListAll(ctx, WithFilterEquals("productid", product.GetID()))
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for _, variant := range Deref(existing) {
+		combination := make(VariantMatrixCombination)
+		for _, characteristic := range variant.GetCharacteristics() {
+			combination[characteristic.GetName()] = characteristic.GetValue()
+		}
+		keys[combination.key()] = true
+	}
+
+	result := &VariantMatrixResult{Variants: NewSlice[Variant]()}
+	for _, combination := range VariantMatrixCombinations(generator.config.Axes...) {
+		if keys[combination.key()] {
+			result.Existing = append(result.Existing, combination)
+			continue
+		}
+
+		variant := new(Variant).SetProduct(product)
+		for _, axis := range generator.config.Axes {
+			value, ok := combination[axis.Name]
+			if !ok {
+				continue
+			}
+			characteristic := new(Characteristic).SetName(axis.Name).SetValue(value)
+			if known, ok := characteristics[strings.ToLower(axis.Name)]; ok {
+				meta := known.GetMeta()
+				characteristic.SetMeta(&meta)
+			}
+			variant.SetCharacteristics(characteristic)
+		}
+
+		if template := generator.config.NameTemplate; template != "" {
+			variant.Name = String(variantMatrixExpand(template, product, combination))
+		}
+		if template := generator.config.CodeTemplate; template != "" {
+			variant.SetCode(variantMatrixExpand(template, product, combination))
+		}
+		if customize := generator.config.Customize; customize != nil {
+			if err := customize(variant, combination); err != nil {
+				return nil, err
+			}
+		}
+		result.Variants.Push(variant)
+	}
+
+	if generator.config.GenerateBarcodes && result.Variants.Len() > 0 {
+		if err := generator.barcodes(ctx, product, result.Variants); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// barcodes заполняет внутренние штрихкоды модификаций и упаковок, для которых они не указаны.
+func (generator *VariantMatrixGenerator) barcodes(ctx context.Context, product *Product, variants Slice[Variant]) error {
+	packs := product.GetPacks()
+
+	var count int
+	for _, variant := range variants {
+		if variant.Barcodes.Len() == 0 {
+			count++
+		}
+		if variant.Packs.Len() == 0 {
+			count += packs.Len()
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	barcodes, err := NewBarcodeGenerator(generator.client).NextMany(ctx, count)
+	if err != nil {
+		return err
+	}
+	next := func() *Barcode {
+		barcode := (*barcodes)[0]
+		*barcodes = (*barcodes)[1:]
+		return barcode
+	}
+
+	for _, variant := range variants {
+		if variant.Barcodes.Len() == 0 {
+			variant.SetBarcodes(next())
+		}
+		if variant.Packs.Len() == 0 {
+			for _, pack := range packs {
+				variant.SetPacks(&VariantPack{ParentPack: pack, Barcodes: Slice[Barcode]{next()}})
+			}
+		}
+	}
+	return nil
+}
+
+// variantMatrixExpand подставляет в шаблон данные товара и значения характеристик.
+func variantMatrixExpand(template string, product *Product, combination VariantMatrixCombination) string {
+	pairs := []string{"{product}", product.GetName(), "{article}", product.GetArticle(), "{code}", product.GetCode()}
+	for name, value := range combination {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.TrimSpace(strings.NewReplacer(pairs...).Replace(template))
+}
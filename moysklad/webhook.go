@@ -179,8 +179,9 @@ func (webhook *Webhook) Delete(ctx context.Context, client *Client) (bool, *rest
 
 // MarshalJSON реализует интерфейс [json.Marshaler].
 func (webhook Webhook) MarshalJSON() ([]byte, error) {
+	type alias Webhook
 	webhook.Method = String("POST")
-	return json.Marshal(webhook)
+	return json.Marshal(alias(webhook))
 }
 
 // WebhookAction Действие, которое отслеживается веб-хуком.
@@ -0,0 +1,320 @@
+package moysklad
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// WebhookChangeKind Вид изменения вебхука.
+//
+// Возможные значения:
+//   - WebhookChangeCreate  – создание
+//   - WebhookChangeUpdate  – изменение параметров
+//   - WebhookChangeEnable  – включение
+//   - WebhookChangeDisable – отключение
+//   - WebhookChangeDelete  – удаление
+type WebhookChangeKind string
+
+const (
+	WebhookChangeCreate  WebhookChangeKind = "create"  // Создание
+	WebhookChangeUpdate  WebhookChangeKind = "update"  // Изменение параметров
+	WebhookChangeEnable  WebhookChangeKind = "enable"  // Включение
+	WebhookChangeDisable WebhookChangeKind = "disable" // Отключение
+	WebhookChangeDelete  WebhookChangeKind = "delete"  // Удаление
+)
+
+// WebhookChange Изменение вебхука или вебхука на изменение остатков.
+//
+// Заполнено одно из полей Webhook или WebhookStock.
+type WebhookChange struct {
+	Kind         WebhookChangeKind // Вид изменения
+	Webhook      *Webhook          // Вебхук: желаемое состояние (для удаления – текущее)
+	WebhookStock *WebhookStock     // Вебхук на изменение остатков: желаемое состояние (для удаления – текущее)
+	ID           string            // ID существующего вебхука (кроме создания)
+	Fields       []string          // Различающиеся поля (для изменения)
+}
+
+// String возвращает описание изменения.
+func (change WebhookChange) String() string {
+	var target string
+	if change.Webhook != nil {
+		target = fmt.Sprintf("%s %s -> %s", change.Webhook.GetEntityType(), change.Webhook.GetAction(), change.Webhook.GetURL())
+	} else {
+		target = fmt.Sprintf("stock %s -> %s", change.WebhookStock.GetReportType(), change.WebhookStock.GetURL())
+	}
+	if len(change.Fields) > 0 {
+		return fmt.Sprintf("%s %s (%s)", change.Kind, target, strings.Join(change.Fields, ", "))
+	}
+	return fmt.Sprintf("%s %s", change.Kind, target)
+}
+
+// WebhookPlan План согласования вебхуков.
+type WebhookPlan struct {
+	Changes []*WebhookChange // Изменения
+}
+
+// IsEmpty возвращает true, если вебхуки соответствуют желаемому состоянию.
+func (plan WebhookPlan) IsEmpty() bool {
+	return len(plan.Changes) == 0
+}
+
+// String возвращает план изменений, по одному изменению в строке.
+func (plan WebhookPlan) String() string {
+	lines := make([]string, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		lines = append(lines, change.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WebhookReconcileConfig конфигурация согласования вебхуков.
+type WebhookReconcileConfig struct {
+	// Приложение, вебхуки которого согласовываются.
+	// Если не указано, то согласовываются вебхуки, созданные не приложениями.
+	// Вебхуки других приложений не изменяются и не удаляются.
+	Application *Application
+
+	// Только сформировать план без внесения изменений.
+	DryRun bool
+
+	// Не удалять вебхуки, отсутствующие в желаемом состоянии.
+	KeepUnknown bool
+}
+
+// WebhookReconciler согласование вебхуков с желаемым состоянием.
+//
+// Вебхуки сопоставляются по типу сущности, действию и URL, вебхуки на изменение остатков –
+// по типу отчёта и URL. Различия в режиме отображения изменений (diffType) и флаге включения
+// приводят к изменению вебхука, отсутствующие вебхуки создаются, лишние – удаляются.
+// Если флаг включения в желаемом состоянии не указан, то вебхук должен быть включён.
+//
+// Пример:
+//
+//	reconciler := moysklad.NewWebhookReconciler(client, moysklad.WebhookReconcileConfig{Application: application})
+//	plan, err := reconciler.Reconcile(ctx, moysklad.Slice[moysklad.Webhook]{
+//		new(moysklad.Webhook).SetEntityType(moysklad.MetaTypeCustomerOrder).SetActionCreate().SetURL(url),
+//		new(moysklad.Webhook).SetEntityType(moysklad.MetaTypeCustomerOrder).SetActionUpdate().SetDiffTypeFields().SetURL(url),
+//	}, nil)
+type WebhookReconciler struct {
+	client *Client
+	config WebhookReconcileConfig
+}
+
+// NewWebhookReconciler возвращает [WebhookReconciler].
+func NewWebhookReconciler(client *Client, config WebhookReconcileConfig) *WebhookReconciler {
+	return &WebhookReconciler{client: client, config: config}
+}
+
+// Reconcile формирует план согласования и, если не указан режим [WebhookReconcileConfig.DryRun], применяет его.
+func (reconciler *WebhookReconciler) Reconcile(ctx context.Context, webhooks Slice[Webhook], webhookStocks Slice[WebhookStock]) (*WebhookPlan, error) {
+	plan, err := reconciler.Plan(ctx, webhooks, webhookStocks)
+	if err != nil || reconciler.config.DryRun {
+		return plan, err
+	}
+	return plan, reconciler.Apply(ctx, plan)
+}
+
+// owned возвращает true, если вебхук создан приложением author, согласуемым по конфигурации.
+func (reconciler *WebhookReconciler) owned(author *Meta) bool {
+	application := reconciler.config.Application
+	if application == nil {
+		return author == nil
+	}
+	if author == nil {
+		return false
+	}
+	id := application.GetID()
+	if id == "" {
+		id = application.GetMeta().GetUUIDFromHref()
+	}
+	return author.GetUUIDFromHref() == id
+}
+
+// webhookKey возвращает ключ сопоставления вебхука.
+func webhookKey(webhook *Webhook) string {
+	return strings.Join([]string{string(webhook.GetEntityType()), strings.ToUpper(string(webhook.GetAction())), webhook.GetURL()}, "|")
+}
+
+// webhookStockKey возвращает ключ сопоставления вебхука на изменение остатков.
+func webhookStockKey(webhookStock *WebhookStock) string {
+	return string(webhookStock.GetReportType()) + "|" + webhookStock.GetURL()
+}
+
+// webhookEnabled возвращает желаемое значение флага включения (по умолчанию включён).
+func webhookEnabled(enabled *bool) bool {
+	return enabled == nil || *enabled
+}
+
+// webhookEnableKind возвращает вид изменения при различии только во флаге включения.
+func webhookEnableKind(enabled bool) WebhookChangeKind {
+	if enabled {
+		return WebhookChangeEnable
+	}
+	return WebhookChangeDisable
+}
+
+// Plan сравнивает текущие вебхуки с желаемыми webhooks и webhookStocks и возвращает план изменений.
+func (reconciler *WebhookReconciler) Plan(ctx context.Context, webhooks Slice[Webhook], webhookStocks Slice[WebhookStock]) (*WebhookPlan, error) {
+	plan := new(WebhookPlan)
+
+	current, _, err := NewWebhookService(reconciler.client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string][]*Webhook)
+	for _, webhook := range Deref(current) {
+		var author *Meta
+		if webhook.AuthorApplication != nil {
+			author = webhook.AuthorApplication.Meta
+		}
+		if reconciler.owned(author) {
+			existing[webhookKey(webhook)] = append(existing[webhookKey(webhook)], webhook)
+		}
+	}
+
+	desired := make(map[string]bool)
+	for _, webhook := range webhooks {
+		key := webhookKey(webhook)
+		if desired[key] {
+			return nil, fmt.Errorf("webhook reconcile: duplicate webhook %s", key)
+		}
+		desired[key] = true
+
+		matches := existing[key]
+		if len(matches) == 0 {
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeCreate, Webhook: webhook})
+			continue
+		}
+		// повторяющиеся вебхуки удаляются
+		for _, duplicate := range matches[1:] {
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeDelete, Webhook: duplicate, ID: duplicate.GetID()})
+		}
+
+		found := matches[0]
+		enabled := webhookEnabled(webhook.Enabled)
+		var fields []string
+		if webhook.GetAction() == WebhookActionUpdate {
+			diffType, foundDiffType := webhook.GetDiffType(), found.GetDiffType()
+			if diffType == "" {
+				diffType = WebhookDiffNone
+			}
+			if foundDiffType == "" {
+				foundDiffType = WebhookDiffNone
+			}
+			if diffType != foundDiffType {
+				fields = append(fields, "diffType")
+			}
+		}
+		switch {
+		case len(fields) > 0:
+			if enabled != found.GetEnabled() {
+				fields = append(fields, "enabled")
+			}
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeUpdate, Webhook: webhook, ID: found.GetID(), Fields: fields})
+		case enabled != found.GetEnabled():
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: webhookEnableKind(enabled), Webhook: webhook, ID: found.GetID()})
+		}
+	}
+	if !reconciler.config.KeepUnknown {
+		for key, matches := range existing {
+			if desired[key] {
+				continue
+			}
+			for _, webhook := range matches {
+				plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeDelete, Webhook: webhook, ID: webhook.GetID()})
+			}
+		}
+	}
+
+	currentStocks, _, err := NewWebhookStockService(reconciler.client).GetListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existingStocks := make(map[string][]*WebhookStock)
+	for _, webhookStock := range Deref(currentStocks) {
+		if reconciler.owned(webhookStock.AuthorApplication) {
+			key := webhookStockKey(webhookStock)
+			existingStocks[key] = append(existingStocks[key], webhookStock)
+		}
+	}
+
+	desiredStocks := make(map[string]bool)
+	for _, webhookStock := range webhookStocks {
+		key := webhookStockKey(webhookStock)
+		if desiredStocks[key] {
+			return nil, fmt.Errorf("webhook reconcile: duplicate stock webhook %s", key)
+		}
+		desiredStocks[key] = true
+
+		matches := existingStocks[key]
+		if len(matches) == 0 {
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeCreate, WebhookStock: webhookStock})
+			continue
+		}
+		for _, duplicate := range matches[1:] {
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeDelete, WebhookStock: duplicate, ID: duplicate.GetID()})
+		}
+		if enabled := webhookEnabled(webhookStock.Enabled); enabled != matches[0].GetEnabled() {
+			plan.Changes = append(plan.Changes, &WebhookChange{Kind: webhookEnableKind(enabled), WebhookStock: webhookStock, ID: matches[0].GetID()})
+		}
+	}
+	if !reconciler.config.KeepUnknown {
+		for key, matches := range existingStocks {
+			if desiredStocks[key] {
+				continue
+			}
+			for _, webhookStock := range matches {
+				plan.Changes = append(plan.Changes, &WebhookChange{Kind: WebhookChangeDelete, WebhookStock: webhookStock, ID: webhookStock.GetID()})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Apply выполняет изменения плана.
+func (reconciler *WebhookReconciler) Apply(ctx context.Context, plan *WebhookPlan) error {
+	webhookService := NewWebhookService(reconciler.client)
+	webhookStockService := NewWebhookStockService(reconciler.client)
+
+	for _, change := range plan.Changes {
+		var err error
+		if webhook := change.Webhook; webhook != nil {
+			enabled := webhookEnabled(webhook.Enabled)
+			switch change.Kind {
+			case WebhookChangeCreate:
+				_, _, err = webhookService.Create(ctx, &Webhook{
+					EntityType: webhook.EntityType,
+					Action:     webhook.Action,
+					DiffType:   webhook.DiffType,
+					URL:        webhook.URL,
+					Enabled:    &enabled,
+				})
+			case WebhookChangeUpdate:
+				_, _, err = webhookService.Update(ctx, change.ID, &Webhook{DiffType: webhook.DiffType, Enabled: &enabled})
+			case WebhookChangeEnable, WebhookChangeDisable:
+				_, _, err = webhookService.Update(ctx, change.ID, &Webhook{Enabled: &enabled})
+			case WebhookChangeDelete:
+				_, _, err = webhookService.DeleteByID(ctx, change.ID)
+			}
+		} else if webhookStock := change.WebhookStock; webhookStock != nil {
+			enabled := webhookEnabled(webhookStock.Enabled)
+			switch change.Kind {
+			case WebhookChangeCreate:
+				_, _, err = webhookStockService.Create(ctx, &WebhookStock{
+					ReportType: webhookStock.ReportType,
+					URL:        webhookStock.URL,
+					Enabled:    &enabled,
+				})
+			case WebhookChangeEnable, WebhookChangeDisable:
+				_, _, err = webhookStockService.Update(ctx, change.ID, &WebhookStock{Enabled: &enabled})
+			case WebhookChangeDelete:
+				_, _, err = webhookStockService.DeleteByID(ctx, change.ID)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("webhook reconcile: %s: %w", change, err)
+		}
+	}
+	return nil
+}
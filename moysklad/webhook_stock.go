@@ -139,8 +139,9 @@ func (webhookStock *WebhookStock) Delete(ctx context.Context, client *Client) (b
 
 // MarshalJSON реализует интерфейс [json.Marshaler].
 func (webhookStock WebhookStock) MarshalJSON() ([]byte, error) {
+	type alias WebhookStock
 	webhookStock.StockType = String("stock")
-	return json.Marshal(webhookStock)
+	return json.Marshal(alias(webhookStock))
 }
 
 // WebhookReport Тип отчёта остатков, к которым привязан вебхук на изменение остатков.
@@ -0,0 +1,290 @@
+package moysklad
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxRelationships файл связей книги (xl/_rels/workbook.xml.rels).
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxWorkbook описание книги (xl/workbook.xml).
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxSharedStrings общие строки книги (xl/sharedStrings.xml).
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+// xlsxRichText строка, которая может состоять из нескольких фрагментов.
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (text xlsxRichText) String() string {
+	if len(text.Runs) == 0 {
+		return text.T
+	}
+	var sb strings.Builder
+	for _, run := range text.Runs {
+		sb.WriteString(run.T)
+	}
+	return sb.String()
+}
+
+// xlsxSheetData данные листа (xl/worksheets/sheetN.xml).
+type xlsxSheetData struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string       `xml:"r,attr"`
+			T  string       `xml:"t,attr"`
+			V  string       `xml:"v"`
+			IS xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func xlsxDecodeFile(zr *zip.Reader, name string, v any) error {
+	for _, file := range zr.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return xml.NewDecoder(rc).Decode(v)
+	}
+	return fmt.Errorf("xlsx: file %s not found", name)
+}
+
+// xlsxColumnIndex возвращает индекс столбца (с нуля) по ссылке на ячейку вида "AB12".
+func xlsxColumnIndex(ref string) int {
+	var idx int
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		idx = idx*26 + int(r-'A'+1)
+	}
+	return idx - 1
+}
+
+// xlsxColumnName возвращает буквенное обозначение столбца по индексу (с нуля).
+func xlsxColumnName(idx int) string {
+	var name []byte
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		name = append([]byte{byte('A' + (idx-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// readXLSX читает все строки листа sheet книги XLSX.
+//
+// Если sheet пустая строка, то читается первый лист книги.
+func readXLSX(r io.ReaderAt, size int64, sheet string) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var workbook xlsxWorkbook
+	if err = xlsxDecodeFile(zr, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+
+	var rels xlsxRelationships
+	if err = xlsxDecodeFile(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	var rid string
+	for _, s := range workbook.Sheets {
+		if sheet == "" || s.Name == sheet {
+			rid = s.RID
+			break
+		}
+	}
+	if rid == "" {
+		return nil, fmt.Errorf("xlsx: sheet %q not found", sheet)
+	}
+
+	var target string
+	for _, rel := range rels.Relationships {
+		if rel.ID == rid {
+			target = rel.Target
+			break
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var shared xlsxSharedStrings
+	// файл общих строк необязателен
+	_ = xlsxDecodeFile(zr, "xl/sharedStrings.xml", &shared)
+
+	var data xlsxSheetData
+	if err = xlsxDecodeFile(zr, target, &data); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range data.Rows {
+		// пустые строки в файле могут быть пропущены
+		for row.R > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			idx := i
+			if cell.R != "" {
+				idx = xlsxColumnIndex(cell.R)
+			}
+			for idx >= len(values) {
+				values = append(values, "")
+			}
+
+			switch cell.T {
+			case "s":
+				n, err := strconv.Atoi(cell.V)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("xlsx: invalid shared string index in cell %s", cell.R)
+				}
+				values[idx] = shared.Items[n].String()
+			case "inlineStr":
+				values[idx] = cell.IS.String()
+			default:
+				values[idx] = cell.V
+			}
+		}
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbookTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// xlsxWriter потоковая запись книги XLSX из одного листа.
+//
+// Строки записываются в лист по мере поступления, служебные части книги дописываются при закрытии.
+type xlsxWriter struct {
+	zw        *zip.Writer
+	sheet     io.Writer
+	sheetName string
+	row       int
+}
+
+func newXLSXWriter(w io.Writer, sheetName string) (*xlsxWriter, error) {
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	}
+
+	zw := zip.NewWriter(w)
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.WriteString(sheet, xlsxSheetHeader); err != nil {
+		return nil, err
+	}
+
+	return &xlsxWriter{zw: zw, sheet: sheet, sheetName: sheetName}, nil
+}
+
+// WriteRow записывает строку. Числа записываются числовыми ячейками, остальные значения – строками.
+func (writer *xlsxWriter) WriteRow(values []any) error {
+	writer.row++
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<row r="%d">`, writer.row)
+	for i, value := range values {
+		ref := xlsxColumnName(i) + strconv.Itoa(writer.row)
+		switch v := value.(type) {
+		case nil:
+			continue
+		case float64:
+			fmt.Fprintf(&sb, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case int, int64:
+			fmt.Fprintf(&sb, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case bool:
+			fmt.Fprintf(&sb, `<c r="%s" t="b"><v>%d</v></c>`, ref, map[bool]int{false: 0, true: 1}[v])
+		default:
+			fmt.Fprintf(&sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(&sb, []byte(fmt.Sprint(v))); err != nil {
+				return err
+			}
+			sb.WriteString(`</t></is></c>`)
+		}
+	}
+	sb.WriteString(`</row>`)
+
+	_, err := io.WriteString(writer.sheet, sb.String())
+	return err
+}
+
+// Close завершает лист, дописывает служебные части книги и закрывает архив.
+func (writer *xlsxWriter) Close() error {
+	if _, err := io.WriteString(writer.sheet, xlsxSheetFooter); err != nil {
+		return err
+	}
+
+	var sheetName strings.Builder
+	if err := xml.EscapeText(&sheetName, []byte(writer.sheetName)); err != nil {
+		return err
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbookTemplate, sheetName.String())},
+	}
+	for _, part := range parts {
+		f, err := writer.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	return writer.zw.Close()
+}